@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/ellisto/monorepo-versioning/pkg"
+)
+
+// inputErrors collects the problems with the action's inputs, so that every invalid input is reported at once
+// rather than one per workflow run
+type inputErrors []string
+
+// add an error naming the invalid input
+func (e *inputErrors) add(input string, format string, args ...any) {
+	*e = append(*e, fmt.Sprintf("Invalid input %s: %s", input, fmt.Sprintf(format, args...)))
+}
+
+// exitIfAny logs each error and exits, if any inputs were invalid. Errors are logged rather than panicking, so
+// that they're highlighted in the workflow log instead of being buried in a stack trace.
+func (e inputErrors) exitIfAny(logger *slog.Logger) {
+	if len(e) == 0 {
+		return
+	}
+
+	for _, message := range e {
+		logger.Error(message)
+	}
+
+	os.Exit(1)
+}
+
+// required checks that an input was provided
+func (e *inputErrors) required(input string, value string) {
+	if strings.TrimSpace(value) == "" {
+		e.add(input, "must be provided")
+	}
+}
+
+// oneOf checks that an input is empty or one of the allowed values
+func (e *inputErrors) oneOf(input string, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+
+	for _, allowedValue := range allowed {
+		if strings.EqualFold(value, allowedValue) {
+			return
+		}
+	}
+
+	e.add(input, "%q is not one of: %s", value, strings.Join(allowed, ", "))
+}
+
+// yesNo parses a yes/no input, where an empty input is no
+func (e *inputErrors) yesNo(input string, value string) bool {
+	e.oneOf(input, value, "yes", "no", "true", "false")
+	return isEnabled(value)
+}
+
+// repository checks that an owner/repository name, such as GITHUB_REPOSITORY, is well-formed
+func (e *inputErrors) repository(input string, value string) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		e.add(input, "%q must be in the format owner/repository", value)
+	}
+}
+
+// revision checks that a commit SHA, such as GITHUB_SHA, is long enough to shorten for prerelease versions
+func (e *inputErrors) revision(input string, value string) {
+	if len(value) < 7 {
+		e.add(input, "%q must be a commit SHA of at least 7 characters", value)
+	}
+}
+
+// apiURL checks that an API URL input is an absolute HTTP or HTTPS URL
+func (e *inputErrors) apiURL(input string, value string) {
+	if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		e.add(input, "%q must be an http or https URL, eg: https://github.example.com/api/v3", value)
+	}
+}
+
+// version parses a semantic version input. An empty input is nil.
+func (e *inputErrors) version(input string, value string) *semver.Version {
+	if value == "" {
+		return nil
+	}
+
+	version, err := semver.NewVersion(value)
+	if err != nil {
+		e.add(input, "%q is not a semantic version: %s", value, err)
+	}
+
+	return version
+}
+
+// duration parses a duration input such as "10m". An empty input means no timeout.
+func (e *inputErrors) duration(input string, value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		e.add(input, "%q is not a duration, eg: 10m: %s", value, err)
+	}
+
+	return duration
+}
+
+// clock parses an RFC 3339 time which the clock is frozen at. An empty input means the system clock.
+func (e *inputErrors) clock(input string, value string) pkg.Clock {
+	if value == "" {
+		return pkg.SystemClock()
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		e.add(input, "%q is not an RFC 3339 time, eg: 2024-01-02T15:04:05Z: %s", value, err)
+	}
+
+	return pkg.FrozenClock(at)
+}
+
+// certPool of the system's certificates and those of a PEM bundle file, or nil if no bundle was provided, so
+// that the system's certificates are used
+func (e *inputErrors) certPool(input string, path string) *x509.CertPool {
+	if path == "" {
+		return nil
+	}
+
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		e.add(input, "%s", err)
+		return nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		e.add(input, "%s has no PEM encoded certificates", path)
+		return nil
+	}
+
+	return pool
+}
+
+// commitLog reads a commit log from a file, or standard input if the path is "-"
+func (e *inputErrors) commitLog(input string, path string) *pkg.CommitLog {
+	reader := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			e.add(input, "%s", err)
+			return nil
+		}
+
+		defer file.Close()
+		reader = file
+	}
+
+	log, err := pkg.ParseCommitLog(reader)
+	if err != nil {
+		e.add(input, "%s", err)
+		return nil
+	}
+
+	return &log
+}
+
+// gitLog reads the commit log of the git repository checked out in a directory
+func (e *inputErrors) gitLog(input string, dir string) *pkg.CommitLog {
+	log, err := pkg.ReadGitLog(context.Background(), dir)
+	if err != nil {
+		e.add(input, "%s", err)
+		return nil
+	}
+
+	return &log
+}
+
+// commitHistory reads a commit log from a JSON commit history file
+func (e *inputErrors) commitHistory(input string, path string) *pkg.CommitLog {
+	file, err := os.Open(path)
+	if err != nil {
+		e.add(input, "%q isn't %s, %s, or a readable commit history file: %s", path, commitSourceGitHub, commitSourceGit, err)
+		return nil
+	}
+
+	defer file.Close()
+	log, err := pkg.ParseCommitHistory(file)
+	if err != nil {
+		e.add(input, "%s", err)
+		return nil
+	}
+
+	return &log
+}
+
+// wholeNumber parses a whole number input. An empty input is zero.
+func (e *inputErrors) wholeNumber(input string, value string) int {
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		e.add(input, "%q must be a whole number", value)
+	}
+
+	return number
+}
+
+// pullRequest parses the pull request number from a pull request ref, such as GITHUB_REF on pull request events
+func (e *inputErrors) pullRequest(input string, value string) int {
+	var number int
+	if _, err := fmt.Sscanf(value, "refs/pull/%d/", &number); err != nil || number <= 0 {
+		e.add(input, "%q is not a pull request ref, eg: refs/pull/1/merge", value)
+	}
+
+	return number
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrozenTimeInput(t *testing.T) {
+	var errs inputErrors
+	clock := errs.clock("frozen-time", "2024-01-02T15:04:05Z")
+	if len(errs) != 0 {
+		t.Fatalf("Expected a valid time, but got %v", errs)
+	}
+
+	expected := time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if now := clock.Now(); !now.Equal(expected) {
+			t.Errorf("Expected the clock to always tell %s, but got %s", expected, now)
+		}
+	}
+
+	errs.clock("frozen-time", "2024-01-02")
+	if len(errs) != 1 {
+		t.Errorf("Expected a time without a time of day to be invalid, but got %v", errs)
+	}
+}
+
+func TestFrozenTimeInputDefaultsToTheSystemClock(t *testing.T) {
+	var errs inputErrors
+	before := time.Now()
+	now := errs.clock("frozen-time", "").Now()
+	if len(errs) != 0 || now.Before(before) || now.After(time.Now()) {
+		t.Errorf("Expected the system clock, but got %s with errors %v", now, errs)
+	}
+}
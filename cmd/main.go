@@ -1,92 +1,1479 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/ellisto/monorepo-versioning/pkg"
+	"github.com/ellisto/monorepo-versioning/pkg/cassette"
+	"github.com/ellisto/monorepo-versioning/pkg/telemetry"
 	"github.com/google/go-github/v50/github"
 	"golang.org/x/oauth2"
 )
 
+// allComponents is the component input which versions every configured component
+const allComponents = "*"
+
+// initFromSemanticRelease is the from input of the init operation which migrates semantic-release's configuration.
+// Without it, components are proposed from the repository's manifests.
+const initFromSemanticRelease = "semantic-release"
+
+// secretInputs are left out of the hash of the inputs in the audit log, as they change between runs with the same
+// configuration
+var secretInputs = []string{"INPUT_GITHUB-TOKEN", "INPUT_SIGNING-KEY", "INPUT_SIGNING-KEY-PASSPHRASE", "INPUT_CHART-REGISTRY-PASSWORD", "INPUT_PUSHGATEWAY-URL"}
+
+// Behaviours when no new version is generated for a component
+const (
+	// Succeed, as the component doesn't need releasing
+	noVersionSuccess = "success"
+	// Succeed, but flag the run as skipped with the skipped output
+	noVersionSkip = "skip"
+	// Fail the run, for pipelines which must always publish a version
+	noVersionFail = "fail"
+)
+
+// Operations which can be selected with the operation input
+const (
+	// Generate and release the next version of each component
+	operationVersion = "version"
+	// Publish the newest draft release of each component
+	operationPublish = "publish"
+	// Delete superseded or old prereleases of each component
+	operationCleanup = "cleanup"
+	// Delete the release of a version created from the current revision
+	operationRollback = "rollback"
+	// Output the newest released versions of each component
+	operationCurrent = "current"
+	// Render the changelog between two released versions of each component
+	operationChangelog = "changelog"
+	// List the components which have been released, with their newest versions
+	operationComponents = "components"
+	// Write the changelog of every release of every component to a file
+	operationHistory = "history"
+	// Import the versions of each component from an existing tagging scheme
+	operationMigrate = "migrate"
+	// Generate a starter configuration file for the repository
+	operationInit = "init"
+	// Mark the release of a version as deprecated, naming the version to upgrade to instead
+	operationYank = "yank"
+	// Generate the release notes of released versions again, updating those which changed
+	operationNotes = "notes"
+	// Check the versions a pull request would release, failing if its commits break any rule
+	operationCheck = "check"
+)
+
+// Sources of commits which the commit-source input selects, besides the path of a commit history file
+const (
+	// Read commits from the GitHub API
+	commitSourceGitHub = "github"
+	// Read commits from the git repository checked out in the workspace
+	commitSourceGit = "git"
+)
+
+// cacheActions is the cache input for the GitHub Actions cache, rather than the directory of a file cache
+const cacheActions = "actions"
+
 func main() {
+	started := time.Now()
+	logFormat := flag.String("log-format", envOrDefault("INPUT_LOG-FORMAT", defaultLogFormat()), "Log output format: text, json, or github")
+	logLevel := flag.String("log-level", envOrDefault("INPUT_LOG-LEVEL", defaultLogLevel()), "Minimum log level: debug, info, warn, or error")
+	operationFlag := flag.String("operation", envOrDefault("INPUT_OPERATION", operationVersion), "Operation to run, eg: version or init")
+	fromFlag := flag.String("from", os.Getenv("INPUT_FROM"), "For the init operation, the tool whose configuration is migrated: semantic-release")
+	interactive := flag.Bool("interactive", false, "For the init operation, confirm or rename each proposed component")
+	frozenTime := flag.String("frozen-time", "", "For debugging, run as if the current time is always this RFC 3339 time, eg: 2024-01-02T15:04:05Z")
+	commitLogFlag := flag.String("commit-log", "", "Generate versions from a git log in the commit log format, read from this file or - for standard input, instead of the GitHub API")
+	forceFlag := flag.Bool("force", false, "Release versions which aren't newer than the latest release, or whose tag already exists")
+	replay := flag.String("replay", "", "For debugging, dry run with the GitHub API responses recorded in this cassette, instead of calling the API")
+	flag.Parse()
+
+	logger := ensureNewLogger(*logFormat, *logLevel)
+	slog.SetDefault(logger)
+
+	var errs inputErrors
 	outputPath := os.Getenv("GITHUB_OUTPUT")
 	token := os.Getenv("INPUT_GITHUB-TOKEN")
-	component := os.Getenv("INPUT_COMPONENT")
-	label := os.Getenv("INPUT_LABEL")
-	isDryRun := isDryRun(os.Getenv("INPUT_DRY-RUN"))
-	initialVersion := os.Getenv("INPUT_INITIAL-VERSION")
-	defaultBranch := os.Getenv("INPUT_DEFAULT-BRANCH")
+	// Several components can be versioned in a single run by separating them with commas
+	components := splitList(os.Getenv("INPUT_COMPONENT"))
+	labels := splitList(os.Getenv("INPUT_LABEL"))
+	operation := *operationFlag
+	isDryRun := errs.yesNo("dry-run", os.Getenv("INPUT_DRY-RUN"))
+	commitSource := envOrDefault("INPUT_COMMIT-SOURCE", commitSourceGitHub)
+	isDraft := errs.yesNo("draft", os.Getenv("INPUT_DRAFT"))
+	transactional := errs.yesNo("transactional", os.Getenv("INPUT_TRANSACTIONAL"))
+	force := errs.yesNo("force", os.Getenv("INPUT_FORCE")) || *forceFlag
+	releaseTrain := os.Getenv("INPUT_RELEASE-TRAIN")
+	makeLatest := strings.ToLower(os.Getenv("INPUT_MAKE-LATEST"))
+	annotatedTags := errs.yesNo("annotated-tags", os.Getenv("INPUT_ANNOTATED-TAGS"))
+	signingKey := os.Getenv("INPUT_SIGNING-KEY")
+	aliasTags := splitList(strings.ToLower(os.Getenv("INPUT_ALIAS-TAGS")))
+	disabledFeatures := splitList(strings.ToLower(os.Getenv("INPUT_DISABLED-FEATURES")))
+	maintenanceBranches := os.Getenv("INPUT_MAINTENANCE-BRANCHES")
+	discussionCategory := os.Getenv("INPUT_DISCUSSION-CATEGORY")
+	deploymentEnvironment := os.Getenv("INPUT_DEPLOYMENT-ENVIRONMENT")
+	milestones := errs.yesNo("milestones", os.Getenv("INPUT_MILESTONES"))
+	nextMilestone := pkg.Bump(strings.ToLower(envOrDefault("INPUT_NEXT-MILESTONE", string(pkg.BumpNone))))
+	releasePullRequests := errs.yesNo("release-pull-requests", os.Getenv("INPUT_RELEASE-PULL-REQUESTS"))
+	locale := envOrDefault("INPUT_LOCALE", pkg.DefaultLocale)
+	notesFormat := strings.ToLower(envOrDefault("INPUT_NOTES-FORMAT", pkg.NotesMarkdown))
+	prereleaseBaseline := errs.yesNo("prerelease-baseline", os.Getenv("INPUT_PRERELEASE-BASELINE"))
+	provenance := errs.yesNo("provenance", os.Getenv("INPUT_PROVENANCE"))
+	sigstore := errs.yesNo("sigstore", os.Getenv("INPUT_SIGSTORE"))
+	sbom := os.Getenv("INPUT_SBOM")
+	dockerImage := os.Getenv("INPUT_DOCKER-IMAGE")
+	chartRegistryCredentials := pkg.RegistryCredentials{
+		Username: envOrDefault("INPUT_CHART-REGISTRY-USERNAME", os.Getenv("GITHUB_ACTOR")),
+		Password: envOrDefault("INPUT_CHART-REGISTRY-PASSWORD", token),
+	}
+	fulcioURL := envOrDefault("INPUT_FULCIO-URL", pkg.DefaultFulcioURL)
+	rekorURL := envOrDefault("INPUT_REKOR-URL", pkg.DefaultRekorURL)
+	otlpEndpoint := envOrDefault("INPUT_OTLP-ENDPOINT", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+	otlpHeaders, err := telemetry.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		errs.add("OTEL_EXPORTER_OTLP_HEADERS", "%s", err)
+	}
+	pushgatewayURL := os.Getenv("INPUT_PUSHGATEWAY-URL")
+	pushgatewayJob := envOrDefault("INPUT_PUSHGATEWAY-JOB", pkg.DefaultPushgatewayJob)
+	metricsFile := os.Getenv("INPUT_METRICS-FILE")
+	auditFile := os.Getenv("INPUT_AUDIT-LOG")
+	auditBranch := os.Getenv("INPUT_AUDIT-BRANCH")
+	configFile := envOrDefault("INPUT_CONFIG-FILE", pkg.DefaultConfigFile)
+	clock := errs.clock("frozen-time", *frozenTime)
+	recordCassette := os.Getenv("INPUT_RECORD-CASSETTE")
+	var replayed *cassette.Cassette
+	if *replay != "" {
+		var err error
+		if replayed, err = cassette.Load(*replay); err != nil {
+			errs.add("replay", "%s", err)
+		} else if *frozenTime == "" {
+			// Replay the run as of when it was recorded, so that time-dependent decisions are the same
+			clock = pkg.FrozenClock(replayed.Recorded)
+		}
+
+		// Nothing the run did is replayed against the API, but dry runs still explain what would have happened
+		isDryRun = true
+	}
+
+	if operation == operationInit {
+		// Initialising reads the checked out repository rather than GitHub, so it doesn't need a token, and can
+		// be run locally
+		errs.oneOf("from", *fromFlag, initFromSemanticRelease)
+		errs.exitIfAny(logger)
+		runInit(logger, envOrDefault("GITHUB_WORKSPACE", "."), configFile, *fromFlag, *interactive, isDryRun)
+		return
+	}
+
+	config, err := pkg.LoadConfig(configFile)
+	if err != nil {
+		errs.add("config-file", "%s", err)
+	}
+
+	channels := config.Channels
+	if hotfixBranches := os.Getenv("INPUT_HOTFIX-BRANCHES"); hotfixBranches != "" {
+		// Hotfix branches take precedence over configured channels
+		channels = append([]pkg.Channel{pkg.HotfixChannel(hotfixBranches)}, channels...)
+	}
+	tagger := pkg.Tagger{
+		Name:  envOrDefault("INPUT_TAGGER-NAME", "github-actions[bot]"),
+		Email: envOrDefault("INPUT_TAGGER-EMAIL", "41898282+github-actions[bot]@users.noreply.github.com"),
+	}
+	explain := errs.yesNo("explain", os.Getenv("INPUT_EXPLAIN"))
+	explainFile := os.Getenv("INPUT_EXPLAIN-FILE")
+	changelogFile := envOrDefault("INPUT_CHANGELOG-FILE", pkg.DefaultChangelogFile)
+	changelogDataDir := os.Getenv("INPUT_CHANGELOG-DATA-DIR")
+	feedDir := os.Getenv("INPUT_FEED-DIR")
+	noVersion := strings.ToLower(envOrDefault("INPUT_NO-VERSION", noVersionSuccess))
+	previewFile := os.Getenv("INPUT_PREVIEW-FILE")
+	exportEnv := errs.yesNo("export-env", os.Getenv("INPUT_EXPORT-ENV"))
+	envPrefix := os.Getenv("INPUT_ENV-PREFIX")
+	initialVersion := envOrDefault("INPUT_INITIAL-VERSION", pkg.DefaultInitialVersion)
+	defaultBranch := envOrDefault("INPUT_DEFAULT-BRANCH", "main")
+	tagTemplate := envOrDefault("INPUT_TAG-TEMPLATE", pkg.DefaultTagTemplate)
+	titleTemplate := envOrDefault("INPUT_TITLE-TEMPLATE", pkg.DefaultTitleTemplate)
+	timeout := errs.duration("timeout", os.Getenv("INPUT_TIMEOUT"))
+	requestTimeout := errs.duration("request-timeout", os.Getenv("INPUT_REQUEST-TIMEOUT"))
+	rootCAs := errs.certPool("ca-bundle", os.Getenv("INPUT_CA-BUNDLE"))
+	apiURL := envOrDefault("INPUT_API-URL", envOrDefault("GITHUB_API_URL", defaultAPIURL))
+	uploadURL := envOrDefault("INPUT_UPLOAD-URL", defaultUploadURL(apiURL))
+	errs.apiURL("api-url", apiURL)
+	errs.apiURL("upload-url", uploadURL)
+	maxCommits := errs.wholeNumber("max-commits", os.Getenv("INPUT_MAX-COMMITS"))
+	cache := os.Getenv("INPUT_CACHE")
+	mirrorRepository := os.Getenv("INPUT_MIRROR-REPOSITORY")
+	mirrorToken := os.Getenv("INPUT_MIRROR-TOKEN")
+	mirrorAPIURL := envOrDefault("INPUT_MIRROR-API-URL", defaultAPIURL)
+	if mirrorRepository != "" {
+		errs.repository("mirror-repository", mirrorRepository)
+		errs.required("mirror-token", mirrorToken)
+		errs.apiURL("mirror-api-url", mirrorAPIURL)
+	}
+	concurrency := errs.wholeNumber("concurrency", envOrDefault("INPUT_CONCURRENCY", "1"))
+	if concurrency == 0 {
+		errs.add("concurrency", "must be at least 1")
+	}
+	retentionDays := errs.wholeNumber("retention-days", os.Getenv("INPUT_RETENTION-DAYS"))
 	// owner/repository
 	ownerAndRepository := os.Getenv("GITHUB_REPOSITORY")
 	// Branch or tag
 	ref := os.Getenv("GITHUB_REF_NAME")
 	revision := os.Getenv("GITHUB_SHA")
+	// Another repository than the workflow's is versioned at the head of its default branch, as the workflow's ref
+	// and commit aren't in it
+	otherRepository := ""
+	if repository := os.Getenv("INPUT_REPOSITORY"); repository != "" && repository != ownerAndRepository {
+		errs.repository("repository", repository)
+		ownerAndRepository, otherRepository = repository, repository
+		ref, revision = defaultBranch, ""
+	}
+
+	// Several repositories can be queried in a single run, eg: by a platform team's central workflow
+	repositories := splitList(os.Getenv("INPUT_REPOSITORIES"))
+	for _, repository := range repositories {
+		errs.repository("repositories", repository)
+	}
+
+	if len(repositories) > 0 {
+		errs.oneOf("operation", operation, operationCurrent)
+	}
+	checkRun := errs.yesNo("check-run", os.Getenv("INPUT_CHECK-RUN"))
+	// Checks on pull requests must be created on the head commit, not the merge commit being built
+	checkRunSHA := revision
+	if strings.HasPrefix(os.Getenv("GITHUB_EVENT_NAME"), "pull_request") {
+		checkRunSHA = pullRequestHeadSHA(os.Getenv("GITHUB_EVENT_PATH"), revision)
+	}
+
+	prComment := errs.yesNo("pr-comment", os.Getenv("INPUT_PR-COMMENT"))
+	if otherRepository != "" && (prComment || checkRun) {
+		errs.add("repository", "%q isn't the workflow's repository, so its commits can't have checks or pull request comments", otherRepository)
+	}
+
+	var pullRequest int
+	var pullRequestLabels []string
+	if operation == operationCheck {
+		// Checks never release anything, so they only need read access
+		isDryRun = true
+	}
+
+	if (prComment || operation == operationCheck) && strings.HasPrefix(os.Getenv("GITHUB_EVENT_NAME"), "pull_request") {
+		// Preview the versions as if the pull request was merged into its base branch, without releasing them
+		pullRequest = errs.pullRequest("GITHUB_REF", os.Getenv("GITHUB_REF"))
+		ref = os.Getenv("GITHUB_BASE_REF")
+		pullRequestLabels = labelsOfPullRequest(os.Getenv("GITHUB_EVENT_PATH"))
+		isDryRun = true
+	}
+
+	var commitLog *pkg.CommitLog
+	offline := *commitLogFlag != "" || commitSource != commitSourceGitHub
+	switch {
+	case *commitLogFlag != "":
+		commitLog = errs.commitLog("commit-log", *commitLogFlag)
+	case commitSource == commitSourceGit:
+		commitLog = errs.gitLog("commit-source", envOrDefault("GITHUB_WORKSPACE", "."))
+	case commitSource != commitSourceGitHub:
+		commitLog = errs.commitHistory("commit-source", commitSource)
+	}
+
+	if offline {
+		if commitLog != nil && revision == "" {
+			revision = commitLog.Head()
+		}
+
+		// The log only has the history, so versions can be previewed but not released
+		errs.oneOf("operation", operation, operationVersion, operationCheck)
+		isDryRun = true
+		if operation == operationCheck {
+			// The pull request's commits can't be listed without the API, so every commit since the releases is checked
+			pullRequest = 0
+		}
+	}
+
+	if *replay == "" && !offline {
+		errs.required("github-token", token)
+	}
+
+	if operation == operationComponents || operation == operationHistory {
+		// Components are discovered rather than versioned, but the action still needs one to be created
+		components = append(components, "")
+	} else if len(components) == 0 {
+		errs.add("component", "must be provided")
+	}
+
+	errs.oneOf("operation", operation, operationVersion, operationPublish, operationCleanup, operationRollback, operationCurrent, operationChangelog, operationComponents, operationHistory, operationMigrate, operationInit, operationYank, operationNotes, operationCheck)
+	errs.oneOf("no-version", noVersion, noVersionSuccess, noVersionSkip, noVersionFail)
+	errs.oneOf("next-milestone", string(nextMilestone), string(pkg.BumpMajor), string(pkg.BumpMinor), string(pkg.BumpPatch), string(pkg.BumpNone))
+	errs.oneOf("locale", locale, pkg.Locales()...)
+	errs.oneOf("notes-format", notesFormat, pkg.NotesMarkdown, pkg.NotesText, pkg.NotesJSON)
+	errs.oneOf("make-latest", makeLatest, "true", "false", "legacy")
+	for _, alias := range aliasTags {
+		errs.oneOf("alias-tags", alias, pkg.AliasMajor, pkg.AliasLatest)
+	}
+
+	for _, feature := range disabledFeatures {
+		errs.oneOf("disabled-features", feature, pkg.Features()...)
+	}
+
+	errs.version("initial-version", initialVersion)
+	var targetVersion *semver.Version
+	if operation == operationRollback || operation == operationYank {
+		errs.required("version", os.Getenv("INPUT_VERSION"))
+		targetVersion = errs.version("version", os.Getenv("INPUT_VERSION"))
+	} else if operation == operationNotes {
+		targetVersion = errs.version("version", os.Getenv("INPUT_VERSION"))
+	}
+
+	var changelogFrom, changelogTo *semver.Version
+	if operation == operationChangelog {
+		errs.required("from", os.Getenv("INPUT_FROM"))
+		changelogFrom = errs.version("from", os.Getenv("INPUT_FROM"))
+		changelogTo = errs.version("to", os.Getenv("INPUT_TO"))
+	}
+
+	yankReason := os.Getenv("INPUT_YANK-REASON")
+	migrateFrom := os.Getenv("INPUT_MIGRATE-FROM")
+	if operation == operationMigrate {
+		errs.required("migrate-from", migrateFrom)
+		if migrateFrom != "" && !strings.Contains(migrateFrom, "{version}") {
+			errs.add("migrate-from", "%q has no {version} placeholder", migrateFrom)
+		}
+	}
+
+	if !strings.Contains(tagTemplate, "{component}") && (len(components) > 1 || len(components) == 1 && components[0] == allComponents) {
+		errs.add("tag-template", "%q has no {component} placeholder, so can only version a single component", tagTemplate)
+	}
+
+	if cache == cacheActions && (os.Getenv("ACTIONS_RESULTS_URL") == "" || os.Getenv("ACTIONS_RUNTIME_TOKEN") == "") {
+		errs.add("cache", "the Actions cache needs the runner's ACTIONS_RESULTS_URL and ACTIONS_RUNTIME_TOKEN, which older runners and GitHub Enterprise Server don't give actions, so use a directory instead")
+	}
+
+	if sigstore && os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") == "" {
+		errs.add("sigstore", "signing needs the workflow's OIDC token, so the job must have the id-token: write permission")
+	}
+
+	errs.repository("GITHUB_REPOSITORY", ownerAndRepository)
+	errs.required("GITHUB_REF_NAME", ref)
+	if otherRepository == "" {
+		errs.revision("GITHUB_SHA", revision)
+	}
+	errs.exitIfAny(logger)
+	transportClient := configureHTTPTransport(logger, rootCAs)
+
+	// Failures found once the run has ended set the exit code rather than exiting, so that the run's span is still
+	// ended and its telemetry exported first
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Bound the whole run so a hung API call fails the job rather than stalling it until the job limit
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Telemetry is only collected if there's a collector to export it to
+	var tracing *telemetry.Telemetry
+	if otlpEndpoint != "" {
+		tracing = telemetry.New(otlpEndpoint, otlpHeaders, map[string]string{
+			"service.name":      envOrDefault("OTEL_SERVICE_NAME", "monorepo-versioning"),
+			"service.version":   os.Getenv("GITHUB_ACTION_REF"),
+			"github.repository": ownerAndRepository,
+			"github.run_id":     os.Getenv("GITHUB_RUN_ID"),
+		})
+		defer shutdownTelemetry(logger, tracing)
+	}
+
+	var span *telemetry.Span
+	ctx, span = tracing.Start(ctx, operation, map[string]string{"operation": operation, "dry_run": yesNo(isDryRun)})
+	defer span.End()
+
+	httpClient := gitHubHTTPClient(logger, token, recordCassette, replayed, clock)
+	httpClient.Transport = tracing.Transport(httpClient.Transport)
+	if concurrency > 1 {
+		httpClient.Transport = pkg.ThrottledTransport(httpClient.Transport, logger)
+	}
+	client := ensureNewGitHubClient(httpClient, apiURL, uploadURL)
+	if otherRepository != "" && revision == "" {
+		if revision, err = pkg.BranchHead(ctx, client, otherRepository, ref); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	versioning, err := pkg.New(
+		pkg.WithRepository(ownerAndRepository),
+		pkg.WithComponent(components[0], labelAt(labels, 0)),
+		pkg.WithBranch(ref, defaultBranch),
+		pkg.WithRevision(revision),
+		pkg.WithInitialVersion(initialVersion),
+		pkg.WithTagTemplate(tagTemplate),
+		pkg.WithTitleTemplate(titleTemplate),
+		pkg.WithClient(client),
+		pkg.WithClock(clock),
+	)
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	versioning = versioning.
+		WithLogger(logger).
+		WithRequestTimeout(requestTimeout).
+		WithTelemetry(tracing).
+		WithMaxCommits(maxCommits).
+		WithActionVersion(os.Getenv("GITHUB_ACTION_REF")).
+		WithDraft(isDraft).
+		WithTransactionalReleases(transactional).
+		WithConcurrency(concurrency).
+		WithForce(force).
+		WithReleaseTrain(releaseTrain).
+		WithMakeLatest(makeLatest).
+		WithDisabledFeatures(disabledFeatures).
+		WithAliasTags(aliasTags).
+		WithMaintenanceBranches(maintenanceBranches).
+		WithPrereleaseBaseline(prereleaseBaseline).
+		WithLocale(locale).
+		WithNotesFormat(notesFormat).
+		WithSBOM(sbom).
+		WithChartRegistryCredentials(chartRegistryCredentials).
+		WithChannels(channels).
+		WithConfig(config).
+		WithReleasePullRequests(releasePullRequests).
+		WithPullRequest(pullRequest).
+		WithDiscussionCategory(discussionCategory).
+		WithDeploymentEnvironment(deploymentEnvironment).
+		WithMilestones(milestones, nextMilestone)
+
+	if commitLog != nil {
+		versioning = versioning.WithCommitLog(*commitLog)
+	}
+
+	// The commit log already has the whole history, so there's nothing to cache
+	switch {
+	case cache == "" || commitLog != nil:
+	case cache == cacheActions:
+		versioning = versioning.WithCache(pkg.NewActionsCache(transportClient, os.Getenv("ACTIONS_RESULTS_URL"), os.Getenv("ACTIONS_RUNTIME_TOKEN")))
+	default:
+		versioning = versioning.WithCache(pkg.NewFileCache(cache))
+	}
+
+	var auditLog *pkg.AuditLog
+	if auditFile != "" || auditBranch != "" {
+		runURL := fmt.Sprintf("%s/%s/actions/runs/%s", envOrDefault("GITHUB_SERVER_URL", "https://github.com"), ownerAndRepository, os.Getenv("GITHUB_RUN_ID"))
+		auditLog = pkg.NewAuditLog(os.Getenv("GITHUB_ACTOR"), os.Getenv("GITHUB_TRIGGERING_ACTOR"), runURL, auditedInputs(os.Environ()))
+		versioning = versioning.WithAuditLog(auditLog)
+	}
+
+	// Check the token before doing any work, so a misconfigured workflow gets an actionable error rather than a
+	// stack trace from the first failing call
+	readOnly := isDryRun || operation == operationCurrent || operation == operationChangelog || operation == operationComponents || operation == operationHistory
+	if commitLog != nil {
+		logger.Debug("Not checking access to the repository, as versions are generated from the commit log")
+	} else if err := versioning.Preflight(ctx, !readOnly); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	if mirrorRepository != "" && !readOnly {
+		// The mirror may be on another server, eg: GitHub.com for a repository on GitHub Enterprise Server
+		mirrorHTTPClient := gitHubHTTPClient(logger, mirrorToken, "", nil, clock)
+		mirrorHTTPClient.Transport = tracing.Transport(mirrorHTTPClient.Transport)
+		if concurrency > 1 {
+			mirrorHTTPClient.Transport = pkg.ThrottledTransport(mirrorHTTPClient.Transport, logger)
+		}
+
+		versioning = versioning.WithMirror(mirrorRepository, ensureNewGitHubClient(mirrorHTTPClient, mirrorAPIURL, defaultUploadURL(mirrorAPIURL)))
+		if err := versioning.PreflightMirror(ctx); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if provenance {
+		versioning = versioning.WithProvenance(pkg.Workflow{
+			ServerURL:         envOrDefault("GITHUB_SERVER_URL", "https://github.com"),
+			Ref:               os.Getenv("GITHUB_WORKFLOW_REF"),
+			Event:             os.Getenv("GITHUB_EVENT_NAME"),
+			RunID:             os.Getenv("GITHUB_RUN_ID"),
+			RunAttempt:        os.Getenv("GITHUB_RUN_ATTEMPT"),
+			RunnerEnvironment: envOrDefault("RUNNER_ENVIRONMENT", "github-hosted"),
+			RepositoryID:      os.Getenv("GITHUB_REPOSITORY_ID"),
+			RepositoryOwnerID: os.Getenv("GITHUB_REPOSITORY_OWNER_ID"),
+		})
+	}
+
+	if sigstore {
+		token := pkg.ActionsIdentityToken(transportClient, os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+		versioning = versioning.WithSigstore(pkg.NewSigstoreSigner(transportClient, fulcioURL, rekorURL, token))
+	}
 
-	versioning := pkg.NewAction(
-		ownerAndRepository,
-		component,
-		label,
-		ref,
-		revision,
-		initialVersion,
-		defaultBranch,
-		ensureNewGitHubClient(token))
+	// A signing key or Sigstore implies annotated tags, as lightweight tags have no tag object to sign
+	if annotatedTags || signingKey != "" || sigstore {
+		var signer pkg.TagSigner
+		if signingKey != "" {
+			var err error
+			signer, err = pkg.NewTagSigner(signingKey, os.Getenv("INPUT_SIGNING-KEY-PASSPHRASE"))
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		versioning = versioning.WithAnnotatedTags(tagger, signer)
+	}
+
+	if len(config.Discover) > 0 {
+		config = versioning.DiscoverComponents(ctx)
+		versioning = versioning.WithConfig(config)
+	}
+
+	if len(components) == 1 && components[0] == allComponents {
+		// Version every configured and discovered component, so new components don't need workflow changes
+		components, labels = config.ComponentNames(), nil
+		if len(components) == 0 {
+			logger.Error("Invalid input component: * versions every configured component, but none are configured or discovered")
+			os.Exit(1)
+		}
+	}
+
+	var actions []pkg.VersioningAction
+	for i, component := range components {
+		actions = append(actions, versioning.ForComponent(component, labelAt(labels, i)))
+	}
+
+	// Check that tags and version file commits aren't blocked by protection rules before releasing anything
+	if commitLog == nil && !isDryRun && (operation == operationVersion || operation == operationPublish) {
+		for _, action := range actions {
+			if err := action.PreflightProtection(ctx); err != nil {
+				logger.Error(err.Error())
+				os.Exit(1)
+			}
+		}
+	}
+
+	var results []pkg.Result
+	var violations []pkg.CheckViolation
+	reportMetrics := func(failed bool) {
+		if pushgatewayURL != "" || metricsFile != "" {
+			metrics := pkg.SummarizeRun(versioning.Repository(), operation, results, started, time.Now(), failed)
+			reportRunMetrics(logger, pushgatewayURL, pushgatewayJob, metricsFile, metrics)
+		}
+	}
+
+	recordAudit := func() bool {
+		return auditLog == nil || writeAuditLog(logger, versioning, auditLog, auditFile, auditBranch)
+	}
+
+	// Record the run's changes and report its metrics however it ends, as failed runs need covering too
+	defer func() {
+		recovered := recover()
+		audited := recordAudit()
+		reportMetrics(recovered != nil || !audited)
+		if recovered != nil {
+			panic(recovered)
+		}
+
+		if !audited {
+			span.Fail("could not write the audit log")
+			exitCode = 1
+		}
+	}()
+
+	switch operation {
+	case operationVersion:
+		results = pkg.GenerateVersions(ctx, actions, isDryRun)
+		versioning.UpdateVersionsManifest(ctx, results)
+		train := versioning.TagReleaseTrain(ctx, results, isDryRun)
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("train=%s\n", train))
+		})
+		if pullRequest != 0 {
+			commentURL := versioning.CommentOnPullRequest(ctx, results)
+			appendOutputs(outputPath, func(output *os.File) {
+				output.WriteString(fmt.Sprintf("pr_comment_url=%s\n", commentURL))
+			})
+		}
+
+		if checkRun {
+			checkRunURL := versioning.CreateCheckRun(ctx, checkRunSHA, results)
+			appendOutputs(outputPath, func(output *os.File) {
+				output.WriteString(fmt.Sprintf("check_run_url=%s\n", checkRunURL))
+			})
+		}
+	case operationCheck:
+		report := pkg.Check(ctx, actions, pullRequestLabels)
+		results, violations = report.Results, report.Violations
+		contents, err := json.Marshal(violations)
+		if err != nil {
+			panic(err)
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("violations=%s\n", contents))
+		})
+	case operationPublish:
+		for _, action := range actions {
+			results = append(results, action.PublishDraft(ctx, isDryRun))
+		}
+
+		versioning.UpdateVersionsManifest(ctx, results)
+	case operationCleanup:
+		maxAge := time.Duration(retentionDays) * 24 * time.Hour
+		var deletedTags []string
+		for _, action := range actions {
+			deletedTags = append(deletedTags, action.CleanupPrereleases(ctx, maxAge, isDryRun)...)
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("deleted_tags=%s\n", strings.Join(deletedTags, ",")))
+		})
+		return
+	case operationRollback:
+		rolledBack := false
+		for _, action := range actions {
+			rolledBack = action.Rollback(ctx, targetVersion, isDryRun) || rolledBack
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("rolled_back=%s\n", yesNo(rolledBack)))
+		})
+		return
+	case operationNotes:
+		var updatedTags []string
+		for _, action := range actions {
+			updatedTags = append(updatedTags, action.RegenerateNotes(ctx, targetVersion, isDryRun)...)
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("updated_tags=%s\n", strings.Join(updatedTags, ",")))
+		})
+		return
+	case operationYank:
+		var yanked []pkg.YankResult
+		for _, action := range actions {
+			yanked = append(yanked, action.Yank(ctx, targetVersion, yankReason, isDryRun))
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			var tags []string
+			for _, result := range yanked {
+				tags = append(tags, result.Tag)
+				prefix := ""
+				if len(yanked) > 1 {
+					prefix = outputPrefix(result.Component)
+				}
+
+				replacement := ""
+				if result.Replacement != nil {
+					replacement = result.Replacement.String()
+				}
+
+				output.WriteString(fmt.Sprintf("%sreplacement_version=%s\n", prefix, replacement))
+			}
+
+			output.WriteString(fmt.Sprintf("yanked_tags=%s\n", strings.Join(tags, ",")))
+		})
+		return
+	case operationCurrent:
+		if len(repositories) > 0 {
+			writeRepositoriesCurrentOutputs(ctx, logger, outputPath, actions, repositories)
+			return
+		}
+
+		var current []pkg.CurrentVersions
+		for _, action := range actions {
+			current = append(current, action.CurrentVersions(ctx))
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			for _, versions := range current {
+				prefix := ""
+				if len(current) > 1 {
+					prefix = outputPrefix(versions.Component)
+				}
 
-	newVersion := versioning.GenerateVersion(isDryRun)
+				writeCurrentOutputs(output, prefix, versions)
+			}
+		})
+		return
+	case operationChangelog:
+		var changelogs []string
+		for _, action := range actions {
+			changelogs = append(changelogs, action.ChangelogBetween(ctx, changelogFrom, changelogTo))
+		}
+
+		changelog := strings.Join(changelogs, "\n")
+		if notesFormat == pkg.NotesJSON {
+			// One JSON document for every component, so that it can be parsed with fromJSON
+			changelog = fmt.Sprintf("[%s]", strings.Join(changelogs, ",\n"))
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			writeMultilineOutput(output, "changelog", changelog)
+		})
+		appendOutputs(os.Getenv("GITHUB_STEP_SUMMARY"), func(summary *os.File) {
+			if notesFormat != pkg.NotesMarkdown {
+				// Keep other formats as they are, rather than rendering them as markdown
+				changelog = fmt.Sprintf("```\n%s\n```\n", changelog)
+			}
+
+			summary.WriteString(changelog)
+		})
+		return
+	case operationHistory:
+		history := versioning.ReleaseHistory(ctx)
+		if err := pkg.WriteAggregatedChangelog(changelogFile, history); err != nil {
+			logger.Error(fmt.Sprintf("Could not write %s: %s", changelogFile, err))
+			os.Exit(1)
+		}
+
+		if changelogDataDir != "" {
+			if err := pkg.WriteChangelogData(changelogDataDir, history); err != nil {
+				logger.Error(fmt.Sprintf("Could not write changelog data to %s: %s", changelogDataDir, err))
+				os.Exit(1)
+			}
+		}
+
+		if feedDir != "" {
+			if err := versioning.WriteFeeds(feedDir, history); err != nil {
+				logger.Error(fmt.Sprintf("Could not write feeds to %s: %s", feedDir, err))
+				os.Exit(1)
+			}
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("changelog_file=%s\n", changelogFile))
+		})
+		return
+	case operationComponents:
+		discovered := versioning.ListComponents(ctx)
+		contents, err := json.Marshal(discovered)
+		if err != nil {
+			panic(err)
+		}
+
+		var names []string
+		for _, component := range discovered {
+			names = append(names, component.Component)
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("components=%s\n", contents))
+			output.WriteString(fmt.Sprintf("component_names=%s\n", strings.Join(names, ",")))
+		})
+		return
+	case operationMigrate:
+		var migratedTags []string
+		for _, action := range actions {
+			migratedTags = append(migratedTags, action.Migrate(ctx, migrateFrom, isDryRun)...)
+		}
+
+		appendOutputs(outputPath, func(output *os.File) {
+			output.WriteString(fmt.Sprintf("migrated_tags=%s\n", strings.Join(migratedTags, ",")))
+		})
+		return
+	default:
+		panic(fmt.Sprintf("Unknown operation %q, expected one of: %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s", operation, operationVersion, operationPublish, operationCleanup, operationRollback, operationCurrent, operationChangelog, operationComponents, operationHistory, operationMigrate, operationInit, operationYank, operationNotes, operationCheck))
+	}
+
+	if isDryRun {
+		logger.Info("Is dry run? Yes")
+	}
+
+	// Always keep the decision trace available in debug logs, even if an explanation wasn't requested
+	explainLevel := slog.LevelDebug
+	if explain {
+		explainLevel = slog.LevelInfo
+	}
+
+	var unversioned, unreleased []string
+	for _, result := range results {
+		result.LogExplanation(logger, explainLevel)
+		if result.Frozen != "" {
+			logger.Warn("New version generated? Yes, but not released", "component", result.Component, "version", result.Version.String(), "frozen", result.Frozen)
+			unreleased = append(unreleased, result.Component)
+		} else if result.Held != "" {
+			logger.Warn("New version generated? Yes, but not released", "component", result.Component, "version", result.Version.String(), "held", result.Held)
+			unreleased = append(unreleased, result.Component)
+		} else if result.Version == nil {
+			logger.Info("New version generated? No", "component", result.Component)
+			unversioned = append(unversioned, result.Component)
+		} else {
+			logger.Info("New version generated? Yes", "component", result.Component, "version", result.Version.String(), "prerelease", result.Version.Prerelease() != "")
+		}
+	}
+
+	if explainFile != "" {
+		if err := pkg.WriteExplanation(explainFile, results); err != nil {
+			panic(err)
+		}
+	}
 
 	if isDryRun {
-		fmt.Println("Is dry run? Yes")
+		writePreviews(previewFile, os.Getenv("GITHUB_STEP_SUMMARY"), results)
+	}
+
+	// Frozen and held versions weren't released, so later steps shouldn't publish them
+	for i := range results {
+		if results[i].Frozen != "" || results[i].Held != "" {
+			results[i].Version, results[i].ModuleSource, results[i].Preview = nil, "", nil
+		}
+	}
+
+	if exportEnv {
+		// Environment variables are more convenient than outputs for later script steps
+		appendOutputs(os.Getenv("GITHUB_ENV"), func(env *os.File) {
+			for _, result := range results {
+				writeResultEnv(env, envPrefixFor(envPrefix, result.Component, len(results) > 1), result)
+			}
+		})
+	}
+
+	appendOutputs(outputPath, func(output *os.File) {
+		if len(results) == 1 {
+			writeResultOutputs(output, "", results[0], dockerImage)
+		} else {
+			// Prefix the outputs with the component name so that each component's version can be referenced
+			for _, result := range results {
+				writeResultOutputs(output, outputPrefix(result.Component), result, dockerImage)
+			}
+		}
+
+		if operation == operationVersion {
+			output.WriteString(fmt.Sprintf("skipped=%s\n", yesNo(len(unreleased) > 0 || noVersion == noVersionSkip && len(unversioned) > 0)))
+		}
+	})
+
+	if len(unversioned) > 0 && operation == operationVersion {
+		switch noVersion {
+		case noVersionSkip:
+			logger.Warn("No new version generated, skipping", "components", strings.Join(unversioned, ","))
+		case noVersionFail:
+			// Fail after writing the outputs, so that they're still available to steps which run on failure
+			logger.Error("No new version generated: there are no commits since the previous version which bump it", "components", strings.Join(unversioned, ","))
+			span.Fail("no new version generated")
+			span.End()
+			recordAudit()
+			reportMetrics(true)
+			shutdownTelemetry(logger, tracing)
+			os.Exit(1)
+		}
+	}
+
+	if len(violations) > 0 {
+		// Fail after writing the outputs and previews, so that the pull request's versions can still be reviewed
+		logger.Error(fmt.Sprintf("Check failed: %d rules were broken", len(violations)))
+		span.Fail("check failed")
+		span.End()
+		recordAudit()
+		reportMetrics(true)
+		shutdownTelemetry(logger, tracing)
+		os.Exit(1)
+	}
+}
+
+// shutdownTelemetry exports the run's telemetry, warning rather than failing the run if it can't be exported
+func shutdownTelemetry(logger *slog.Logger, tracing *telemetry.Telemetry) {
+	// The run's own timeout may have expired, but the telemetry of a timed out run is the most useful
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		logger.Warn(err.Error())
+	}
+}
+
+// auditedInputs of the action, as "name=value" environment variables, without secrets
+func auditedInputs(environ []string) map[string]string {
+	inputs := make(map[string]string)
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(name, "INPUT_") && !slices.Contains(secretInputs, name) {
+			inputs[name] = value
+		}
+	}
+
+	return inputs
+}
+
+// writeAuditLog appends the run's changes to the audit log file and branch, whichever were requested, returning
+// whether they were recorded. Changes which were made but couldn't be recorded fail the run.
+func writeAuditLog(logger *slog.Logger, versioning pkg.VersioningAction, auditLog *pkg.AuditLog, auditFile string, auditBranch string) (ok bool) {
+	ok = true
+	if auditFile != "" {
+		if err := auditLog.Append(auditFile); err != nil {
+			logger.Error(fmt.Sprintf("Could not write the audit log to %s: %s", auditFile, err))
+			ok = false
+		}
+	}
+
+	if auditBranch != "" {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logger.Error(fmt.Sprint(recovered))
+				ok = false
+			}
+		}()
+
+		// The run's own timeout may have expired, but changes made before it did still need recording
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		versioning.CommitAuditLog(ctx, auditBranch)
+	}
+
+	return ok
+}
+
+// reportRunMetrics by pushing them to a Prometheus Pushgateway and writing them to a JSON file, whichever were
+// requested, warning rather than failing the run if they can't be
+func reportRunMetrics(logger *slog.Logger, pushgatewayURL string, job string, metricsFile string, metrics pkg.RunMetrics) {
+	if metricsFile != "" {
+		if err := pkg.WriteRunMetrics(metricsFile, metrics); err != nil {
+			logger.Warn(fmt.Sprintf("Could not write metrics to %s: %s", metricsFile, err))
+		}
+	}
+
+	if pushgatewayURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pkg.PushRunMetrics(ctx, pushgatewayURL, job, metrics); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+}
+
+// appendOutputs calls write with the GitHub output file, if it exists. This makes it easier to test changes
+// locally when no output file is specified.
+func appendOutputs(outputPath string, write func(output *os.File)) {
+	if _, err := os.Stat(outputPath); err != nil {
+		return
+	}
+
+	output, err := os.OpenFile(outputPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	defer output.Close()
+	write(output)
+}
+
+// writeResultOutputs for a component's result, with each output name starting with prefix. Docker tags are
+// qualified with the image, if one was provided.
+func writeResultOutputs(output *os.File, prefix string, result pkg.Result, dockerImage string) {
+	writeVersionOutputs(output, prefix, result.Version)
+	output.WriteString(fmt.Sprintf("%sfrozen=%s\n", prefix, yesNo(result.Frozen != "")))
+	output.WriteString(fmt.Sprintf("%sheld=%s\n", prefix, yesNo(result.Held != "")))
+	if result.PreviousVersion == nil {
+		output.WriteString(fmt.Sprintf("%sprevious_version=\n", prefix))
+	} else {
+		output.WriteString(fmt.Sprintf("%sprevious_version=%s\n", prefix, result.PreviousVersion.String()))
+	}
+
+	output.WriteString(fmt.Sprintf("%sbump_type=%s\n", prefix, result.Bump))
+	output.WriteString(fmt.Sprintf("%schannel=%s\n", prefix, result.Channel))
+	output.WriteString(fmt.Sprintf("%scommit_count=%d\n", prefix, result.IncludedCommits()))
+	output.WriteString(fmt.Sprintf("%srelease_pr_url=%s\n", prefix, result.ReleasePullRequestURL))
+	output.WriteString(fmt.Sprintf("%sdependency_pr_url=%s\n", prefix, result.DependencyPullRequestURL))
+	output.WriteString(fmt.Sprintf("%sdocker_tags=%s\n", prefix, strings.Join(dockerTags(dockerImage, result), ",")))
+	output.WriteString(fmt.Sprintf("%schart=%s\n", prefix, result.Chart))
+	output.WriteString(fmt.Sprintf("%smodule_source=%s\n", prefix, result.ModuleSource))
+	if result.DistTag == "" {
+		output.WriteString(fmt.Sprintf("%spublish_needed=no\n", prefix))
+	} else {
+		output.WriteString(fmt.Sprintf("%spublish_needed=yes\n", prefix))
+	}
+
+	output.WriteString(fmt.Sprintf("%sdist_tag=%s\n", prefix, result.DistTag))
+	// Release outputs are empty if no release was created, eg: in a dry run
+	if result.Release == nil {
+		output.WriteString(fmt.Sprintf("%srelease_id=\n", prefix))
+		output.WriteString(fmt.Sprintf("%supload_url=\n", prefix))
+		output.WriteString(fmt.Sprintf("%shtml_url=\n", prefix))
+		output.WriteString(fmt.Sprintf("%sprovenance_url=\n", prefix))
+		output.WriteString(fmt.Sprintf("%ssbom_url=\n", prefix))
+		output.WriteString(fmt.Sprintf("%smirror_html_url=\n", prefix))
+	} else {
+		output.WriteString(fmt.Sprintf("%srelease_id=%d\n", prefix, result.Release.ID))
+		output.WriteString(fmt.Sprintf("%supload_url=%s\n", prefix, result.Release.UploadURL))
+		output.WriteString(fmt.Sprintf("%shtml_url=%s\n", prefix, result.Release.HTMLURL))
+		output.WriteString(fmt.Sprintf("%sprovenance_url=%s\n", prefix, result.Release.ProvenanceURL))
+		output.WriteString(fmt.Sprintf("%ssbom_url=%s\n", prefix, result.Release.SBOMURL))
+		if result.MirrorRelease == nil {
+			output.WriteString(fmt.Sprintf("%smirror_html_url=\n", prefix))
+		} else {
+			output.WriteString(fmt.Sprintf("%smirror_html_url=%s\n", prefix, result.MirrorRelease.HTMLURL))
+		}
+	}
+}
+
+// dockerTags recommended for a result's version, qualified with the image if one was provided, where "{component}"
+// is replaced with the component name, eg: "ghcr.io/owner/{component}:1.4.2"
+func dockerTags(image string, result pkg.Result) []string {
+	tags := result.DockerTags()
+	if image == "" {
+		return tags
+	}
+
+	image = strings.ReplaceAll(image, "{component}", strings.ToLower(result.Component))
+	for i, tag := range tags {
+		tags[i] = fmt.Sprintf("%s:%s", image, tag)
 	}
 
+	return tags
+}
+
+// writeRepositoriesCurrentOutputs finds the current versions of the components in each of several repositories,
+// writing the outputs of each component prefixed with its repository, eg: "owner_repository_api_version", and
+// every version as JSON
+func writeRepositoriesCurrentOutputs(ctx context.Context, logger *slog.Logger, outputPath string, actions []pkg.VersioningAction, repositories []string) {
+	var current []pkg.CurrentVersions
+	for _, repository := range repositories {
+		for _, action := range actions {
+			action = action.ForRepository(repository)
+			if err := action.Preflight(ctx, false); err != nil {
+				logger.Error(err.Error())
+				os.Exit(1)
+			}
+
+			current = append(current, action.CurrentVersions(ctx))
+		}
+	}
+
+	contents, err := json.Marshal(current)
+	if err != nil {
+		panic(err)
+	}
+
+	appendOutputs(outputPath, func(output *os.File) {
+		output.WriteString(fmt.Sprintf("versions=%s\n", contents))
+		for _, versions := range current {
+			writeCurrentOutputs(output, outputPrefix(versions.Repository+"/"+versions.Component), versions)
+		}
+	})
+}
+
+// writeCurrentOutputs for a component's newest released versions, with each output name starting with prefix
+func writeCurrentOutputs(output *os.File, prefix string, current pkg.CurrentVersions) {
+	version, prereleaseVersion := "", ""
+	if current.Stable != nil {
+		version = current.Stable.String()
+	}
+
+	if current.Prerelease != nil {
+		prereleaseVersion = current.Prerelease.String()
+	}
+
+	output.WriteString(fmt.Sprintf("%sversion=%s\n", prefix, version))
+	output.WriteString(fmt.Sprintf("%stag=%s\n", prefix, current.StableTagName()))
+	output.WriteString(fmt.Sprintf("%sprerelease_version=%s\n", prefix, prereleaseVersion))
+	output.WriteString(fmt.Sprintf("%sprerelease_tag=%s\n", prefix, current.PrereleaseTagName()))
+}
+
+// writeMultilineOutput writes an output whose value may contain newlines, using a delimiter which can't appear in
+// the value
+func writeMultilineOutput(output *os.File, name string, value string) {
+	delimiter := fmt.Sprintf("EOF_%d", time.Now().UnixNano())
+	for strings.Contains(value, delimiter) {
+		delimiter += "_"
+	}
+
+	output.WriteString(fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter))
+}
+
+// writeVersionOutputs for a generated version, with each output name starting with prefix
+func writeVersionOutputs(output *os.File, prefix string, newVersion *semver.Version) {
 	if newVersion == nil {
-		fmt.Println("New version generated? No")
+		output.WriteString(fmt.Sprintf("%snew_version_created=no\n", prefix))
+		output.WriteString(fmt.Sprintf("%sversion=0.0.0-none\n", prefix))
+		output.WriteString(fmt.Sprintf("%sprerelease=no\n", prefix))
 	} else {
-		fmt.Println("New version generated? Yes")
-		fmt.Printf("Is pre-release? %t\n", newVersion.Prerelease() != "")
-		fmt.Printf("New version: %s\n", newVersion.String())
+		output.WriteString(fmt.Sprintf("%snew_version_created=yes\n", prefix))
+		output.WriteString(fmt.Sprintf("%sversion=%s\n", prefix, newVersion.String()))
+		if newVersion.Prerelease() == "" {
+			output.WriteString(fmt.Sprintf("%sprerelease=no\n", prefix))
+		} else {
+			output.WriteString(fmt.Sprintf("%sprerelease=yes\n", prefix))
+		}
 	}
+}
 
-	// Only attempt to write to the GitHub output path if it exists
-	// This makes it easier to test changes locally when no output file is specified
-	if _, err := os.Stat(outputPath); err == nil {
-		output, err := os.OpenFile(outputPath, os.O_APPEND|os.O_WRONLY, 0644)
+// writePreviews of the releases a dry run would have created to the preview file, if one was requested, and the
+// step summary, so that they can be checked before merging
+func writePreviews(previewFile string, summaryPath string, results []pkg.Result) {
+	if previewFile != "" {
+		preview, err := os.Create(previewFile)
 		if err != nil {
 			panic(err)
 		}
 
-		defer output.Close()
+		defer preview.Close()
+		if err := pkg.WritePreviews(preview, results); err != nil {
+			panic(err)
+		}
+	}
 
-		if newVersion == nil {
-			output.WriteString("new_version_created=no\n")
-			output.WriteString("version=0.0.0-none\n")
-			output.WriteString("prerelease=no\n")
-		} else {
-			output.WriteString("new_version_created=yes\n")
-			output.WriteString(fmt.Sprintf("version=%s\n", newVersion.String()))
-			if newVersion.Prerelease() == "" {
-				output.WriteString("prerelease=no\n")
-			} else {
-				output.WriteString("prerelease=yes\n")
-			}
+	appendOutputs(summaryPath, func(summary *os.File) {
+		if err := pkg.WritePreviews(summary, results); err != nil {
+			panic(err)
 		}
+	})
+}
+
+// writeResultEnv exports a component's version to later steps, with each variable name starting with prefix
+func writeResultEnv(env *os.File, prefix string, result pkg.Result) {
+	if result.Version == nil {
+		env.WriteString(fmt.Sprintf("%sVERSION=\n", prefix))
+	} else {
+		env.WriteString(fmt.Sprintf("%sVERSION=%s\n", prefix, result.Version.String()))
+	}
+
+	env.WriteString(fmt.Sprintf("%sPRERELEASE=%s\n", prefix, yesNo(result.Version != nil && result.Version.Prerelease() != "")))
+	env.WriteString(fmt.Sprintf("%sTAG=%s\n", prefix, result.TagName()))
+}
+
+// envPrefixFor a component's environment variables. By default the component name is used, eg: "API_VERSION". A
+// custom prefix is used as-is, unless multiple components are versioned so that their variables must be told
+// apart, eg: "RELEASE_API_VERSION".
+func envPrefixFor(prefix string, component string, multipleComponents bool) string {
+	componentPrefix := fmt.Sprintf("%s_", strings.ToUpper(regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString(component, "_")))
+	if prefix == "" {
+		return componentPrefix
+	}
+
+	if multipleComponents {
+		return prefix + componentPrefix
+	}
+
+	return prefix
+}
+
+// unsafeOutputCharacters are replaced in output names, so that they can be read with expressions like
+// steps.version.outputs.services_payment-api_version
+var unsafeOutputCharacters = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// outputPrefix for a component's outputs when multiple components are versioned, eg: "api_version", or
+// "services_payment-api_version" for "services/Payment-API"
+func outputPrefix(component string) string {
+	return fmt.Sprintf("%s_", unsafeOutputCharacters.ReplaceAllString(strings.ToLower(component), "_"))
+}
+
+// splitList splits a comma-separated input into its trimmed, non-empty values
+func splitList(input string) []string {
+	var values []string
+	for _, value := range strings.Split(input, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// labelAt gets the label for the component at index i, if one was provided
+func labelAt(labels []string, i int) string {
+	if i < len(labels) {
+		return labels[i]
 	}
+
+	return ""
 }
 
-func isDryRun(input string) bool {
+// yesNo formats a boolean output
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// isEnabled checks whether a yes/no input is enabled
+func isEnabled(input string) bool {
 	return strings.EqualFold(input, "yes") || strings.EqualFold(input, "true")
 }
 
-// Create an HTTP client which communicates with the GitHub API using a token.
+// ensureNewLogger creates the logger for the run, panicking if the format or level is invalid
+func ensureNewLogger(format string, levelName string) *slog.Logger {
+	level, err := pkg.ParseLogLevel(levelName)
+	if err != nil {
+		panic(err)
+	}
+
+	logger, err := pkg.NewLogger(os.Stdout, format, level)
+	if err != nil {
+		panic(err)
+	}
+
+	return logger
+}
+
+// defaultLogFormat uses workflow commands when running in GitHub Actions, so that warnings are highlighted
+func defaultLogFormat() string {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		return pkg.LogFormatGitHub
+	}
+
+	return pkg.LogFormatText
+}
+
+// defaultLogLevel logs debug messages when the workflow is re-run with debug logging enabled
+func defaultLogLevel() string {
+	if os.Getenv("RUNNER_DEBUG") == "1" {
+		return "debug"
+	}
+
+	return "info"
+}
+
+// labelsOfPullRequest reads the names of the pull request's labels from the event payload, or none if the payload
+// can't be read
+func labelsOfPullRequest(eventPath string) []string {
+	contents, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil
+	}
+
+	var event struct {
+		PullRequest struct {
+			Labels []struct {
+				Name string `json:"name"`
+			} `json:"labels"`
+		} `json:"pull_request"`
+	}
+
+	if err := json.Unmarshal(contents, &event); err != nil {
+		return nil
+	}
+
+	var labels []string
+	for _, label := range event.PullRequest.Labels {
+		labels = append(labels, label.Name)
+	}
+
+	return labels
+}
+
+// pullRequestHeadSHA reads the head commit of the pull request from the event payload, or fallback if the payload
+// can't be read
+func pullRequestHeadSHA(eventPath string, fallback string) string {
+	contents, err := os.ReadFile(eventPath)
+	if err != nil {
+		return fallback
+	}
+
+	var event struct {
+		PullRequest struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}
+
+	if err := json.Unmarshal(contents, &event); err != nil || event.PullRequest.Head.SHA == "" {
+		return fallback
+	}
+
+	return event.PullRequest.Head.SHA
+}
+
+// runInit generates a starter configuration file and a sample workflow from the repository checked out at root,
+// either by migrating another tool's configuration, or by proposing a component for each directory with a
+// manifest. Existing files are never overwritten. If interactive is true, each proposed component is confirmed on
+// the terminal. If dryRun is true, the files are only logged.
+func runInit(logger *slog.Logger, root string, configFile string, from string, interactive bool, dryRun bool) {
+	if _, err := os.Stat(configFile); err == nil {
+		logger.Error(fmt.Sprintf("Configuration file %s already exists", configFile))
+		os.Exit(1)
+	}
+
+	var scaffold pkg.Scaffold
+	var err error
+	if from == initFromSemanticRelease {
+		scaffold, err = pkg.InitFromSemanticRelease(root)
+	} else {
+		scaffold, err = pkg.InitFromManifests(root)
+	}
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	if interactive {
+		scaffold = confirmComponents(scaffold, bufio.NewReader(os.Stdin), os.Stdout)
+	}
+
+	for _, note := range scaffold.Notes {
+		logger.Warn(note)
+	}
+
+	writeScaffoldFile(logger, configFile, scaffold.ConfigYAML(), dryRun)
+	writeScaffoldFile(logger, filepath.Join(root, ".github", "workflows", "release.yml"), scaffold.WorkflowYAML(), dryRun)
+}
+
+// confirmComponents asks whether to keep each proposed component, which can also be renamed by answering with a
+// new name
+func confirmComponents(scaffold pkg.Scaffold, in *bufio.Reader, out io.Writer) pkg.Scaffold {
+	components := make(map[string]pkg.ComponentConfig)
+	for _, name := range scaffold.Config.ComponentNames() {
+		component := scaffold.Config.Components[name]
+		location := "the repository root"
+		if component.Path != "" {
+			location = component.Path
+		}
+
+		fmt.Fprintf(out, "Add component %s in %s? [Y/n/new name]: ", name, location)
+		answer, _ := in.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		switch strings.ToLower(answer) {
+		case "", "y", "yes":
+			components[name] = component
+		case "n", "no":
+		default:
+			components[strings.ToLower(answer)] = component
+		}
+	}
+
+	scaffold.Config.Components = components
+	return scaffold
+}
+
+// writeScaffoldFile creates a file generated by the init operation, unless it already exists. If dryRun is true,
+// the file is only logged.
+func writeScaffoldFile(logger *slog.Logger, filePath string, contents []byte, dryRun bool) {
+	if dryRun {
+		logger.Info("Would write file, but this is a dry run", "path", filePath, "contents", string(contents))
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		panic(err)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		logger.Warn("File already exists, so it was left alone", "path", filePath)
+		return
+	}
+
+	if err != nil {
+		logger.Error(fmt.Sprintf("Could not create %s: %s", filePath, err))
+		os.Exit(1)
+	}
+
+	defer file.Close()
+	if _, err := file.Write(contents); err != nil {
+		panic(err)
+	}
+
+	logger.Info("Wrote file, review it before committing", "path", filePath)
+}
+
+// envOrDefault gets an environment variable, or fallback if it is not set or empty
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// configureHTTPTransport makes every HTTP client use the proxy from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+// environment variables, and trust the CA bundle's certificates as well as the system's, if one was provided. The
+// default transport is replaced, so that the GitHub API, registries and notifications all use them, and the client
+// returned uses them too, for the services which are given a client, eg: Sigstore and the Actions cache.
+func configureHTTPTransport(logger *slog.Logger, rootCAs *x509.CertPool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if rootCAs != nil {
+		logger.Debug("Trusting the certificates of the CA bundle")
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	}
+
+	http.DefaultTransport = transport
+	return &http.Client{Transport: transport}
+}
+
+// gitHubHTTPClient authenticates requests to the GitHub API with a token, recording them to a cassette if one was
+// requested. When replaying a cassette, requests are answered by it instead.
+func gitHubHTTPClient(logger *slog.Logger, token string, recordCassette string, replayed *cassette.Cassette, clock pkg.Clock) *http.Client {
+	if replayed != nil {
+		return &http.Client{Transport: replayed.Transport()}
+	}
+
+	httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	if recordCassette != "" {
+		// Requests are written as they're made, so the cassette doesn't need closing before the run exits
+		recorder, err := cassette.Record(recordCassette, clock.Now(), httpClient.Transport)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Could not record the cassette: %s", err))
+			os.Exit(1)
+		}
+
+		httpClient.Transport = recorder
+	}
+
+	return httpClient
+}
+
+// defaultAPIURL is GitHub.com's API, used when neither the api-url input nor GITHUB_API_URL are set
+const defaultAPIURL = "https://api.github.com"
+
+// defaultUploadURL is the URL release assets are uploaded to for an API URL. GitHub.com and GitHub Enterprise
+// Cloud upload to an "uploads." host beside the "api." one, while GitHub Enterprise Server uploads to
+// "/api/uploads" on its own host, eg: "https://github.example.com/api/uploads" for
+// "https://github.example.com/api/v3".
+func defaultUploadURL(apiURL string) string {
+	parsed, err := url.Parse(apiURL)
+	if err != nil {
+		return apiURL
+	}
+
+	if host, ok := strings.CutPrefix(parsed.Host, "api."); ok {
+		parsed.Host = "uploads." + host
+		parsed.Path = "/"
+		return parsed.String()
+	}
+
+	serverPath, _, _ := strings.Cut(parsed.Path, "/api/v3")
+	parsed.Path = strings.TrimSuffix(serverPath, "/") + "/api/uploads/"
+	return parsed.String()
+}
+
+// Create a GitHub client which communicates with the GitHub API over an HTTP client.
 // This function follows the GitHub Action best practices by sourcing the GitHub
-// API address from an environment variable. See:
+// API address from an environment variable, unless the API and upload URLs
+// are set with inputs. See:
 // https://docs.github.com/en/actions/creating-actions/about-custom-actions#compatibility-with-github-enterprise-server
-func ensureNewGitHubClient(token string) *github.Client {
-	apiAddress := os.Getenv("GITHUB_API_URL")
-	httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
-	if client, err := github.NewEnterpriseClient(apiAddress, apiAddress, httpClient); err == nil {
+func ensureNewGitHubClient(httpClient *http.Client, apiURL string, uploadURL string) *github.Client {
+	// The client adds "/api/v3" to server URLs, but would also add "/api/uploads" to upload hosts which don't
+	// have it, so the upload URL is used as it is
+	client, err := github.NewEnterpriseClient(apiURL, apiURL, httpClient)
+	if err == nil {
+		client.UploadURL, err = url.Parse(strings.TrimSuffix(uploadURL, "/") + "/")
+	}
+
+	if err == nil {
 		return client
 	}
 
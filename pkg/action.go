@@ -2,18 +2,22 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
+	"log/slog"
+	"net/http"
+	"path"
 	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/Masterminds/semver"
+	"github.com/ellisto/monorepo-versioning/pkg/changelog"
+	"github.com/ellisto/monorepo-versioning/pkg/policy"
+	"github.com/ellisto/monorepo-versioning/pkg/telemetry"
 	"github.com/google/go-github/v50/github"
 	"github.com/leodido/go-conventionalcommits"
-	"github.com/leodido/go-conventionalcommits/parser"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 // VersioningAction contains logic to generate a new version
@@ -28,100 +32,531 @@ type VersioningAction struct {
 	initialVersion string
 	defaultBranch  string
 	parser         conventionalcommits.Machine
+	clock          Clock
+	// Template of tag names, eg: "{component}-{version}"
+	tagTemplate string
+	// Template of release titles, or nil for DefaultTitleTemplate
+	titleTemplate  *template.Template
+	history        *repositoryHistory
+	requestTimeout time.Duration
+	// Maximum number of commits listed for a single run, or zero for no limit
+	maxCommits int
+	logger     *slog.Logger
+	// Telemetry the action's phases and metrics are recorded to, or nil
+	telemetry *telemetry.Telemetry
+	// Whether versions which aren't newer than the latest release can be released
+	force bool
+	// Source of the commits versions are generated from, or nil for the GitHub API
+	commitSource CommitSource
+	// Policy deciding the version bump from the component's commits, or nil for the conventional policy
+	bumpPolicy policy.Policy
+	// Cache of the history fetched from GitHub between runs, or nil
+	cache Cache
+	// Audit log which the action's changes to the repository are recorded in, or nil
+	auditLog      *AuditLog
+	draft         bool
+	makeLatest    string
+	annotatedTags bool
+	tagger        Tagger
+	signer        TagSigner
+	aliasTags     []string
+	// Pattern of maintenance branch names, eg: "release/{major}.x"
+	maintenanceBranches string
+	channels            []Channel
+	config              Config
+	releasePullRequests bool
+	// Whether published prereleases can be the previous version, as well as stable releases
+	prereleaseBaseline bool
+	// Number of the pull request being previewed, if any
+	pullRequest int
+	// Category of the discussion created for each stable release, if any
+	discussionCategory string
+	// Environment which each published release is recorded as a deployment to, if any
+	deploymentEnvironment string
+	milestones            bool
+	// The part of the version incremented to name the next milestone, if one should be created
+	nextMilestone Bump
+	// Version of the action recorded in release metadata, if known
+	actionVersion string
+	// Locale release notes are written in, eg: "en"
+	locale string
+	// Format changelogs are rendered in, eg: "markdown"
+	notesFormat string
+	// Workflow run recorded as the builder in the provenance of each release, if provenance is attached
+	provenance *Workflow
+	// Signer of tags and uploaded assets, if they're signed with Sigstore
+	sigstore *SigstoreSigner
+	// Path of the SBOM file attached to each release, or SBOMGo to generate one, if any
+	sbom string
+	// Credentials of the registries Helm charts are pushed to
+	chartRegistryCredentials RegistryCredentials
+	// Features left out of requests, eg: FeatureMakeLatest, as well as those the server is too old for
+	disabledFeatures []string
+	// Whether GenerateVersions releases its components all or nothing
+	transactional bool
+	// Number of components GenerateVersions versions at once
+	concurrency int
+	// Releases created by the run, which are rolled back if releasing any component fails
+	transaction *releaseTransaction
+	// Template of the tag of each release train, eg: "train-{date}". Release trains aren't tagged if empty.
+	releaseTrain string
+	// Repository each release is published to as well, if any
+	mirror *releaseMirror
+	// Least version released, eg: to keep up with a component the component is constrained to
+	minimumVersion *semver.Version
+	// Group the component is released together with, if it's in one which is released in the run
+	group *componentGroup
 }
 
 // NewAction creates a new instance of the GitHub action for a given repository specified in the format
-// "owner/repository"
+// "owner/repository", panicking if the arguments are invalid.
+//
+// Deprecated: use New, which reports invalid options as an error and supports every option.
 func NewAction(ownerAndRepository string, component string, label string, branch string, revision string, initialVersion string, defaultBranch string, client *github.Client) VersioningAction {
-	nameParts := strings.Split(ownerAndRepository, "/")
-	owner := nameParts[0]
-	repository := nameParts[1]
+	a, err := New(
+		WithRepository(ownerAndRepository),
+		WithComponent(component, label),
+		WithBranch(branch, defaultBranch),
+		WithRevision(revision),
+		WithInitialVersion(initialVersion),
+		WithClient(client),
+	)
 
-	return VersioningAction{
-		client:         client,
-		owner:          owner,
-		repository:     repository,
-		branch:         branch,
-		component:      component,
-		label:          label,
-		revision:       revision,
-		initialVersion: initialVersion,
-		defaultBranch:  defaultBranch,
-		parser:         parser.NewMachine(conventionalcommits.WithTypes(conventionalcommits.TypesConventional)),
+	if err != nil {
+		panic(err)
 	}
+
+	return a
+}
+
+// ForComponent creates a copy of the action which versions a different component of the same repository.
+// The copy shares any releases and commits already fetched from GitHub with the original action.
+func (a VersioningAction) ForComponent(component string, label string) VersioningAction {
+	a.component = component
+	a.label = label
+	return a
+}
+
+// WithRequestTimeout creates a copy of the action where each GitHub API call must complete within timeout.
+// A timeout of zero means API calls are only bounded by the context passed to the action.
+func (a VersioningAction) WithRequestTimeout(timeout time.Duration) VersioningAction {
+	a.requestTimeout = timeout
+	return a
+}
+
+// WithMaxCommits creates a copy of the action which fails rather than listing more than max commits, so that a
+// component without a recent release doesn't page through the whole history. Zero means no limit.
+func (a VersioningAction) WithMaxCommits(max int) VersioningAction {
+	a.maxCommits = max
+	return a
+}
+
+// WithLogger creates a copy of the action which writes its logs to logger. Defaults to slog's default logger.
+func (a VersioningAction) WithLogger(logger *slog.Logger) VersioningAction {
+	a.logger = logger
+	return a
+}
+
+// WithTelemetry creates a copy of the action which traces its phases and counts the commits it processes. API
+// calls are only traced if the client's transport is wrapped with Telemetry.Transport.
+func (a VersioningAction) WithTelemetry(telemetry *telemetry.Telemetry) VersioningAction {
+	a.telemetry = telemetry
+	return a
+}
+
+// WithDraft creates a copy of the action which creates releases as drafts, so that they can be reviewed
+// before being published with PublishDraft
+func (a VersioningAction) WithDraft(draft bool) VersioningAction {
+	a.draft = draft
+	return a
+}
+
+// WithMakeLatest creates a copy of the action which controls whether its releases are marked as the
+// repository's latest release. It can be "true", "false", or "legacy" (latest by creation date and version),
+// or empty to use GitHub's default.
+func (a VersioningAction) WithMakeLatest(makeLatest string) VersioningAction {
+	a.makeLatest = makeLatest
+	return a
+}
+
+// WithAnnotatedTags creates a copy of the action which creates an annotated tag for each release, instead of
+// the lightweight tag GitHub creates along with a release. If signer is not nil, the tags are signed.
+func (a VersioningAction) WithAnnotatedTags(tagger Tagger, signer TagSigner) VersioningAction {
+	a.annotatedTags = true
+	a.tagger = tagger
+	a.signer = signer
+	return a
+}
+
+// WithAliasTags creates a copy of the action which moves the given alias tags (AliasMajor, AliasLatest) to each
+// new stable release of the component
+func (a VersioningAction) WithAliasTags(aliasTags []string) VersioningAction {
+	a.aliasTags = aliasTags
+	return a
+}
+
+// WithMaintenanceBranches creates a copy of the action which treats branches matching pattern as maintenance
+// branches. The pattern contains "{major}", and optionally "{minor}", placeholders for the version line the branch
+// maintains, eg: "release/{major}.x". Versions generated on a maintenance branch are stable, are based on the
+// latest release in the branch's line, and never bump past that line.
+func (a VersioningAction) WithMaintenanceBranches(pattern string) VersioningAction {
+	a.maintenanceBranches = pattern
+	return a
+}
+
+// WithChannels creates a copy of the action which releases versions to the first channel matching the branch
+func (a VersioningAction) WithChannels(channels []Channel) VersioningAction {
+	a.channels = channels
+	return a
+}
+
+// WithConfig creates a copy of the action which uses the configuration file
+func (a VersioningAction) WithConfig(config Config) VersioningAction {
+	a.config = config
+	return a
+}
+
+// WithReleasePullRequests opens a pull request for each new stable version instead of releasing it directly. The
+// version is released when the pull request is merged, so that releases can be reviewed before they're tagged.
+func (a VersioningAction) WithReleasePullRequests(enabled bool) VersioningAction {
+	a.releasePullRequests = enabled
+	return a
+}
+
+// WithDiscussionCategory creates a discussion in the category, eg: "Announcements", for each stable release, so
+// that users can ask questions about the release on its discussion thread
+func (a VersioningAction) WithDiscussionCategory(category string) VersioningAction {
+	a.discussionCategory = category
+	return a
+}
+
+// componentConfig is the configuration of the action's component
+func (a VersioningAction) componentConfig() ComponentConfig {
+	return a.config.Component(a.component)
+}
+
+// GenerateVersions generates the next version for several components of the same repository, returning the
+// results in the same order as the actions. All of the actions must have been created using ForComponent
+// from the same action. The release list and the commit range are fetched once and reused for every
+// component. Components are versioned concurrently if the actions were created WithConcurrency.
+func GenerateVersions(ctx context.Context, actions []VersioningAction, dryRun bool) []Result {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	first := actions[0]
+	actions = prepareVersions(ctx, actions)
+	if first.transactional && !dryRun {
+		return generateVersionsTransactionally(ctx, actions)
+	}
+
+	return forEachComponent(actions, func(a VersioningAction) Result {
+		a.logger.Info("Generating version", "component", a.component)
+		return a.GenerateVersion(ctx, dryRun)
+	})
+}
+
+// prepareVersions of the components before any is generated: fetching the commits they're generated from, and
+// keeping the versions of groups and constrained components together
+func prepareVersions(ctx context.Context, actions []VersioningAction) []VersioningAction {
+	// Fetch the widest commit range needed by any of the components up front, so that every component can
+	// take its own commits from the same range
+	var earliestChange *changePoint
+	previousChanges := forEachComponent(actions, func(a VersioningAction) *changePoint {
+		return a.getPreviousChange(ctx, a.baselineReleases(a.getAllReleases(ctx)))
+	})
+
+	for _, previousChange := range previousChanges {
+		if previousChange == nil {
+			// At least one component has never been released, so all commits are needed
+			earliestChange = nil
+			break
+		}
+
+		if earliestChange == nil || previousChange.time.Before(earliestChange.time) {
+			earliestChange = previousChange
+		}
+	}
+
+	first := actions[0]
+	first.getNewCommits(ctx, earliestChange, first.revision)
+	return constrainVersions(ctx, groupVersions(ctx, actions))
 }
 
 // GenerateVersion will generate the next version for a component based on the commits since the previous
 // version. If dryRun is true, then the version will not be created on GitHub. The next version number is
 // picked based on the Conventional Commits specification. Only commits with a scope matching the component
-// name will be considered.
-func (a VersioningAction) GenerateVersion(dryRun bool) *semver.Version {
-	existingReleases := filterAndSortReleasesForComponent(a.component, a.getAllReleases())
-	existingVersion, firstVersionCreated := existingVersionOrNew(a.component, existingReleases, a.initialVersion)
-
-	previousChangeTime := a.getPreviousChangeTime(existingReleases)
-	currentChangeTime := a.getCurrentChangeTime()
-	// Add 1 millisecond to the current change time so that the current commit is included in the
-	// changelog (as when we list commits until a given time, the "until" parameter is exclusive)
-	newCommits := a.getNewCommits(previousChangeTime, currentChangeTime.Add(time.Millisecond), a.branch)
-	componentConventionalCommits := convertAndFilterCommitsForComponent(a.component, newCommits)
-
-	newVersion := a.newVersion(existingVersion, componentConventionalCommits, firstVersionCreated)
+// name will be considered. The result explains the decision made for each commit.
+func (a VersioningAction) GenerateVersion(ctx context.Context, dryRun bool) Result {
+	ctx, span := a.telemetry.Start(ctx, "version", map[string]string{"component": a.component})
+	defer span.End()
+
+	return a.completeRelease(ctx, a.generateRelease(ctx, dryRun), dryRun)
+}
+
+// generateRelease generates the next version of the component, and creates its release unless dryRun is true,
+// without announcing it or pushing anything else which is released along with it
+func (a VersioningAction) generateRelease(ctx context.Context, dryRun bool) Result {
+	analyseCtx, analyseSpan := a.telemetry.Start(ctx, "analyse commits", map[string]string{"component": a.component})
+	allReleases := a.getAllReleases(analyseCtx)
+	restored := a.restoreHistory(analyseCtx)
+	existingReleases := a.baselineReleases(allReleases)
+	existingVersion, firstVersionCreated := a.existingVersionOrBaseline(existingReleases)
+
+	previousChange := a.getPreviousChange(analyseCtx, existingReleases)
+	rangeCommits := a.applyMergeCommitPolicy(a.getNewCommits(analyseCtx, previousChange, a.revision))
+	newCommits, released := a.withoutReleasedCommits(analyseCtx, rangeCommits, previousChange, existingReleases)
+	_, decisions := a.convertAndFilterCommitsForComponent(analyseCtx, rangeCommits)
+	skipReleasedDecisions(decisions, released)
+	warnAboutSkippedCommits(a.logger, a.component, decisions)
+	if !restored {
+		a.saveHistory(analyseCtx)
+	}
+
+	a.telemetry.Add(telemetry.MetricCommits, int64(len(rangeCommits)), map[string]string{"component": a.component})
+	analyseSpan.End()
+
+	result := Result{
+		Component: a.component,
+		Channel:   a.channel().Name,
+		Bump:      BumpNone,
+		Commits:   decisions,
+		tagPrefix: a.tagPrefix(),
+		revision:  a.revision,
+		latest:    a.releaseLine() == nil,
+	}
+
+	if !firstVersionCreated {
+		result.PreviousVersion = existingVersion
+		result.Bump = a.limitBump(a.versionPolicy().Decide(policyCommits(decisions)))
+		result.Bump = policy.Highest(result.Bump, a.groupBump())
+	}
+
+	a.runHooks(ctx, HookPreVersion, result, dryRun, "")
+	newVersion := a.newVersion(existingVersion, result.Bump, firstVersionCreated)
+	if newVersion != nil && existingVersion.Prerelease() != "" {
+		newVersion = a.versionAfterPrerelease(existingVersion, result.Bump, existingReleases)
+	}
+	if a.minimumVersion != nil && (newVersion == nil || newVersion.LessThan(a.minimumVersion)) {
+		// Keep up with a component the component is constrained to, even without commits of its own
+		newVersion = a.minimumVersion
+		result.Bump = BumpMinor
+		if existingVersion == nil || existingVersion.Major() != newVersion.Major() {
+			result.Bump = BumpMajor
+		}
+	}
 	if newVersion == nil {
 		// No new version, nothing else to do
-		return nil
+		return result
+	}
+
+	// Versions in prerelease channels are marked as prereleases
+	newVersion = a.applyChannel(newVersion, allReleases)
+	a.checkVersionIsNew(ctx, newVersion, allReleases)
+	result.Version = newVersion
+	result.ModuleSource = a.moduleSource(newVersion)
+	// Show what will be published, so that dry runs can be reviewed
+	result.Preview = a.releasePreview(ctx, newVersion, newCommits)
+	if result.Frozen = a.frozen(); result.Frozen != "" {
+		a.logger.Warn(fmt.Sprintf("Not releasing %s, as %s", newVersion, result.Frozen), "component", a.component)
+		return result
 	}
 
 	if dryRun {
 		// Dry run, don't publish version on GitHub
-		return newVersion
+		return result
+	}
+
+	a.checkRevisionIsOnDefaultBranch(ctx, newVersion, a.revision)
+
+	mergedReleasePullRequest := false
+	if a.releasePullRequests && newVersion.Prerelease() == "" {
+		mergedVersion := a.mergedReleasePullRequestVersion(ctx)
+		if mergedVersion == nil {
+			// Release once the pull request is reviewed and merged
+			result.ReleasePullRequestURL = a.openReleasePullRequest(ctx, newVersion, newCommits)
+			return result
+		}
+
+		// The pull request already updated the version files, and its version is the one which was reviewed
+		a.checkVersionIsNew(ctx, mergedVersion, allReleases)
+		newVersion = mergedVersion
+		result.Version = newVersion
+		result.ModuleSource = a.moduleSource(newVersion)
+		result.Preview = a.releasePreview(ctx, newVersion, newCommits)
+		mergedReleasePullRequest = true
 	}
 
-	a.createGitHubRelease(newVersion, newCommits)
-	return newVersion
+	// Wait for approval before changing anything, so that a release which isn't approved leaves nothing behind
+	if result.Held = a.awaitApproval(ctx, newVersion, result.Preview); result.Held != "" {
+		a.logger.Warn(fmt.Sprintf("Not releasing %s, as %s", newVersion, result.Held), "component", a.component)
+		return result
+	}
+
+	if !mergedReleasePullRequest && len(a.componentConfig().versionFiles()) > 0 {
+		// Release the commit which updates the version files, so that the tag includes them
+		a.revision = a.commitVersionFiles(ctx, newVersion)
+		result.revision = a.revision
+	}
+
+	ctx, releaseSpan := a.telemetry.Start(ctx, "release", map[string]string{"component": a.component, "version": newVersion.String()})
+	defer releaseSpan.End()
+	a.runHooks(ctx, HookPreRelease, result, dryRun, result.Preview.Notes)
+	sbomName, sbom := a.releaseSBOM(ctx, newVersion)
+	metadata := a.releaseMetadata(ctx, newVersion, result.Bump, existingReleases)
+	a.checkMirrorHasCommit(ctx, a.revision)
+	release, tagObject := a.createGitHubRelease(ctx, newVersion, result.Preview.Notes+metadata.block())
+	result.Release = newRelease(release)
+	result.Release.ProvenanceURL = a.attachProvenance(ctx, result)
+	result.Release.SBOMURL = a.attachSBOM(ctx, result.Release, sbomName, sbom)
+	a.signTag(ctx, result.Release, result.TagName(), tagObject)
+	result.created = release
+	if !a.draft {
+		// Drafts are aliased once they're published
+		a.updateAliasTags(ctx, newVersion)
+	}
+
+	return result
 }
 
-// createGitHubRelease based on the current revision and generated version
-func (a VersioningAction) createGitHubRelease(newVersion *semver.Version, commits []*github.RepositoryCommit) {
-	versionName := strings.ToLower(prefixWithComponent(a.component, newVersion.String()))
-	var releaseTitle string
-	// Prefer a human-readable label if one provided, otherwise use the component name
-	if a.label != "" {
-		releaseTitle = fmt.Sprintf("%s: %s", cases.Title(language.English).String(a.label), newVersion.String())
-	} else {
-		releaseTitle = fmt.Sprintf("%s: %s", cases.Title(language.English).String(a.component), newVersion.String())
+// completeRelease follows up the release created by generateRelease, if one was created: it's mirrored, announced
+// and deployed, and the component's chart, milestone and dependents are updated. None of this can be rolled back,
+// so with transactional releases, it's only done once every component was released.
+func (a VersioningAction) completeRelease(ctx context.Context, result Result, dryRun bool) Result {
+	if result.created == nil {
+		return result
+	}
+
+	ctx, span := a.telemetry.Start(ctx, "announce", map[string]string{"component": a.component, "version": result.Version.String()})
+	defer span.End()
+	// The release may be of the commit updating the version files
+	a.revision = result.revision
+	newVersion := result.Version
+	result.MirrorRelease = a.mirrorRelease(ctx, result.created)
+	if !a.draft {
+		// Drafts are announced, deployed and their milestones closed once they're published
+		a.notifyRelease(ctx, result, result.Preview.Notes)
+		a.recordDeployment(ctx, result)
+		a.completeMilestone(ctx, newVersion)
+		result.Chart = a.pushChart(ctx, newVersion)
+		result.DistTag = a.distTag(newVersion)
+		a.runHooks(ctx, HookPostRelease, result, dryRun, result.Preview.Notes)
+	}
+
+	if len(a.componentConfig().Dependents) > 0 && newVersion.Prerelease() == "" {
+		result.DependencyPullRequestURL = a.propagateVersion(ctx, newVersion)
 	}
-	isPrerelease := a.branch != a.defaultBranch
+
+	return result
+}
+
+// createGitHubRelease based on the current revision and generated version, returning the contents of the
+// annotated tag object it created as well, or nil if GitHub created a lightweight tag
+func (a VersioningAction) createGitHubRelease(ctx context.Context, newVersion *semver.Version, releaseNotes string) (*github.RepositoryRelease, []byte) {
+	versionName := a.tagName(newVersion.String())
+	releaseTitle := a.releaseTitle(newVersion)
+	isPrerelease := a.isPrerelease()
 	// We can't use auto-generated release notes, as we need to manually filter for changes specific to the
 	// given component.
 	useGitHubGeneratedReleaseNotes := false
-	releaseNotes := a.generateReleaseNotes(commits)
-
-	fmt.Printf("Creating GitHub tag: %s\n", versionName)
-	_, _, err := a.client.Repositories.CreateRelease(context.Background(), a.owner, a.repository, &github.RepositoryRelease{
-		TagName:              &versionName,
-		Name:                 &releaseTitle,
-		TargetCommitish:      &a.revision,
-		GenerateReleaseNotes: &useGitHubGeneratedReleaseNotes,
-		Body:                 &releaseNotes,
-		Prerelease:           &isPrerelease,
-	})
+
+	if existing := a.existingReleaseForTag(ctx, versionName); existing != nil && a.releasedRevision(ctx, existing) == a.revision {
+		// Eg: a re-run of a release which was already created, so its notes are brought up to date instead
+		a.logger.Info("Release already exists, so updating its notes", "component", a.component, "tag", versionName)
+		release, _ := a.updateReleaseNotes(ctx, existing, releaseNotes, false)
+		return release, nil
+	}
+
+	var tagObject []byte
+	if a.annotatedTags && !a.draft {
+		// The release will use the existing tag rather than creating a lightweight one. Drafts are tagged once
+		// they're published, so that discarding a draft doesn't leave its tag behind.
+		tagObject = a.createAnnotatedTag(ctx, versionName, releaseTitle)
+	}
+
+	a.logger.Info("Creating GitHub tag", "component", a.component, "tag", versionName)
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	release, _, err := a.client.Repositories.CreateRelease(requestCtx, a.owner, a.repository, a.withoutUnsupportedFields(ctx, &github.RepositoryRelease{
+		TagName:                &versionName,
+		Name:                   &releaseTitle,
+		TargetCommitish:        &a.revision,
+		GenerateReleaseNotes:   &useGitHubGeneratedReleaseNotes,
+		Body:                   &releaseNotes,
+		Prerelease:             &isPrerelease,
+		Draft:                  &a.draft,
+		MakeLatest:             a.makeLatestOrDefault(),
+		DiscussionCategoryName: a.discussionCategoryOrNone(isPrerelease),
+	}))
 
 	if err != nil {
 		panic(err)
 	}
+
+	a.audit(AuditRecord{Action: AuditCreateRelease, Tag: versionName, SHA: a.revision})
+	a.recordRelease(release)
+
+	return release, tagObject
+}
+
+// discussionCategoryOrNone omits discussion_category_name from release requests for prereleases, or if no
+// category was set, so that no discussion is created
+func (a VersioningAction) discussionCategoryOrNone(isPrerelease bool) *string {
+	if a.discussionCategory == "" || isPrerelease {
+		return nil
+	}
+
+	return &a.discussionCategory
+}
+
+// makeLatestOrDefault omits make_latest from release requests if it wasn't set, so GitHub's default is used.
+// Releases from maintenance branches default to not being the latest release, as they are usually backports.
+func (a VersioningAction) makeLatestOrDefault() *string {
+	if a.makeLatest == "" && a.releaseLine() != nil {
+		return github.String("false")
+	}
+
+	if a.makeLatest == "" {
+		return nil
+	}
+
+	return &a.makeLatest
+}
+
+// requestContext derives the context for a single GitHub API call from ctx, applying the request timeout
+func (a VersioningAction) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, a.requestTimeout)
+}
+
+// isNotFound checks whether a GitHub API call failed because the resource doesn't exist
+func isNotFound(err error) bool {
+	var errorResponse *github.ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
 }
 
 // getAllReleases for the given repository
-func (a VersioningAction) getAllReleases() (existingReleases []*github.RepositoryRelease) {
+func (a VersioningAction) getAllReleases(ctx context.Context) (existingReleases []*github.RepositoryRelease) {
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	if a.history.releasesListed {
+		return a.history.releases
+	}
+
 	allReleasesListed := false
 	page := 1
+	cacheKey := ""
 
 	for !allReleasesListed {
-		releases, _, err := a.client.Repositories.ListReleases(context.Background(), a.owner, a.repository, &github.ListOptions{
+		requestCtx, cancel := a.requestContext(ctx)
+		releases, _, err := a.client.Repositories.ListReleases(requestCtx, a.owner, a.repository, &github.ListOptions{
 			PerPage: 100,
 			Page:    page,
 		})
+		cancel()
 
 		if err != nil {
 			panic(err)
@@ -129,352 +564,312 @@ func (a VersioningAction) getAllReleases() (existingReleases []*github.Repositor
 
 		existingReleases = append(existingReleases, releases...)
 		allReleasesListed = len(releases) == 0
+		if page == 1 && len(releases) == 100 && a.cache != nil {
+			// Older releases are only read from the cache if the newest are the same as when it was stored. The
+			// newest are always the ones just listed, so that edits to them are seen.
+			cacheKey = a.cacheKey("older-releases", releasesFingerprint(releases))
+			var olderReleases []*github.RepositoryRelease
+			if a.getCached(ctx, cacheKey, &olderReleases) {
+				existingReleases, allReleasesListed, cacheKey = append(existingReleases, olderReleases...), true, ""
+			}
+		}
+
 		page++
 	}
 
+	if cacheKey != "" {
+		a.putCached(ctx, cacheKey, existingReleases[100:])
+	}
+
+	a.history.releases = existingReleases
+	a.history.releasesListed = true
 	return existingReleases
 }
 
-// getNewCommits since a given commit-like reference. If sinceComitish is empty, gets all commits
-func (a VersioningAction) getNewCommits(since *time.Time, until time.Time, branch string) (existingCommits []*github.RepositoryCommit) {
-	if since == nil {
-		startOfEpoch := time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
-		since = &startOfEpoch
-	} else {
-		// Add a second from the last change time so there's no overlap
-		exclusiveSince := since.Add(time.Second)
-		since = &exclusiveSince
+// getNewCommits reachable from head since a previous change, leaving out the previous change's commit and its
+// ancestors. If since is nil, gets every commit reachable from head.
+func (a VersioningAction) getNewCommits(ctx context.Context, since *changePoint, head string) []*github.RepositoryCommit {
+	sinceTime := time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if since != nil {
+		sinceTime = since.time
 	}
 
-	fmt.Printf("Looking for commits from %s, until %s\n", since.String(), until.String())
-
-	page := 1
-	allCommitsListed := false
-	for !allCommitsListed {
-		commits, _, err := a.client.Repositories.ListCommits(context.Background(), a.owner, a.repository, &github.CommitsListOptions{
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: 100,
-			},
-			Since: *since,
-			Until: until,
-			SHA:   branch,
-		})
-
-		if err != nil {
-			panic(err)
-		}
-
-		existingCommits = append(existingCommits, commits...)
-		allCommitsListed = len(commits) == 0
-		page++
+	// Another component may have already fetched a range which includes all of these commits
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	if !a.history.commits.covers(head, sinceTime) {
+		a.history.commits = a.listCommits(ctx, head, sinceTime)
 	}
 
-	return existingCommits
+	return a.history.commits.slice(since)
 }
 
-func (a VersioningAction) getCurrentChangeTime() time.Time {
-	commit, _, err := a.client.Git.GetCommit(context.Background(), a.owner, a.repository, a.revision)
+// listCommits reachable from head made on or after since, which includes the commit since was taken from, so
+// that getNewCommits can leave it out by SHA
+func (a VersioningAction) listCommits(ctx context.Context, head string, since time.Time) *commitRange {
+	a.logger.Info("Looking for commits", "head", head, "since", since.String())
+
+	commits, err := a.commits().Commits(ctx, head, since)
 	if err != nil {
 		panic(err)
 	}
 
-	return commit.GetCommitter().Date.Time
+	if a.maxCommits > 0 && len(commits) > a.maxCommits {
+		panic(fmt.Sprintf("There are more than %d commits on %s since %s, which is the max-commits limit. "+
+			"This usually means a component has never been released, or not for a long time. "+
+			"Create a baseline release of the component's current version (eg: %s) so that only later commits are needed, "+
+			"or raise max-commits if the range is expected", a.maxCommits, a.branch, since.Format(time.RFC3339), a.tagName(a.initialVersion)))
+	}
 
+	return &commitRange{head: head, since: since, commits: commits}
 }
 
-func (a VersioningAction) getPreviousChangeTime(existingReleases []*github.RepositoryRelease) *time.Time {
+// getPreviousChange gets the commit the component was last released at, or its baseline tag points at, or nil if
+// every commit is new
+func (a VersioningAction) getPreviousChange(ctx context.Context, existingReleases []*github.RepositoryRelease) *changePoint {
 	if len(existingReleases) == 0 {
-		return nil
+		return a.getBaselineChange(ctx)
 	}
 
-	// Releases are ordered descending by publish date
+	// Releases are ordered descending by version, so backports published after a newer version are skipped
 	latestRelease := existingReleases[0]
-	fmt.Printf("Using %s as latest release for change time comparison...\n", latestRelease.GetName())
-	ref, _, err := a.client.Git.GetRef(context.TODO(), a.owner, a.repository, fmt.Sprintf("refs/tags/%s", latestRelease.GetTagName()))
+	a.logger.Info("Using latest release for change comparison", "component", a.component, "release", latestRelease.GetName())
+	change := a.getTagChange(ctx, latestRelease.GetTagName())
+	return &change
+}
+
+// getTagChange gets the commit a tag points at
+func (a VersioningAction) getTagChange(ctx context.Context, tagName string) changePoint {
+	tagRef := fmt.Sprintf("refs/tags/%s", tagName)
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	if change, ok := a.history.tagCommits[tagRef]; ok {
+		return change
+	}
+
+	commitSHA := a.getTagCommitSHA(ctx, tagName)
+
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	commit, _, err := a.client.Git.GetCommit(requestCtx, a.owner, a.repository, commitSHA)
 	if err != nil {
 		panic(err)
 	}
 
-	commit, _, err := a.client.Git.GetCommit(context.Background(), a.owner, a.repository, *ref.Object.SHA)
+	change := changePoint{sha: commitSHA, time: commit.GetCommitter().Date.Time}
+	a.history.tagCommits[tagRef] = change
+	return change
+}
+
+// getTagCommitSHA gets the SHA of the commit a tag points at
+func (a VersioningAction) getTagCommitSHA(ctx context.Context, tagName string) string {
+	requestCtx, cancel := a.requestContext(ctx)
+	ref, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, fmt.Sprintf("refs/tags/%s", tagName))
+	cancel()
 	if err != nil {
 		panic(err)
 	}
 
-	commitTime := commit.GetCommitter().Date.Time
-	return &commitTime
+	commitSHA := ref.GetObject().GetSHA()
+	if ref.GetObject().GetType() == "tag" {
+		// Annotated tags point at a tag object, which in turn points at the commit
+		requestCtx, cancel = a.requestContext(ctx)
+		tag, _, err := a.client.Git.GetTag(requestCtx, a.owner, a.repository, commitSHA)
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		commitSHA = tag.GetObject().GetSHA()
+	}
+
+	return commitSHA
 }
 
-// newVersion based on the current version and commits since this version
-func (a VersioningAction) newVersion(currentVersion *semver.Version, newCommits []*conventionalcommits.ConventionalCommit, firstVersionCreated bool) *semver.Version {
+// newVersion based on the current version and the largest version bump of the commits since this version
+func (a VersioningAction) newVersion(currentVersion *semver.Version, bump Bump, firstVersionCreated bool) *semver.Version {
 	// If the version was just created (ie: it's 1.0.0 and was generated because no existing version is present)
 	// then just return the created version. Otherwise we'll immediately bump to 1.0.1/1.1.0/2.0.0 based on
 	// any commits in the repository.
 	if firstVersionCreated {
-		// We aren't generating a version on the default branch, so this
-		// should be a prerelease version
-		if a.branch != a.defaultBranch {
-			fmt.Printf("Current branch (%s) is not the default branch (%s), this version will be a pre-release\n", a.branch, a.defaultBranch)
-			prereleaseVersion, err := currentVersion.SetPrerelease(a.revision[:7])
-			if err != nil {
-				panic(err)
-			}
-
-			currentVersion = &prereleaseVersion
-		}
-
-		fmt.Printf("No existing version found for component, will generate %s\n", currentVersion.String())
+		a.logger.Info("No existing version found for component", "component", a.component, "version", currentVersion.String())
 
 		return currentVersion
 	}
 
-	// Major version bump
-	breakingChangesFound := false
-	// Minor version bump
-	featureChangesFound := false
-	// Patch version bump
-	fixChangesFound := false
-	refactorChangesFound := false
-	// Any other commit types are currently ignored and will not generate a new version
-
-	for _, commit := range newCommits {
-		if commit.IsBreakingChange() {
-			breakingChangesFound = true
-			// Breaking changes always mean a major version bump so we can bail out here
-			// without examining any other commits
-			break
-		}
-
-		if commit.IsFeat() {
-			featureChangesFound = true
-		}
-
-		if commit.IsFix() {
-			fixChangesFound = true
-		}
-
-		if strings.EqualFold(commit.Type, "refactor") {
-			refactorChangesFound = true
-		}
-	}
-
+	// Breaking changes are a major version bump, features are a minor version bump, and fixes and refactors
+	// are a patch version bump. Any other commit types are currently ignored and will not generate a new version
 	var nextVersion semver.Version
-	if breakingChangesFound {
+	switch bump {
+	case BumpMajor:
 		nextVersion = currentVersion.IncMajor()
-	} else if featureChangesFound {
+	case BumpMinor:
 		nextVersion = currentVersion.IncMinor()
-	} else if fixChangesFound {
-		nextVersion = currentVersion.IncPatch()
-	} else if refactorChangesFound {
+	case BumpPatch:
 		nextVersion = currentVersion.IncPatch()
-	}
-
-	// No changes, so no new version
-	if nextVersion == (semver.Version{}) {
+	default:
+		// No changes, so no new version
 		return nil
 	}
 
-	// We aren't generating a version on the default branch, so this
-	// should be a prerelease version
-	if a.branch != a.defaultBranch {
-		fmt.Printf("Current branch (%s) is not the default branch (%s), this version will be a pre-release\n", a.branch, a.defaultBranch)
-
-		var err error
-		nextVersion, err = nextVersion.SetPrerelease(a.revision[:7])
-		if err != nil {
-			panic(err)
-		}
-	}
-
-	// No relevant changes found, don't generate a new version number
 	return &nextVersion
 }
 
-// generateReleaseNotes based on the commits since the last version
-func (a VersioningAction) generateReleaseNotes(commits []*github.RepositoryCommit) string {
-	releaseNotesTemplate := `
-> Below is the changelog for this version. Changes are categorised by the type of change (breaking change, new feature, or bugfix). If there isn't a heading for a type of change, there were no relevant changes.
-{breaking}
-{features}
-{fixes}
-{refactors}
-{contributors}
-`
-
-	breakingChangesStr := strings.Builder{}
-	breakingChangesStr.WriteString("### :hammer: Breaking Changes\n")
-	breakingChangesStr.WriteString("_Breaking changes indicate that an existing behaviour or feature no longer works as before. Pay close attention to any listed breaking changes, and make sure they are acknowledged or mitigated before deploying this version._\n")
-	breakingChangesInitialLength := breakingChangesStr.Len()
-
-	featuresStr := strings.Builder{}
-	featuresStr.WriteString("### :bulb: Features\n")
-	featuresStr.WriteString("_Feature changes contain some new functionality. Existing behaviour should not be affected._\n")
-	featuresInitialLength := featuresStr.Len()
-
-	fixesStr := strings.Builder{}
-	fixesStr.WriteString("### :construction_worker: Fixes\n")
-	fixesStr.WriteString("_Fixes some unintended behaviour from a previous version. You should familiarise yourself with these changes to understand any problems you may have experienced in previous versions._\n")
-
-	fixesInitialLength := fixesStr.Len()
-
-	refactorsStr := strings.Builder{}
-	refactorsStr.WriteString("### :raised_hands: Refactoring\n")
-	refactorsStr.WriteString("_Changes or improvements to an existing implementation._\n")
+// generateReleaseNotes based on the commits since the last version, as markdown
+func (a VersioningAction) generateReleaseNotes(ctx context.Context, commits []*github.RepositoryCommit) string {
+	return a.releaseNotes(ctx, commits).Markdown()
+}
 
-	refactorsInitialLength := refactorsStr.Len()
+// releaseNotes groups the commits since the last version which affect the component into sections by the type of
+// change, and credits their contributors
+func (a VersioningAction) releaseNotes(ctx context.Context, commits []*github.RepositoryCommit) ReleaseNotes {
+	messages := a.messages()
+	notes := changelog.New(messages)
+	if a.channel().Hotfix {
+		notes.Hotfix = fmt.Sprintf(messages.Hotfix, a.branch)
+	}
 
-	contributorsStr := strings.Builder{}
-	contributorsStr.WriteString("### :heart_eyes: Contributors\n")
-	contributorsStr.WriteString("_These people contributed to this version of the component - thank you! Note: GitHub's auto-generated contributor list may also include contributors to other components._\n")
-	contributorsInitialLength := contributorsStr.Len()
-	contributors := make(map[string]bool)
+	if update := a.submoduleUpdate(ctx, commits); update != "" {
+		notes.Submodule = fmt.Sprintf(messages.Submodule, path.Clean(strings.Trim(a.componentConfig().Path, "/")), update)
+	}
 
 	for _, commit := range commits {
-		parsedMessage, err := a.parser.Parse([]byte(commit.GetCommit().GetMessage()))
-		if err != nil {
-			continue
-		}
+		if a.isDependencyUpdate(commit) {
+			// Bots are left out of the contributors, as they're thanked enough by the section
+			if included, _ := a.includesDependencyUpdate(ctx, commit); included {
+				// Bots don't write conventional commits, so the first line of the message is used
+				summary, _, _ := strings.Cut(commit.GetCommit().GetMessage(), "\n")
+				notes.Add(changelog.Dependencies, newReleaseNotesEntry(commit, summary, a.contributor(commit)))
+			}
 
-		conventionalCommit, ok := parsedMessage.(*conventionalcommits.ConventionalCommit)
-		if !ok {
 			continue
 		}
 
-		if conventionalCommit.Scope == nil {
+		description := ""
+		conventionalCommit, err := a.parseCommitForComponent(ctx, commit)
+		if err != nil {
 			continue
 		}
 
-		if conventionalCommit.Scope != nil && !strings.EqualFold(*conventionalCommit.Scope, a.component) {
+		if included, _ := a.includesCommit(ctx, commit, conventionalCommit); included {
+			description = conventionalCommit.Description
+		} else if member, memberCommit, ok := a.groupMemberChange(ctx, commit); ok {
+			// Members of a group share their release notes, so other members' changes are listed by member
+			conventionalCommit = memberCommit
+			description = fmt.Sprintf("%s: %s", member, conventionalCommit.Description)
+		} else {
 			continue
 		}
 
-		if conventionalCommit.IsBreakingChange() {
-			breakingChangesStr.WriteString(formatCommitChangelogEntry(commit, conventionalCommit))
-		}
-
-		if conventionalCommit.IsFeat() {
-			featuresStr.WriteString(formatCommitChangelogEntry(commit, conventionalCommit))
-		}
-
-		if conventionalCommit.IsFix() {
-			fixesStr.WriteString(formatCommitChangelogEntry(commit, conventionalCommit))
-		}
-
-		if strings.EqualFold(conventionalCommit.Type, "refactor") {
-			refactorsStr.WriteString(formatCommitChangelogEntry(commit, conventionalCommit))
-		}
-
-		if _, ok := contributors[commit.GetAuthor().GetLogin()]; !ok {
-			contributors[commit.GetAuthor().GetLogin()] = true
-			contributorsStr.WriteString(fmt.Sprintf("* @%s\n", commit.GetAuthor().GetLogin()))
-		}
-	}
-
-	if breakingChangesStr.Len() == breakingChangesInitialLength {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{breaking}", "", 1)
-	} else {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{breaking}", breakingChangesStr.String(), 1)
-	}
-
-	if featuresStr.Len() == featuresInitialLength {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{features}", "", 1)
-	} else {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{features}", featuresStr.String(), 1)
-	}
-
-	if fixesStr.Len() == fixesInitialLength {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{fixes}", "", 1)
-	} else {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{fixes}", fixesStr.String(), 1)
-	}
-
-	if refactorsStr.Len() == refactorsInitialLength {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{refactors}", "", 1)
-	} else {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{refactors}", refactorsStr.String(), 1)
-	}
-
-	if contributorsStr.Len() == contributorsInitialLength {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{contributors}", "", 1)
-	} else {
-		releaseNotesTemplate = strings.Replace(releaseNotesTemplate, "{contributors}", contributorsStr.String(), 1)
+		notes.AddChange(changelog.Change{
+			Entry:    newReleaseNotesEntry(commit, description, a.contributor(commit)),
+			Type:     conventionalCommit.Type,
+			Breaking: conventionalCommit.IsBreakingChange(),
+		})
+		notes.Credit(a.contributor(commit))
 	}
 
-	return releaseNotesTemplate
-}
-
-// formatCommitChangelogEntry formats a given commit as a changelog entry
-func formatCommitChangelogEntry(commit *github.RepositoryCommit, conventionalCommit *conventionalcommits.ConventionalCommit) string {
-	if commit.GetSHA() != "" {
-		// Shorten SHA to 7 characters to match how GitHub usually displays it
-		return fmt.Sprintf("* [`%s`](%s) %s (@%s)\n", commit.GetSHA()[:7], commit.GetHTMLURL(), conventionalCommit.Description, commit.GetAuthor().GetLogin())
-	} else {
-		return fmt.Sprintf("* [%s](%s) (@%s)\n", commit.GetHTMLURL(), conventionalCommit.Description, commit.GetAuthor().GetLogin())
-	}
+	return notes
 }
 
 // existingVersionOrNew gets the existing version for a component, or generates a version 1.0.0.
-func existingVersionOrNew(component string, existingReleases []*github.RepositoryRelease, initialVersion string) (version *semver.Version, firstVersion bool) {
+func (a VersioningAction) existingVersionOrNew(existingReleases []*github.RepositoryRelease) (version *semver.Version, firstVersion bool) {
 	if len(existingReleases) == 0 {
-		fmt.Println("No existing releases for component, will use initial version")
-		return semver.MustParse(initialVersion), true
+		a.logger.Info("No existing releases for component, will use initial version", "component", a.component, "initialVersion", a.initialVersion)
+		return semver.MustParse(a.initialVersion), true
 	}
 
-	latestRelease := existingReleases[0] // existingReleases is sorted in descending order of publish date
-	fmt.Printf("Using %s as latest release for version comparison...\n", latestRelease.GetName())
-	// Releases are named "ComponentName-SemanticVersion", strip the prefix to just get the latest version
-	latestReleaseVersion := strings.TrimPrefix(latestRelease.GetTagName(), getComponentPrefix(component))
-	return semver.MustParse(latestReleaseVersion), false
+	latestRelease := existingReleases[0] // existingReleases is sorted in descending order of version
+	a.logger.Info("Using latest release for version comparison", "component", a.component, "release", latestRelease.GetName())
+	return a.releaseVersion(latestRelease), false
 }
 
 // convertAndFilterCommitsForComponent, parsing the conventional commit message, and then filtering for commits
-// scoped to the provided component. If a commit does not match the Conventional Commits specification, it is
-// ignored.
-func convertAndFilterCommitsForComponent(component string, commits []*github.RepositoryCommit) []*conventionalcommits.ConventionalCommit {
+// which affect the component. If a commit does not match the Conventional Commits specification, it is ignored.
+// The decision made for each commit is also returned.
+func (a VersioningAction) convertAndFilterCommitsForComponent(ctx context.Context, commits []*github.RepositoryCommit) ([]*conventionalcommits.ConventionalCommit, []CommitDecision) {
 	var matchingCommits []*conventionalcommits.ConventionalCommit
+	var decisions []CommitDecision
 	for _, commit := range commits {
-		// Parse conventional commit message
-		// m := conventionalcommits.WithTypes(conventionalcommits.TypesConventional)
-		parser := parser.NewMachine(conventionalcommits.WithTypes(conventionalcommits.TypesConventional))
-		parsedMessage, err := parser.Parse([]byte(commit.GetCommit().GetMessage()))
+		decision := newCommitDecision(commit)
+		if a.isDependencyUpdate(commit) {
+			included, reason := a.includesDependencyUpdate(ctx, commit)
+			decision.Type = "dependencies"
+			if included {
+				decision.MatchedScope = true
+				decision.Bump = a.commitBump(decision.policyCommit())
+				decision.Reason = fmt.Sprintf("included: %s", reason)
+			} else {
+				decision.Reason = fmt.Sprintf("skipped: %s", reason)
+			}
 
-		if err != nil {
+			decisions = append(decisions, decision)
 			continue
 		}
 
-		conventionalCommit, ok := parsedMessage.(*conventionalcommits.ConventionalCommit)
-		if !ok {
+		// Parse conventional commit message
+		conventionalCommit, err := a.parseCommitForComponent(ctx, commit)
+		if errors.Is(err, errNotConventional) {
+			decision.Reason = "skipped: not a conventional commit"
+			decisions = append(decisions, decision)
 			continue
 		}
 
-		if conventionalCommit.Scope == nil {
+		if err != nil {
+			decision.Reason = fmt.Sprintf("skipped: not a conventional commit (%s)", err)
+			decisions = append(decisions, decision)
 			continue
 		}
 
-		if strings.EqualFold(*conventionalCommit.Scope, component) {
+		decision.Parsed = true
+		decision.Type = conventionalCommit.Type
+		decision.Breaking = conventionalCommit.IsBreakingChange()
+
+		if conventionalCommit.Scope != nil {
+			decision.Scope = *conventionalCommit.Scope
+		}
+
+		if included, reason := a.includesCommit(ctx, commit, conventionalCommit); included {
+			decision.MatchedScope = true
+			decision.Bump = a.commitBump(decision.policyCommit())
+			if decision.Bump == BumpNone {
+				decision.Reason = fmt.Sprintf("included: %s commits don't change the version", conventionalCommit.Type)
+			} else {
+				decision.Reason = fmt.Sprintf("included: %s", reason)
+			}
+
 			matchingCommits = append(matchingCommits, conventionalCommit)
+		} else {
+			decision.Reason = fmt.Sprintf("skipped: %s", reason)
 		}
+
+		decisions = append(decisions, decision)
 	}
 
-	return matchingCommits
+	return matchingCommits, decisions
 }
 
-// Filter all the repository releases to only the releases for the provided component, and then
-// sort them by release publish date.
-func filterAndSortReleasesForComponent(component string, releases []*github.RepositoryRelease) []*github.RepositoryRelease {
+// Filter all the repository releases to only the stable releases of the component, and then sort them in
+// descending order of version rather than when they were published, so that backports and republished releases
+// don't change which release is the latest. Releases of the same version, eg: one published again under a new tag
+// template, are sorted newest first.
+func (a VersioningAction) filterAndSortReleasesForComponent(releases []*github.RepositoryRelease) []*github.RepositoryRelease {
 	var matchingReleases []*github.RepositoryRelease
-	pattern := regexp.MustCompile(fmt.Sprintf(`^%s[0-9\.]+$`, getComponentPrefix(component)))
+	versions := make(map[*github.RepositoryRelease]*semver.Version)
 	for _, release := range releases {
-		if pattern.MatchString(strings.ToLower(release.GetTagName())) {
+		if version := a.releaseVersion(release); version != nil && version.Prerelease() == "" {
 			matchingReleases = append(matchingReleases, release)
+			versions[release] = version
 		}
 	}
 
-	sort.Slice(matchingReleases, func(i, j int) bool {
+	sort.SliceStable(matchingReleases, func(i, j int) bool {
 		// Use > instead of < in the less function so that we end up sorting in descending (greatest first)
 		// order, instead of ascending (smallest first) order
-		verI := semver.MustParse(strings.Split(matchingReleases[i].GetTagName(), "-")[1])
-		verJ := semver.MustParse(strings.Split(matchingReleases[j].GetTagName(), "-")[1])
+		verI, verJ := versions[matchingReleases[i]], versions[matchingReleases[j]]
+		if verI.Equal(verJ) {
+			return matchingReleases[i].GetPublishedAt().After(matchingReleases[j].GetPublishedAt().Time)
+		}
 
 		return verI.GreaterThan(verJ)
 	})
@@ -482,12 +877,41 @@ func filterAndSortReleasesForComponent(component string, releases []*github.Repo
 	return matchingReleases
 }
 
-// prefixWithComponent defines the logic to convert a component name into a tag prefix
-func prefixWithComponent(component string, str string) string {
-	return fmt.Sprintf("%s%s", getComponentPrefix(component), str)
+// publishedReleases filters out draft releases. Drafts don't have a tag until they are published, so they
+// can't be used as the previous version of a component.
+func publishedReleases(releases []*github.RepositoryRelease) []*github.RepositoryRelease {
+	var published []*github.RepositoryRelease
+	for _, release := range releases {
+		if !release.GetDraft() {
+			published = append(published, release)
+		}
+	}
+
+	return published
+}
+
+// tagName for a version of the component, using the tag template
+func (a VersioningAction) tagName(version string) string {
+	return fmt.Sprintf("%s%s", a.tagPrefix(), strings.ToLower(version))
 }
 
-func getComponentPrefix(component string) string {
-	// Append a hyphen to the component name
-	return fmt.Sprintf("%s-", strings.ToLower(component))
+// tagPrefix is the start of the tag name of every version of the component, eg: "api-". Terraform modules' tags
+// are named after their directory instead of with the tag template.
+func (a VersioningAction) tagPrefix() string {
+	if config := a.componentConfig(); config.Type == ComponentTerraform {
+		return terraformTagPrefix(config.Path)
+	}
+
+	return a.config.Naming.tag(tagPrefix(a.tagTemplate, a.tagComponent()))
+}
+
+// tagPrefix of a component's tags with a tag template, which ends with the version, before the component
+// names configuration normalises it
+func tagPrefix(template string, component string) string {
+	if template == "" {
+		template = DefaultTagTemplate
+	}
+
+	prefix := strings.TrimSuffix(template, "{version}")
+	return strings.ReplaceAll(prefix, "{component}", component)
 }
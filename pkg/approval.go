@@ -0,0 +1,250 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+const (
+	// DefaultApprovalTimeout is how long a release waits for approval, which fits in the action's default timeout
+	DefaultApprovalTimeout = 10 * time.Minute
+	// approvalLabel labels the issues asking for releases to be approved
+	approvalLabel = "release-approval"
+	// approvalPollInterval is how often the approval issue is checked for an approver's decision
+	approvalPollInterval = 30 * time.Second
+)
+
+// Commands which approvers comment on approval issues
+const (
+	approveCommand = "/approve"
+	denyCommand    = "/deny"
+)
+
+// ApprovalConfig requires releases of a component to be approved, eg: because it's production-critical. An issue
+// is opened for each release, which waits until an approver comments "/approve" or "/deny" on it.
+type ApprovalConfig struct {
+	// Approvers are the users, or teams as "org/team", who can approve releases. Anyone who can push to the
+	// repository can approve releases if empty.
+	Approvers []string `yaml:"approvers,omitempty"`
+	// Timeout is how long to wait for approval, eg: "30m". The run's own timeout has to be longer.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// validate the approval configuration
+func (c ApprovalConfig) validate() error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("approval timeout %s is negative", c.Timeout)
+	}
+
+	for _, approver := range c.Approvers {
+		if strings.Count(approver, "/") > 1 || strings.HasPrefix(approver, "@") {
+			return fmt.Errorf("invalid approver %q, expected a user or a team as org/team", approver)
+		}
+	}
+
+	return nil
+}
+
+// timeoutOrDefault is how long to wait for approval
+func (c ApprovalConfig) timeoutOrDefault() time.Duration {
+	if c.Timeout == 0 {
+		return DefaultApprovalTimeout
+	}
+
+	return c.Timeout
+}
+
+// awaitApproval of the release of a version, if the component requires it. Returns an empty string once it's
+// approved, or why it isn't released if it's denied or isn't approved in time. An issue which times out is left
+// open, so that the release can be approved before the next run.
+func (a VersioningAction) awaitApproval(ctx context.Context, version *semver.Version, preview *ReleasePreview) string {
+	config := a.componentConfig().Approval
+	if config == nil {
+		return ""
+	}
+
+	tagName := a.tagName(version.String())
+	issue := a.approvalIssue(ctx, tagName, preview, config.Approvers)
+	timeout := config.timeoutOrDefault()
+	a.logger.Info("Waiting for the release to be approved", "component", a.component, "tag", tagName, "issue", issue.GetHTMLURL(), "timeout", timeout.String())
+	deadline := a.clock.Now().Add(timeout)
+	for waited := time.Duration(0); ; waited += approvalPollInterval {
+		if approver, approved := a.approvalDecision(ctx, issue, config.Approvers); approver != "" {
+			a.closeApprovalIssue(ctx, issue, approver, approved, tagName)
+			if !approved {
+				return fmt.Sprintf("the release was denied by @%s in %s", approver, issue.GetHTMLURL())
+			}
+
+			a.logger.Info("Release approved", "component", a.component, "tag", tagName, "approver", approver)
+			return ""
+		}
+
+		// Time doesn't pass on a frozen clock, so the time waited between checks counts towards the timeout too
+		timedOut := fmt.Sprintf("the release wasn't approved within %s in %s", timeout, issue.GetHTMLURL())
+		if waited >= timeout || !a.clock.Now().Before(deadline) {
+			return timedOut
+		}
+
+		select {
+		case <-ctx.Done():
+			return timedOut
+		case <-a.clock.After(approvalPollInterval):
+		}
+	}
+}
+
+// approvalIssue asking for the release of a tag to be approved, reusing the open issue of an earlier run
+func (a VersioningAction) approvalIssue(ctx context.Context, tagName string, preview *ReleasePreview, approvers []string) *github.Issue {
+	title := fmt.Sprintf("Approve release %s", tagName)
+	for page := 1; ; page++ {
+		requestCtx, cancel := a.requestContext(ctx)
+		issues, response, err := a.client.Issues.ListByRepo(requestCtx, a.owner, a.repository, &github.IssueListByRepoOptions{
+			State:       "open",
+			Labels:      []string{approvalLabel},
+			ListOptions: github.ListOptions{Page: page, PerPage: 100},
+		})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		for _, issue := range issues {
+			if issue.GetTitle() == title {
+				return issue
+			}
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+	}
+
+	who := "anyone who can push to the repository"
+	if len(approvers) > 0 {
+		who = "@" + strings.Join(approvers, ", @")
+	}
+
+	body := fmt.Sprintf("%s needs approval before it's released. Comment `%s` to release it, or `%s` not to. It can be approved by %s.\n\n<details>\n<summary>%s</summary>\n\n%s\n</details>\n",
+		tagName, approveCommand, denyCommand, who, preview.Title, preview.Notes)
+	a.logger.Info("Opening approval issue", "component", a.component, "tag", tagName)
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	issue, _, err := a.client.Issues.Create(requestCtx, a.owner, a.repository, &github.IssueRequest{
+		Title:  &title,
+		Body:   &body,
+		Labels: &[]string{approvalLabel},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return issue
+}
+
+// approvalDecision reads the first approve or deny command commented on the issue by an approver, returning who
+// decided and whether they approved, or an empty approver if no approver has decided yet. Commands from anyone
+// else are ignored.
+func (a VersioningAction) approvalDecision(ctx context.Context, issue *github.Issue, approvers []string) (approver string, approved bool) {
+	for page := 1; ; page++ {
+		requestCtx, cancel := a.requestContext(ctx)
+		comments, response, err := a.client.Issues.ListComments(requestCtx, a.owner, a.repository, issue.GetNumber(), &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{Page: page, PerPage: 100},
+		})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		for _, comment := range comments {
+			command := strings.ToLower(strings.TrimSpace(comment.GetBody()))
+			if command != approveCommand && command != denyCommand {
+				continue
+			}
+
+			user := comment.GetUser().GetLogin()
+			if comment.GetUser().GetType() == "Bot" || !a.isApprover(ctx, user, approvers) {
+				a.logger.Warn("Ignoring approval command from someone who isn't an approver", "component", a.component, "user", user)
+				continue
+			}
+
+			return user, command == approveCommand
+		}
+
+		if response.NextPage == 0 {
+			return "", false
+		}
+	}
+}
+
+// isApprover checks whether a user can approve releases, either as one of the approvers or a member of one of
+// their teams, or if there are no approvers, by being able to push to the repository
+func (a VersioningAction) isApprover(ctx context.Context, user string, approvers []string) bool {
+	if len(approvers) == 0 {
+		requestCtx, cancel := a.requestContext(ctx)
+		defer cancel()
+		permission, _, err := a.client.Repositories.GetPermissionLevel(requestCtx, a.owner, a.repository, user)
+		if err != nil {
+			panic(err)
+		}
+
+		return permission.GetPermission() == "admin" || permission.GetPermission() == "write"
+	}
+
+	for _, approver := range approvers {
+		org, team, isTeam := strings.Cut(approver, "/")
+		if !isTeam {
+			if strings.EqualFold(approver, user) {
+				return true
+			}
+
+			continue
+		}
+
+		requestCtx, cancel := a.requestContext(ctx)
+		membership, _, err := a.client.Teams.GetTeamMembershipBySlug(requestCtx, org, team, user)
+		cancel()
+		if err != nil && !isNotFound(err) {
+			panic(err)
+		}
+
+		if membership.GetState() == "active" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// closeApprovalIssue once an approver has decided, recording the decision on it
+func (a VersioningAction) closeApprovalIssue(ctx context.Context, issue *github.Issue, approver string, approved bool, tagName string) {
+	body := fmt.Sprintf("Approved by @%s, releasing %s.", approver, tagName)
+	reason := "completed"
+	if !approved {
+		body = fmt.Sprintf("Denied by @%s, so %s isn't released.", approver, tagName)
+		reason = "not_planned"
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	_, _, err := a.client.Issues.CreateComment(requestCtx, a.owner, a.repository, issue.GetNumber(), &github.IssueComment{Body: &body})
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	_, _, err = a.client.Issues.Edit(requestCtx, a.owner, a.repository, issue.GetNumber(), &github.IssueRequest{
+		State:       github.String("closed"),
+		StateReason: &reason,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+)
+
+func TestAwaitApproval(t *testing.T) {
+	tests := []struct {
+		name string
+		// Comments on the approval issue before the run checks it, as login and body
+		comments [][2]string
+		held     string
+		// Reason the issue was closed with, or empty if it's left open
+		closed string
+	}{
+		{
+			name: "approved",
+			// Only approvers can approve releases
+			comments: [][2]string{{"hubot", "/deny"}, {"octocat", "/approve"}},
+			closed:   "completed",
+		},
+		{
+			name:     "denied",
+			comments: [][2]string{{"octocat", "/deny"}},
+			held:     "the release was denied by @octocat in https://github.com/octocat/monorepo/issues/1",
+			closed:   "not_planned",
+		},
+		{
+			name:     "not approved in time",
+			comments: [][2]string{{"hubot", "/approve"}},
+			held:     "the release wasn't approved within 2m0s in https://github.com/octocat/monorepo/issues/1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+			a := newTestAction(t, server, "api", WithClock(FrozenClock(time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC))))
+			a = a.WithConfig(Config{Components: map[string]ComponentConfig{"api": {
+				Approval: &ApprovalConfig{Approvers: []string{"octocat"}, Timeout: 2 * time.Minute},
+			}}})
+
+			// An earlier run opened the issue, so this run waits on it
+			approval := a.approvalIssue(context.Background(), "api-1.0.0", &ReleasePreview{Title: "api 1.0.0"}, []string{"octocat"})
+			for _, comment := range test.comments {
+				server.Comment(approval.GetNumber(), comment[0], comment[1])
+			}
+
+			result := a.GenerateVersion(context.Background(), false)
+			if result.Held != test.held || result.Frozen != "" {
+				t.Errorf("Expected the release to be held because %q, and not frozen, but got held %q and frozen %q", test.held, result.Held, result.Frozen)
+			}
+
+			if released := len(server.Releases()) == 1; released != (test.held == "") {
+				t.Errorf("Expected released to be %t, but got %d releases", test.held == "", len(server.Releases()))
+			}
+
+			issues := server.Issues()
+			if len(issues) != 1 {
+				t.Fatalf("Expected the approval issue to be reused, but got %d issues", len(issues))
+			}
+
+			if test.closed == "" {
+				if issues[0].GetState() != "open" {
+					t.Errorf("Expected the approval issue to be left open, but it's %s", issues[0].GetState())
+				}
+
+				// With the frozen clock, the timeout passes after checking every poll interval without waiting
+				checks := 0
+				for _, request := range server.Requests() {
+					if strings.HasSuffix(request, "/issues/1/comments") {
+						checks++
+					}
+				}
+
+				if expected := int(2*time.Minute/approvalPollInterval) + 1; checks != expected {
+					t.Errorf("Expected the issue to be checked %d times, but it was checked %d times", expected, checks)
+				}
+
+				return
+			}
+
+			if issues[0].GetState() != "closed" || issues[0].GetStateReason() != test.closed {
+				t.Errorf("Expected the approval issue to be closed as %s, but it's %s as %s", test.closed, issues[0].GetState(), issues[0].GetStateReason())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// uploadReleaseAsset attaches a file to a release, signing it if Sigstore signing was enabled, and panicking if the
+// upload fails. Returns the asset's download URL.
+func (a VersioningAction) uploadReleaseAsset(ctx context.Context, release *Release, name string, contents []byte, mediaType string) string {
+	downloadURL := a.uploadAsset(ctx, release, name, contents, mediaType)
+	if a.sigstore != nil {
+		a.uploadSignature(ctx, release, name, contents)
+	}
+
+	return downloadURL
+}
+
+// uploadAsset attaches a file to a release as it is, returning the asset's download URL
+func (a VersioningAction) uploadAsset(ctx context.Context, release *Release, name string, contents []byte, mediaType string) string {
+	name = assetName(name)
+	a.logger.Info("Uploading release asset", "component", a.component, "release", release.HTMLURL, "asset", name)
+	// UploadReleaseAsset needs a file, so the request is made directly for contents which are already in memory
+	path := fmt.Sprintf("repos/%s/%s/releases/%d/assets?name=%s", a.owner, a.repository, release.ID, url.QueryEscape(name))
+	request, err := a.client.NewUploadRequest(path, bytes.NewReader(contents), int64(len(contents)), mediaType)
+	if err != nil {
+		panic(err)
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	asset := new(github.ReleaseAsset)
+	if _, err := a.client.Do(requestCtx, request, asset); err != nil {
+		panic(err)
+	}
+
+	return asset.GetBrowserDownloadURL()
+}
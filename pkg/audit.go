@@ -0,0 +1,251 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// AuditLogFile is the file of the audit branch that records are appended to
+const AuditLogFile = "audit.jsonl"
+
+// Changes recorded in the audit log
+const (
+	AuditCreateRelease  = "create-release"
+	AuditPublishRelease = "publish-release"
+	AuditDeleteRelease  = "delete-release"
+	AuditCreateTag      = "create-tag"
+	AuditUpdateTag      = "update-tag"
+	AuditCreateBranch   = "create-branch"
+	AuditUpdateBranch   = "update-branch"
+	AuditYankRelease    = "yank-release"
+	AuditUpdateRelease  = "update-release"
+)
+
+// AuditRecord of a change the action made to the repository's releases, tags or branches
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Who started the workflow run, and who re-ran it, if someone else did
+	Actor           string `json:"actor"`
+	TriggeringActor string `json:"triggeringActor,omitempty"`
+	Repository      string `json:"repository"`
+	Component       string `json:"component,omitempty"`
+	Action          string `json:"action"`
+	Tag             string `json:"tag,omitempty"`
+	Branch          string `json:"branch,omitempty"`
+	// SHA of the commit the tag, release or branch points at after the change
+	SHA string `json:"sha,omitempty"`
+	// SHA-256 of the action's inputs, so that runs with the same configuration can be identified without
+	// recording the inputs themselves
+	InputsHash string `json:"inputsHash"`
+	RunURL     string `json:"runUrl,omitempty"`
+}
+
+// AuditLog records every change a run makes to the repository's releases, tags and branches, as evidence for
+// change management. It's shared by every component of the run, and records are only ever appended, to a JSON
+// Lines file and optionally to a branch of the repository, so that earlier records are never rewritten.
+type AuditLog struct {
+	actor           string
+	triggeringActor string
+	inputsHash      string
+	runURL          string
+
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+// NewAuditLog for a workflow run, which identifies its inputs by their hash. Secret inputs, such as tokens, should
+// be left out of the inputs, as they change between runs with the same configuration.
+func NewAuditLog(actor string, triggeringActor string, runURL string, inputs map[string]string) *AuditLog {
+	if triggeringActor == actor {
+		triggeringActor = ""
+	}
+
+	return &AuditLog{actor: actor, triggeringActor: triggeringActor, inputsHash: hashInputs(inputs), runURL: runURL}
+}
+
+// hashInputs as one "name=value" line per input, sorted by name
+func hashInputs(inputs map[string]string) string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s=%s\n", name, inputs[name])
+	}
+
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil))
+}
+
+// Records of the changes made so far, in the order they were made
+func (l *AuditLog) Records() []AuditRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]AuditRecord(nil), l.records...)
+}
+
+// Append the records to a JSON Lines file, creating it if it doesn't exist, eg: to upload as an artifact
+func (l *AuditLog) Append(path string) error {
+	lines, err := auditLines(l.Records())
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(lines); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// auditLines encodes records as JSON Lines
+func auditLines(records []AuditRecord) ([]byte, error) {
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+
+		lines = append(append(lines, line...), '\n')
+	}
+
+	return lines, nil
+}
+
+// WithAuditLog records every change made to the repository's releases, tags and branches in the audit log
+func (a VersioningAction) WithAuditLog(log *AuditLog) VersioningAction {
+	a.auditLog = log
+	return a
+}
+
+// audit a change which was made, filling in who made it, when, and with which inputs
+func (a VersioningAction) audit(record AuditRecord) {
+	if a.auditLog == nil {
+		return
+	}
+
+	record.Time = a.clock.Now().UTC()
+	record.Actor = a.auditLog.actor
+	record.TriggeringActor = a.auditLog.triggeringActor
+	record.Repository = a.Repository()
+	record.Component = a.component
+	record.InputsHash = a.auditLog.inputsHash
+	record.RunURL = a.auditLog.runURL
+	a.auditLog.mu.Lock()
+	defer a.auditLog.mu.Unlock()
+	a.auditLog.records = append(a.auditLog.records, record)
+}
+
+// auditBranchAttempts is how many times appending to the audit branch is attempted, in case other runs append to
+// it at the same time
+const auditBranchAttempts = 3
+
+// CommitAuditLog appends the audit log's records to AuditLogFile in a branch of the repository, eg: "audit/releases",
+// creating the branch without any history if it doesn't exist. The branch is only ever fast-forwarded, so its
+// history is a record of when each record was added.
+func (a VersioningAction) CommitAuditLog(ctx context.Context, branch string) {
+	records := a.auditLog.Records()
+	if len(records) == 0 {
+		return
+	}
+
+	lines, err := auditLines(records)
+	if err != nil {
+		panic(err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := a.appendToAuditBranch(ctx, branch, lines, len(records))
+		if err == nil {
+			return
+		}
+
+		if attempt == auditBranchAttempts {
+			panic(fmt.Sprintf("Could not append to the audit log in branch %s: %s", branch, err))
+		}
+
+		a.logger.Warn("Could not append to the audit log, retrying", "branch", branch, "error", err.Error())
+	}
+}
+
+// appendToAuditBranch commits the lines appended to the audit log file at the head of the branch, returning an
+// error if the branch moved on in the meantime
+func (a VersioningAction) appendToAuditBranch(ctx context.Context, branch string, lines []byte, count int) error {
+	ref := fmt.Sprintf("refs/heads/%s", branch)
+	requestCtx, cancel := a.requestContext(ctx)
+	head, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, ref)
+	cancel()
+	if err != nil && !isNotFound(err) {
+		panic(err)
+	}
+
+	message := fmt.Sprintf("chore: append to %s [skip ci]", AuditLogFile)
+	if a.auditLog.runURL != "" {
+		message += fmt.Sprintf("\n\nRecorded by %s", a.auditLog.runURL)
+	}
+
+	entries := func(contents string) []*github.TreeEntry {
+		return []*github.TreeEntry{{
+			Path:    github.String(AuditLogFile),
+			Mode:    github.String("100644"),
+			Type:    github.String("blob"),
+			Content: github.String(contents + string(lines)),
+		}}
+	}
+
+	if head == nil {
+		a.logger.Info("Creating audit log branch", "branch", branch)
+		requestCtx, cancel := a.requestContext(ctx)
+		tree, _, err := a.client.Git.CreateTree(requestCtx, a.owner, a.repository, "", entries(""))
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		requestCtx, cancel = a.requestContext(ctx)
+		commit, _, err := a.client.Git.CreateCommit(requestCtx, a.owner, a.repository, &github.Commit{Message: &message, Tree: tree})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		requestCtx, cancel = a.requestContext(ctx)
+		defer cancel()
+		_, _, err = a.client.Git.CreateRef(requestCtx, a.owner, a.repository, &github.Reference{
+			Ref:    &ref,
+			Object: &github.GitObject{SHA: commit.SHA},
+		})
+
+		return err
+	}
+
+	headSHA := head.GetObject().GetSHA()
+	contents, _ := a.findFileContents(ctx, AuditLogFile, headSHA)
+	commitSHA := a.createCommit(ctx, headSHA, message, entries(contents))
+	a.logger.Info("Appending to audit log", "branch", branch, "records", count)
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	_, _, err = a.client.Git.UpdateRef(requestCtx, a.owner, a.repository, &github.Reference{
+		Ref:    &ref,
+		Object: &github.GitObject{SHA: &commitSHA},
+	}, false)
+
+	return err
+}
@@ -0,0 +1,253 @@
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// releaseLine is a major (or major and minor) version line maintained on a maintenance branch, such as
+// "release/1.x"
+type releaseLine struct {
+	major int64
+	// The minor version, or -1 if the branch maintains the whole major version
+	minor int64
+}
+
+// String formats the line like "1.x" or "1.2.x"
+func (l releaseLine) String() string {
+	if l.minor < 0 {
+		return fmt.Sprintf("%d.x", l.major)
+	}
+
+	return fmt.Sprintf("%d.%d.x", l.major, l.minor)
+}
+
+// contains checks whether a version is part of the release line
+func (l releaseLine) contains(version *semver.Version) bool {
+	return version.Major() == l.major && (l.minor < 0 || version.Minor() == l.minor)
+}
+
+// maxBump within the release line: a major version line can't have major bumps, and a minor version line
+// can only have patch bumps
+func (l releaseLine) maxBump() Bump {
+	if l.minor < 0 {
+		return BumpMinor
+	}
+
+	return BumpPatch
+}
+
+// parseReleaseLine matches a branch against a maintenance branch pattern, where "{major}" and "{minor}" match
+// the version numbers of the line, eg: "release/{major}.x". Returns nil if the branch doesn't match.
+func parseReleaseLine(pattern string, branch string) *releaseLine {
+	if pattern == "" {
+		return nil
+	}
+
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.Replace(expression, regexp.QuoteMeta("{major}"), `(?P<major>\d+)`, 1)
+	expression = strings.Replace(expression, regexp.QuoteMeta("{minor}"), `(?P<minor>\d+)`, 1)
+	branchPattern := regexp.MustCompile(fmt.Sprintf("^%s$", expression))
+	matches := branchPattern.FindStringSubmatch(branch)
+	if matches == nil {
+		return nil
+	}
+
+	line := releaseLine{major: -1, minor: -1}
+	for i, name := range branchPattern.SubexpNames() {
+		switch name {
+		case "major":
+			line.major, _ = strconv.ParseInt(matches[i], 10, 64)
+		case "minor":
+			line.minor, _ = strconv.ParseInt(matches[i], 10, 64)
+		}
+	}
+
+	if line.major < 0 {
+		panic(fmt.Sprintf("Maintenance branch pattern %q must include {major}", pattern))
+	}
+
+	return &line
+}
+
+// releaseLine maintained by the current branch, or nil if it isn't a maintenance branch
+func (a VersioningAction) releaseLine() *releaseLine {
+	return parseReleaseLine(a.maintenanceBranches, a.branch)
+}
+
+// isPrerelease checks whether versions generated on the current branch are prereleases
+func (a VersioningAction) isPrerelease() bool {
+	return a.channel().Prerelease != ""
+}
+
+// WithPrereleaseBaseline allows the newest published prerelease to be the previous version, rather than only
+// stable releases, except on maintenance branches. Commits are then only considered since the prerelease, and the
+// next stable version is the prerelease's version unless the new commits need a bigger bump.
+func (a VersioningAction) WithPrereleaseBaseline(enabled bool) VersioningAction {
+	a.prereleaseBaseline = enabled
+	return a
+}
+
+// baselineReleases are the published releases of the component which the next version can be based on, sorted
+// in descending order of version. On a maintenance branch, only releases in the branch's release line are used.
+// Prereleases are excluded unless they are allowed with WithPrereleaseBaseline.
+func (a VersioningAction) baselineReleases(releases []*github.RepositoryRelease) []*github.RepositoryRelease {
+	baseline := publishedReleases(a.filterAndSortReleasesForComponent(releases))
+	line := a.releaseLine()
+	if line == nil {
+		if a.prereleaseBaseline {
+			return a.withPrereleases(baseline, releases)
+		}
+
+		return baseline
+	}
+
+	var lineReleases []*github.RepositoryRelease
+	for _, release := range baseline {
+		if line.contains(a.releaseVersion(release)) {
+			lineReleases = append(lineReleases, release)
+		}
+	}
+
+	if len(lineReleases) == 0 {
+		panic(fmt.Sprintf("Branch %s maintains the %s release line of %s, but there are no releases in that line yet", a.branch, line.String(), a.component))
+	}
+
+	return lineReleases
+}
+
+// limitBump so that the next version stays within the current branch's release line and channel
+func (a VersioningAction) limitBump(bump Bump) Bump {
+	if line := a.releaseLine(); line != nil && bump.Greater(line.maxBump()) {
+		a.logger.Warn(fmt.Sprintf("Changes on %s would cause a %s version bump, but the branch maintains the %s release line, so a %s version bump will be used", a.branch, bump, line.String(), line.maxBump()), "component", a.component)
+		bump = line.maxBump()
+	}
+
+	if channel := a.channel(); channel.MaxBump != "" && bump.Greater(channel.MaxBump) {
+		a.logger.Warn(fmt.Sprintf("Changes on %s would cause a %s version bump, but the %s channel only allows up to a %s version bump", a.branch, bump, channel.Name, channel.MaxBump), "component", a.component)
+		bump = channel.MaxBump
+	}
+
+	return bump
+}
+
+// Channel configures how versions generated on matching branches are released
+type Channel struct {
+	// Name of the channel, which is output so that later steps can publish to the right place, eg: "beta"
+	Name string `yaml:"name,omitempty"`
+	// Branch pattern, matched with path.Match, eg: "beta/*"
+	Branch string `yaml:"branch,omitempty"`
+	// Prerelease identifier for versions in the channel. Empty for stable versions, "sha" to use the shortened
+	// commit hash (eg: 1.2.0-abc1234), or any other identifier to number prereleases (eg: 1.2.0-beta.3)
+	Prerelease string `yaml:"prerelease,omitempty"`
+	// MaxBump limits the version bump in the channel, eg: "patch" for hotfix branches
+	MaxBump Bump `yaml:"max-bump,omitempty"`
+	// Hotfix notes in the release notes that the version was released from a hotfix branch
+	Hotfix bool `yaml:"hotfix,omitempty"`
+}
+
+// HotfixChannel releases stable patch versions from branches matching pattern, eg: "hotfix/*", so that production
+// incident fixes can be released without waiting for them to be merged to the default branch
+func HotfixChannel(pattern string) Channel {
+	return Channel{
+		Name:    "hotfix",
+		Branch:  pattern,
+		MaxBump: BumpPatch,
+		Hotfix:  true,
+	}
+}
+
+// PrereleaseSHA identifies prereleases by the shortened commit hash
+const PrereleaseSHA = "sha"
+
+var (
+	stableChannel     = Channel{Name: "stable"}
+	prereleaseChannel = Channel{Name: "prerelease", Prerelease: PrereleaseSHA}
+)
+
+// channel which versions generated on the current branch are released to. Unless a configured channel matches the
+// branch, versions on the default branch and maintenance branches are stable, and all other versions are
+// prereleases identified by the commit hash.
+func (a VersioningAction) channel() Channel {
+	for _, channel := range a.channels {
+		if matched, _ := path.Match(channel.Branch, a.branch); matched {
+			return channel
+		}
+	}
+
+	if a.branch == a.defaultBranch || a.releaseLine() != nil {
+		return stableChannel
+	}
+
+	return prereleaseChannel
+}
+
+// withPrereleases adds the component's published prereleases to the stable releases, keeping them sorted in
+// descending order of version
+func (a VersioningAction) withPrereleases(stable []*github.RepositoryRelease, releases []*github.RepositoryRelease) []*github.RepositoryRelease {
+	combined := append(a.filterPrereleasesForComponent(releases), stable...)
+	sort.SliceStable(combined, func(i, j int) bool {
+		return a.releaseVersion(combined[i]).GreaterThan(a.releaseVersion(combined[j]))
+	})
+
+	return combined
+}
+
+// versionAfterPrerelease is the next stable version after a prerelease baseline. It's the version the prerelease
+// was for, eg: 1.2.0 after 1.2.0-rc.1, unless bumping the newest stable version needs a bigger version.
+func (a VersioningAction) versionAfterPrerelease(prerelease *semver.Version, bump Bump, releases []*github.RepositoryRelease) *semver.Version {
+	released, err := prerelease.SetPrerelease("")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, release := range releases {
+		if stable := a.releaseVersion(release); stable.Prerelease() == "" {
+			if bumped := a.newVersion(stable, bump, false); bumped.GreaterThan(&released) {
+				return bumped
+			}
+
+			break
+		}
+	}
+
+	return &released
+}
+
+// applyChannel marks a version as a prerelease if the channel is a prerelease channel. Numbered prereleases
+// continue from the highest existing prerelease of the same version in the channel.
+func (a VersioningAction) applyChannel(version *semver.Version, releases []*github.RepositoryRelease) *semver.Version {
+	channel := a.channel()
+	if channel.Prerelease == "" {
+		return version
+	}
+
+	a.logger.Info("Current branch is in a prerelease channel, this version will be a pre-release", "branch", a.branch, "defaultBranch", a.defaultBranch, "channel", channel.Name)
+	identifier := a.revision[:7]
+	if channel.Prerelease != PrereleaseSHA {
+		number := 1
+		prefix := fmt.Sprintf("%s-%s.", version.String(), channel.Prerelease)
+		for _, release := range a.filterPrereleasesForComponent(releases) {
+			existing := strings.ToLower(a.releaseVersion(release).String())
+			if existingNumber, err := strconv.Atoi(strings.TrimPrefix(existing, prefix)); strings.HasPrefix(existing, prefix) && err == nil && existingNumber >= number {
+				number = existingNumber + 1
+			}
+		}
+
+		identifier = fmt.Sprintf("%s.%d", channel.Prerelease, number)
+	}
+
+	prereleaseVersion, err := version.SetPrerelease(identifier)
+	if err != nil {
+		panic(err)
+	}
+
+	return &prereleaseVersion
+}
@@ -0,0 +1,361 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// Cache stores the release lists, commit ranges and resolved tag SHAs fetched from GitHub between runs, so that
+// repeat runs on big monorepos don't list the same history again. Keys include the repository, component and a SHA
+// which the value can't change without, so values are never replaced once they're stored.
+type Cache interface {
+	// Get the value stored under a key, or false if there isn't one
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Put a value under a key. A key which already has a value may be ignored.
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// WithCache stores the history fetched from GitHub in a cache, eg: a FileCache or ActionsCache, and reads it from
+// the cache in later runs instead of GitHub
+func (a VersioningAction) WithCache(cache Cache) VersioningAction {
+	a.cache = cache
+	return a
+}
+
+// cacheKey of a kind of value, eg: "releases", keyed by the SHA which it's derived from
+func (a VersioningAction) cacheKey(kind string, sha string) string {
+	return fmt.Sprintf("monorepo-versioning-%s/%s-%s-%s-%s", a.owner, a.repository, a.component, kind, sha)
+}
+
+// getCached value under a key, decoding it from JSON. Returns false if there's no cache, the key has no value, or it
+// can't be read, as the value can always be fetched from GitHub instead.
+func (a VersioningAction) getCached(ctx context.Context, key string, value any) bool {
+	if a.cache == nil {
+		return false
+	}
+
+	contents, ok, err := a.cache.Get(ctx, key)
+	if err == nil && ok {
+		err = json.Unmarshal(contents, value)
+	}
+
+	if err != nil {
+		a.logger.Warn("Can't read from the cache, so fetching from GitHub instead", "key", key, "error", err.Error())
+		return false
+	}
+
+	if ok {
+		a.logger.Debug("Read from the cache", "key", key)
+	}
+
+	return ok
+}
+
+// putCached value under a key, encoded as JSON. Failing to store it only makes the next run slower, so it's only
+// warned about.
+func (a VersioningAction) putCached(ctx context.Context, key string, value any) {
+	if a.cache == nil {
+		return
+	}
+
+	contents, err := json.Marshal(value)
+	if err == nil {
+		err = a.cache.Put(ctx, key, contents)
+	}
+
+	if err != nil {
+		a.logger.Warn("Can't write to the cache", "key", key, "error", err.Error())
+	}
+}
+
+// releasesFingerprint identifies the newest page of releases, which changes when a release is created, or one of
+// the newest is published or deleted, so that the older pages can be read from the cache. Only the releases' IDs,
+// tags and publication times are used, as the rest changes without the older pages changing, eg: the download
+// counts of assets.
+func releasesFingerprint(newest []*github.RepositoryRelease) string {
+	digest := sha256.New()
+	for _, release := range newest {
+		fmt.Fprintf(digest, "%d %s %s\n", release.GetID(), release.GetTagName(), release.GetPublishedAt().UTC().Format(time.RFC3339))
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// cachedHistory is the history a component's version was generated from at a revision
+type cachedHistory struct {
+	// Commits tags point at, keyed by tag reference
+	TagCommits map[string]cachedChange    `json:"tag_commits"`
+	Head       string                     `json:"head"`
+	Since      time.Time                  `json:"since"`
+	Commits    []*github.RepositoryCommit `json:"commits"`
+	// Paths of the files changed by each commit in the range, if they were needed, keyed by commit SHA
+	CommitFiles map[string][]string `json:"commit_files,omitempty"`
+}
+
+type cachedChange struct {
+	SHA  string    `json:"sha"`
+	Time time.Time `json:"time"`
+}
+
+// restoreHistory the component's version was generated from at the revision in an earlier run, returning false if
+// it isn't cached. Commits are still listed from GitHub if the cached range doesn't cover them, eg: because the
+// component was released since.
+func (a VersioningAction) restoreHistory(ctx context.Context) bool {
+	var history cachedHistory
+	if !a.getCached(ctx, a.cacheKey("history", a.revision), &history) {
+		return false
+	}
+
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	for tagRef, change := range history.TagCommits {
+		a.history.tagCommits[tagRef] = changePoint{sha: change.SHA, time: change.Time}
+	}
+
+	for sha, files := range history.CommitFiles {
+		a.history.commitFiles[sha] = files
+	}
+
+	if !a.history.commits.covers(history.Head, history.Since) {
+		a.history.commits = &commitRange{head: history.Head, since: history.Since, commits: history.Commits}
+	}
+
+	return true
+}
+
+// saveHistory the component's version was generated from at the revision, for later runs at the same revision
+func (a VersioningAction) saveHistory(ctx context.Context) {
+	if a.cache == nil {
+		return
+	}
+
+	a.history.mu.Lock()
+	if a.history.commits == nil {
+		a.history.mu.Unlock()
+		return
+	}
+
+	history := cachedHistory{
+		TagCommits:  make(map[string]cachedChange),
+		Head:        a.history.commits.head,
+		Since:       a.history.commits.since,
+		Commits:     a.history.commits.commits,
+		CommitFiles: make(map[string][]string),
+	}
+
+	for tagRef, change := range a.history.tagCommits {
+		history.TagCommits[tagRef] = cachedChange{SHA: change.sha, Time: change.time}
+	}
+
+	for _, commit := range history.Commits {
+		if files, ok := a.history.commitFiles[commit.GetSHA()]; ok {
+			history.CommitFiles[commit.GetSHA()] = files
+		}
+	}
+
+	a.history.mu.Unlock()
+	a.putCached(ctx, a.cacheKey("history", a.revision), history)
+}
+
+// FileCache stores values as files in a directory, eg: one restored and saved by actions/cache, or kept on a
+// self-hosted runner between runs
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache stores values in a directory, which is created if it doesn't exist
+func NewFileCache(dir string) *FileCache {
+	return &FileCache{dir: dir}
+}
+
+func (c *FileCache) path(key string) string {
+	return filepath.Join(c.dir, url.PathEscape(key)+".json")
+}
+
+// Get the value stored in a key's file
+func (c *FileCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	contents, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return contents, true, nil
+}
+
+// Put a value in a key's file, replacing it atomically so that concurrent runs never read a partial value
+func (c *FileCache) Put(_ context.Context, key string, value []byte) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(c.dir, ".cache-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+	if _, err := file.Write(value); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), c.path(key))
+}
+
+// actionsCacheVersion tells the action's cache entries apart from those of actions/cache, which are archives
+var actionsCacheVersion = func() string {
+	digest := sha256.Sum256([]byte("monorepo-versioning-cache-1"))
+	return hex.EncodeToString(digest[:])
+}()
+
+// ActionsCache stores values in the GitHub Actions cache, using the cache service the runner gives actions access
+// to, the same as actions/cache. Entries are scoped to the branch they're saved from, and can be read from branches
+// based on it, eg: pull requests read the default branch's entries.
+type ActionsCache struct {
+	serviceURL string
+	token      string
+	client     *http.Client
+}
+
+// NewActionsCache uses the cache service at the runner's results URL, authorised with its runtime token, which
+// are given to actions as ACTIONS_RESULTS_URL and ACTIONS_RUNTIME_TOKEN, with a client, eg: one which uses a proxy
+func NewActionsCache(client *http.Client, resultsURL string, runtimeToken string) *ActionsCache {
+	return &ActionsCache{
+		serviceURL: strings.TrimSuffix(resultsURL, "/") + "/twirp/github.actions.results.api.v1.CacheService/",
+		token:      runtimeToken,
+		client:     client,
+	}
+}
+
+// Get the value of a key's cache entry, downloading it from the entry's storage
+func (c *ActionsCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var entry struct {
+		OK          bool   `json:"ok"`
+		DownloadURL string `json:"signed_download_url"`
+	}
+
+	if _, err := c.call(ctx, "GetCacheEntryDownloadURL", map[string]any{"key": key, "restore_keys": []string{}, "version": actionsCacheVersion}, &entry); err != nil {
+		return nil, false, err
+	}
+
+	if !entry.OK {
+		return nil, false, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, entry.DownloadURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("downloading cache entry %s returned %s", key, resp.Status)
+	}
+
+	contents, err := io.ReadAll(resp.Body)
+	return contents, err == nil, err
+}
+
+// Put a value in a new cache entry for a key, uploading it to the entry's storage. Nothing is stored if the key
+// already has an entry, or another run is storing one.
+func (c *ActionsCache) Put(ctx context.Context, key string, value []byte) error {
+	var entry struct {
+		OK        bool   `json:"ok"`
+		UploadURL string `json:"signed_upload_url"`
+	}
+
+	status, err := c.call(ctx, "CreateCacheEntry", map[string]any{"key": key, "version": actionsCacheVersion}, &entry)
+	if status == http.StatusConflict || (err == nil && !entry.OK) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, entry.UploadURL, bytes.NewReader(value))
+	if err != nil {
+		return err
+	}
+
+	// The entry's storage is an Azure blob
+	request.Header.Set("x-ms-blob-type", "BlockBlob")
+	request.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("uploading cache entry %s returned %s", key, resp.Status)
+	}
+
+	var finalized struct {
+		OK bool `json:"ok"`
+	}
+
+	if _, err := c.call(ctx, "FinalizeCacheEntryUpload", map[string]any{"key": key, "version": actionsCacheVersion, "size_bytes": strconv.Itoa(len(value))}, &finalized); err != nil {
+		return err
+	}
+
+	if !finalized.OK {
+		return fmt.Errorf("cache entry %s couldn't be finalized", key)
+	}
+
+	return nil
+}
+
+// call a method of the cache service, returning the response's status
+func (c *ActionsCache) call(ctx context.Context, method string, body any, response any) (int, error) {
+	request, err := newJSONRequest(ctx, c.serviceURL+method, body)
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+	contents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("%s returned %s: %s", method, resp.Status, strings.TrimSpace(string(contents)))
+	}
+
+	return resp.StatusCode, json.Unmarshal(contents, response)
+}
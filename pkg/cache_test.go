@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+	"github.com/google/go-github/v50/github"
+)
+
+func TestCachedReleasesSeeEditsToNewest(t *testing.T) {
+	server := newTestServer(t)
+	sha := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))[0]
+	for patch := 0; patch < 150; patch++ {
+		server.AddRelease(githubtest.Release(fmt.Sprintf("api-1.0.%d", patch), sha))
+	}
+
+	cache := NewFileCache(t.TempDir())
+	listed := newTestAction(t, server, "api").WithCache(cache).getAllReleases(context.Background())
+	if len(listed) != 150 {
+		t.Fatalf("Expected 150 releases, but got %d", len(listed))
+	}
+
+	// Editing the notes of the newest releases, or downloading their assets, doesn't change older pages
+	newest := listed[0]
+	_, _, err := server.Client().Repositories.EditRelease(context.Background(), "octocat", "monorepo", newest.GetID(), &github.RepositoryRelease{Body: github.String("Edited notes")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := len(server.Requests())
+	releases := newTestAction(t, server, "api").WithCache(cache).getAllReleases(context.Background())
+	pages := 0
+	for _, request := range server.Requests()[before:] {
+		if strings.HasSuffix(request, "/releases") {
+			pages++
+		}
+	}
+
+	if pages != 1 {
+		t.Errorf("Expected only the newest page of releases to be listed, but %d pages were listed", pages)
+	}
+
+	if len(releases) != 150 || releases[0].GetBody() != "Edited notes" {
+		t.Errorf("Expected 150 releases with the newest as edited, but got %d releases with the newest as %q", len(releases), releases[0].GetBody())
+	}
+
+	// Publishing a release changes the newest page, so the older ones are listed again
+	server.AddRelease(githubtest.Release("api-1.0.150", sha))
+	before = len(server.Requests())
+	releases = newTestAction(t, server, "api").WithCache(cache).getAllReleases(context.Background())
+	if pages := len(server.Requests()) - before; pages != 3 || len(releases) != 151 {
+		t.Errorf("Expected every page of 151 releases to be listed, but got %d requests and %d releases", pages, len(releases))
+	}
+}
@@ -0,0 +1,227 @@
+// Package cassette records the GitHub API requests of a run to a file, and replays them instead of calling the
+// API, so that a run's decisions can be investigated or tested against the repository history it saw.
+//
+// A cassette is a JSON Lines file: a header recording when the run happened, then each request and its response
+// in the order they were made. It's written as requests are made, so that a run which fails part way still leaves
+// a cassette of everything until the failure. Request headers aren't recorded, so tokens are never written to it.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// header is the first line of a cassette
+type header struct {
+	// Recorded is the time the run saw as the current time
+	Recorded time.Time `json:"recorded"`
+}
+
+// Interaction is a request made by a run, and the response it got
+type Interaction struct {
+	Method string `json:"method"`
+	// URL of the request, relative to the server, eg: "/repos/owner/repository/releases?page=2"
+	URL         string      `json:"url"`
+	RequestBody string      `json:"requestBody,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+// Recorder is an HTTP transport which records each request and response to a cassette
+type Recorder struct {
+	transport http.RoundTripper
+	mu        sync.Mutex
+	file      *os.File
+}
+
+// Record the requests made through transport to a new cassette at path, replacing any existing file. recorded is
+// the current time of the run, which is replayed with the requests.
+func Record(path string, recorded time.Time, transport http.RoundTripper) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &Recorder{transport: transport, file: file}
+	if err := recorder.write(header{Recorded: recorded}); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return recorder, nil
+}
+
+// RoundTrip makes the request with the wrapped transport, and records it with its response
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := readBody(&response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err = r.write(Interaction{
+		Method:      request.Method,
+		URL:         request.URL.RequestURI(),
+		RequestBody: string(requestBody),
+		Status:      response.StatusCode,
+		Header:      response.Header,
+		Body:        string(body),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("could not record %s %s: %w", request.Method, request.URL.Path, err)
+	}
+
+	return response, nil
+}
+
+// Close the cassette file
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// write a line of the cassette
+func (r *Recorder) write(line any) error {
+	contents, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.file.Write(append(contents, '\n'))
+	return err
+}
+
+// readBody reads a request or response body, replacing it so that it can be read again
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	contents, err := io.ReadAll(*body)
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(contents))
+	return contents, err
+}
+
+// Cassette is a recording of a run's requests, which is replayed with its Transport
+type Cassette struct {
+	// Recorded is the time the run saw as the current time, which replays should use too, eg: with
+	// pkg.FrozenClock
+	Recorded     time.Time
+	Interactions []Interaction
+
+	mu sync.Mutex
+	// Interactions which have been replayed
+	replayed map[int]bool
+}
+
+// Load a cassette written by a Recorder
+func Load(path string) (*Cassette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	var start header
+	if err := decoder.Decode(&start); err != nil {
+		return nil, fmt.Errorf("%s is not a cassette: %w", path, err)
+	}
+
+	cassette := &Cassette{Recorded: start.Recorded}
+	for {
+		var interaction Interaction
+		if err := decoder.Decode(&interaction); errors.Is(err, io.EOF) {
+			return cassette, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s has an invalid interaction: %w", path, err)
+		}
+
+		cassette.Interactions = append(cassette.Interactions, interaction)
+	}
+}
+
+// Transport answers requests with the cassette's recorded responses instead of calling the server. Requests are
+// matched by method and URL, in the order they were recorded, so repeated requests get the responses they got in
+// the recording. Once every recording of a GET request has been replayed, the last one is replayed again, as a
+// replay may repeat reads a recording cached. Any other request which wasn't recorded fails.
+func (c *Cassette) Transport() http.RoundTripper {
+	return replayer{c}
+}
+
+type replayer struct {
+	cassette *Cassette
+}
+
+func (r replayer) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+
+	interaction, ok := r.cassette.next(request.Method, request.URL.RequestURI())
+	if !ok {
+		return nil, fmt.Errorf("cassette has no recording of %s %s", request.Method, request.URL.RequestURI())
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Body))),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       request,
+	}, nil
+}
+
+// next recorded interaction for a request
+func (c *Cassette) next(method string, url string) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.replayed == nil {
+		c.replayed = make(map[int]bool)
+	}
+
+	last := -1
+	for i, interaction := range c.Interactions {
+		if interaction.Method != method || interaction.URL != url {
+			continue
+		}
+
+		if !c.replayed[i] {
+			c.replayed[i] = true
+			return interaction, true
+		}
+
+		last = i
+	}
+
+	if last < 0 || method != http.MethodGet {
+		return Interaction{}, false
+	}
+
+	return c.Interactions[last], true
+}
@@ -0,0 +1,139 @@
+package cassette_test
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ellisto/monorepo-versioning/pkg"
+	"github.com/ellisto/monorepo-versioning/pkg/cassette"
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+	"github.com/google/go-github/v50/github"
+)
+
+var update = flag.Bool("update", false, "record the cassettes in testdata again, from the fake repositories they replay")
+
+// monorepoCassette is a run versioning api and web after a release of each, with a breaking change to api and
+// features and fixes to web since
+const monorepoCassette = "testdata/monorepo.jsonl"
+
+// monorepoHead is the head of the branch the run in the cassette versioned
+const monorepoHead = "ae43b6882e34297f36d14169a06bbfc78dbea8c1"
+
+// monorepoRecorded is when the run in the cassette happened
+var monorepoRecorded = time.Date(2024, time.June, 3, 9, 30, 0, 0, time.UTC)
+
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// generateVersions of api and web with a dry run, as the recorded run did
+func generateVersions(t *testing.T, client *github.Client, revision string, clock pkg.Clock) []pkg.Result {
+	t.Helper()
+	a, err := pkg.New(
+		pkg.WithRepository("octocat/monorepo"),
+		pkg.WithClient(client),
+		pkg.WithBranch(githubtest.DefaultBranch, githubtest.DefaultBranch),
+		pkg.WithRevision(revision),
+		pkg.WithComponent("api", ""),
+		pkg.WithClock(clock),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a = a.WithLogger(slog.New(slog.NewTextHandler(testLogWriter{t}, nil)))
+	return pkg.GenerateVersions(context.Background(), []pkg.VersioningAction{a, a.ForComponent("web", "")}, true)
+}
+
+// recordMonorepo records the cassette from a fake of the repository's history
+func recordMonorepo(t *testing.T) {
+	t.Helper()
+	server := githubtest.NewServer("octocat", "monorepo")
+	defer server.Close()
+	released := server.Push(githubtest.DefaultBranch,
+		githubtest.Commit("feat(api): add users").WithFile("api/main.go", "package main\n"),
+		githubtest.Commit("feat(web): add a login page").WithFile("web/index.html", "<h1>Login</h1>\n"),
+		githubtest.Commit("fix(api): validate emails").WithFile("api/main.go", "package main\n\n// validate\n"),
+	)
+	server.AddRelease(githubtest.Release("api-1.3.0", released[2]))
+	server.AddRelease(githubtest.Release("web-0.9.2", released[2]))
+	server.Push(githubtest.DefaultBranch,
+		githubtest.Commit("fix(web): align the header"),
+		githubtest.Commit("docs: describe the architecture"),
+		githubtest.Commit("feat(api)!: remove the v1 endpoints\n\nBREAKING CHANGE: use the v2 endpoints instead"),
+		githubtest.Commit("chore(deps): bump golang.org/x/net").By("dependabot[bot]"),
+		githubtest.Commit("feat(web): add a settings page"),
+	)
+
+	if head := server.Head(githubtest.DefaultBranch); head != monorepoHead {
+		t.Fatalf("Expected the fake repository's head to be %s, but it's %s", monorepoHead, head)
+	}
+
+	recorder, err := cassette.Record(monorepoCassette, monorepoRecorded, server.Client().Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer recorder.Close()
+	client := github.NewClient(&http.Client{Transport: recorder})
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	generateVersions(t, client, monorepoHead, pkg.FrozenClock(monorepoRecorded))
+}
+
+func TestReplayMonorepo(t *testing.T) {
+	if *update {
+		recordMonorepo(t)
+	}
+
+	recording, err := cassette.Load(monorepoCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !recording.Recorded.Equal(monorepoRecorded) {
+		t.Errorf("Expected the cassette to be recorded at %s, but it was recorded at %s", monorepoRecorded, recording.Recorded)
+	}
+
+	// Replayed against GitHub.com's API URL, which the recording's requests are relative to
+	client := github.NewClient(&http.Client{Transport: recording.Transport()})
+	results := generateVersions(t, client, monorepoHead, pkg.FrozenClock(recording.Recorded))
+	expected := []struct {
+		component string
+		bump      pkg.Bump
+		version   string
+	}{
+		{component: "api", bump: pkg.BumpMajor, version: "2.0.0"},
+		{component: "web", bump: pkg.BumpMinor, version: "0.10.0"},
+	}
+
+	for i, test := range expected {
+		result := results[i]
+		if result.Component != test.component || result.Bump != test.bump || result.Version == nil || result.Version.String() != test.version {
+			t.Errorf("Expected a %s bump of %s to %s, but got a %s bump of %s to %v", test.bump, test.component, test.version, result.Bump, result.Component, result.Version)
+		}
+	}
+}
+
+func TestReplayFailsUnrecordedRequests(t *testing.T) {
+	recording, err := cassette.Load(monorepoCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := github.NewClient(&http.Client{Transport: recording.Transport()})
+	if _, err := client.Repositories.DeleteRelease(context.Background(), "octocat", "monorepo", 1); err == nil || !strings.Contains(err.Error(), "cassette has no recording of DELETE") {
+		t.Errorf("Expected deleting a release to fail, as it wasn't recorded, but got %v", err)
+	}
+}
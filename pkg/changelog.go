@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// ChangelogBetween renders the changelog of the component's changes after version from, up to and including
+// version to, such as for upgrade notes when consumers skip several releases. If to is nil, the newest stable
+// version is used. Both versions must have been released. The changelog is rendered in the notes format.
+func (a VersioningAction) ChangelogBetween(ctx context.Context, from *semver.Version, to *semver.Version) string {
+	releases := a.getAllReleases(ctx)
+	if to == nil {
+		to = a.CurrentVersions(ctx).Stable
+		if to == nil {
+			panic(fmt.Sprintf("Component %s has no stable releases to render the changelog up to", a.component))
+		}
+	}
+
+	if !from.LessThan(to) {
+		panic(fmt.Sprintf("Version %s must be older than version %s to render the changelog between them", from.String(), to.String()))
+	}
+
+	fromTag, toTag := a.releasedTagName(releases, from), a.releasedTagName(releases, to)
+	since := a.getTagChange(ctx, fromTag)
+	changelog := a
+	changelog.revision = a.getTagChange(ctx, toTag).sha
+	commits := changelog.applyMergeCommitPolicy(a.getNewCommits(ctx, &since, changelog.revision))
+
+	a.logger.Info("Rendering changelog", "component", a.component, "from", fromTag, "to", toTag, "commits", len(commits))
+	notes := a.releaseNotes(ctx, commits)
+	switch a.notesFormat {
+	case NotesText:
+		return fmt.Sprintf("%s → %s\n\n%s", from.String(), to.String(), notes.Text())
+	case NotesJSON:
+		contents, err := json.MarshalIndent(struct {
+			Component string       `json:"component"`
+			From      string       `json:"from"`
+			To        string       `json:"to"`
+			Notes     ReleaseNotes `json:"notes"`
+		}{a.component, from.String(), to.String(), notes}, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+
+		return string(contents)
+	default:
+		return fmt.Sprintf("## %s → %s\n%s", from.String(), to.String(), notes.Markdown())
+	}
+}
+
+// releasedTagName finds the tag of a released version of the component, panicking if it hasn't been released
+func (a VersioningAction) releasedTagName(releases []*github.RepositoryRelease, version *semver.Version) string {
+	for _, release := range releases {
+		if released := a.releaseVersion(release); released != nil && released.Equal(version) && !release.GetDraft() {
+			return release.GetTagName()
+		}
+	}
+
+	panic(fmt.Sprintf("Version %s of %s has not been released", version.String(), a.component))
+}
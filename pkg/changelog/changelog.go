@@ -0,0 +1,296 @@
+// Package changelog models the changelog of a version, its changes grouped into sections by the type of change
+// and the contributors credited for them, and renders it as markdown, plain text or JSON.
+//
+// It doesn't depend on where the changes come from, so that other tools can produce changelogs identical to the
+// action's release notes from their own commit sources: create one with New, then Add each change to its section,
+// or AddChange to section a conventional commit the way the action does, and Credit its author.
+package changelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formats a changelog can be rendered in
+const (
+	// Markdown formats changelogs as they appear on release pages
+	Markdown = "markdown"
+	// Text formats changelogs as plain text, eg: for emails
+	Text = "text"
+	// JSON formats changelogs as JSON of their sections and entries, for other tools to render
+	JSON = "json"
+)
+
+// Types of change, which each have a section
+const (
+	Breaking     = "breaking"
+	Features     = "features"
+	Fixes        = "fixes"
+	Refactors    = "refactors"
+	Dependencies = "dependencies"
+)
+
+// SectionTypes in the order sections are rendered
+var SectionTypes = []string{Breaking, Features, Fixes, Refactors, Dependencies}
+
+// emoji heading each type of section in markdown, as shortcodes
+var emoji = map[string]string{
+	Breaking:     ":hammer:",
+	Features:     ":bulb:",
+	Fixes:        ":construction_worker:",
+	Refactors:    ":raised_hands:",
+	Dependencies: ":package:",
+}
+
+// Changelog is the changes in a version grouped into sections by the type of change, which can be rendered in any
+// of the formats
+type Changelog struct {
+	Intro string `json:"intro"`
+	// Hotfix notes that the version was released from a hotfix branch, if it was
+	Hotfix string `json:"hotfix,omitempty"`
+	// Submodule notes the range of commits a submodule component was updated across, if it was
+	Submodule string `json:"submodule,omitempty"`
+	// Sections with at least one entry, in the order they're rendered
+	Sections []Section `json:"sections"`
+	// Contributors credited for the changes, as @login or name
+	Contributors []string `json:"contributors"`
+	messages     Messages
+}
+
+// Section is a type of change, eg: features
+type Section struct {
+	// Type of change: breaking, features, fixes, refactors, or dependencies
+	Type        string  `json:"type"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Entries     []Entry `json:"entries"`
+}
+
+// Entry is a single change
+type Entry struct {
+	// SHA of the commit which made the change, if it was a single commit
+	SHA         string `json:"sha,omitempty"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	// Author credited for the change, as @login or name, or empty if unknown
+	Author string `json:"author,omitempty"`
+}
+
+// Change is a conventional commit's change, which AddChange sections by its type
+type Change struct {
+	Entry
+	// Type of the conventional commit, eg: "feat"
+	Type string
+	// Breaking is whether the commit is a breaking change, eg: "feat!: ..."
+	Breaking bool
+}
+
+// New changelog without any changes, with headings and sentences in the language of messages
+func New(messages Messages) Changelog {
+	// Empty rather than nil, so that JSON has empty lists rather than nulls
+	return Changelog{Intro: messages.Intro, Sections: []Section{}, Contributors: []string{}, messages: messages}
+}
+
+// Add an entry to the section of a type of change, adding the section if it's the type's first entry
+func (c *Changelog) Add(sectionType string, entry Entry) {
+	for i := range c.Sections {
+		if c.Sections[i].Type == sectionType {
+			c.Sections[i].Entries = append(c.Sections[i].Entries, entry)
+			return
+		}
+	}
+
+	section := c.messages.section(sectionType)
+	section.Entries = []Entry{entry}
+	// Keep the sections in the order they're rendered
+	position := len(c.Sections)
+	for i, existing := range c.Sections {
+		if sectionOrder(existing.Type) > sectionOrder(sectionType) {
+			position = i
+			break
+		}
+	}
+
+	c.Sections = append(c.Sections[:position], append([]Section{section}, c.Sections[position:]...)...)
+}
+
+// AddChange to the sections of its type: a breaking change is listed under breaking changes, and also under
+// features, fixes or refactoring if it's one of those. Other types of change, eg: "docs", aren't listed.
+func (c *Changelog) AddChange(change Change) {
+	if change.Breaking {
+		c.Add(Breaking, change.Entry)
+	}
+
+	switch {
+	case change.Type == "feat":
+		c.Add(Features, change.Entry)
+	case change.Type == "fix":
+		c.Add(Fixes, change.Entry)
+	case strings.EqualFold(change.Type, "refactor"):
+		c.Add(Refactors, change.Entry)
+	}
+}
+
+// Credit a contributor, unless they're already credited. Logins and names aren't case-sensitive, as commits may be
+// authored with differently cased names.
+func (c *Changelog) Credit(contributor string) {
+	if contributor == "" {
+		return
+	}
+
+	for _, credited := range c.Contributors {
+		if strings.EqualFold(credited, contributor) {
+			return
+		}
+	}
+
+	c.Contributors = append(c.Contributors, contributor)
+}
+
+// sectionOrder is the position of a type of section when rendered
+func sectionOrder(sectionType string) int {
+	for i, known := range SectionTypes {
+		if known == sectionType {
+			return i
+		}
+	}
+
+	return len(SectionTypes)
+}
+
+// Format the changelog in a format, defaulting to markdown
+func (c Changelog) Format(format string) string {
+	switch format {
+	case Text:
+		return c.Text()
+	case JSON:
+		return c.JSON()
+	default:
+		return c.Markdown()
+	}
+}
+
+// Markdown renders the changelog as it appears on release pages
+func (c Changelog) Markdown() string {
+	var notes strings.Builder
+	if c.Hotfix != "" {
+		notes.WriteString(fmt.Sprintf("\n> :ambulance: %s\n", c.Hotfix))
+	}
+
+	notes.WriteString(fmt.Sprintf("\n> %s\n", c.Intro))
+	if c.Submodule != "" {
+		notes.WriteString(fmt.Sprintf("\n:link: %s\n", c.Submodule))
+	}
+
+	for _, sectionType := range SectionTypes {
+		if section, ok := c.section(sectionType); ok {
+			notes.WriteString(fmt.Sprintf("### %s %s\n_%s_\n", emoji[section.Type], section.Title, section.Description))
+			for _, entry := range section.Entries {
+				notes.WriteString(entry.markdown())
+			}
+		}
+
+		notes.WriteString("\n")
+	}
+
+	if len(c.Contributors) > 0 {
+		notes.WriteString(fmt.Sprintf("### :heart_eyes: %s\n_%s_\n", c.messages.Contributors, c.messages.ContributorsDescription))
+		for _, contributor := range c.Contributors {
+			notes.WriteString(fmt.Sprintf("* %s\n", contributor))
+		}
+	}
+
+	notes.WriteString("\n")
+	return notes.String()
+}
+
+// Text renders the changelog as plain text, with underlined headings
+func (c Changelog) Text() string {
+	var notes strings.Builder
+	if c.Hotfix != "" {
+		notes.WriteString(fmt.Sprintf("%s\n\n", c.Hotfix))
+	}
+
+	notes.WriteString(fmt.Sprintf("%s\n", c.Intro))
+	if c.Submodule != "" {
+		notes.WriteString(fmt.Sprintf("\n%s\n", c.Submodule))
+	}
+
+	for _, section := range c.Sections {
+		notes.WriteString(fmt.Sprintf("\n%s\n%s\n", section.Title, underline(section.Title)))
+		for _, entry := range section.Entries {
+			notes.WriteString(entry.text())
+		}
+	}
+
+	if len(c.Contributors) > 0 {
+		notes.WriteString(fmt.Sprintf("\n%s\n%s\n", c.messages.Contributors, underline(c.messages.Contributors)))
+		for _, contributor := range c.Contributors {
+			notes.WriteString(fmt.Sprintf("- %s\n", contributor))
+		}
+	}
+
+	return notes.String()
+}
+
+// JSON renders the changelog as indented JSON
+func (c Changelog) JSON() string {
+	contents, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	return string(contents)
+}
+
+// section of a type of change, if the changelog has any changes of that type
+func (c Changelog) section(sectionType string) (Section, bool) {
+	for _, section := range c.Sections {
+		if section.Type == sectionType {
+			return section, true
+		}
+	}
+
+	return Section{}, false
+}
+
+func (e Entry) markdown() string {
+	if e.SHA != "" {
+		return fmt.Sprintf("* [`%s`](%s) %s%s\n", shortSHA(e.SHA), e.URL, e.Description, e.credit())
+	}
+
+	return fmt.Sprintf("* [%s](%s)%s\n", e.Description, e.URL, e.credit())
+}
+
+func (e Entry) text() string {
+	if e.SHA != "" {
+		return fmt.Sprintf("- %s %s%s\n", shortSHA(e.SHA), e.Description, e.credit())
+	}
+
+	return fmt.Sprintf("- %s%s\n", e.Description, e.credit())
+}
+
+// credit for the entry's author at the end of the entry, or empty if the author isn't known
+func (e Entry) credit() string {
+	if e.Author == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" (%s)", e.Author)
+}
+
+// shortSHA shortens a commit SHA to 7 characters to match how GitHub usually displays it
+func shortSHA(sha string) string {
+	if len(sha) < 7 {
+		return sha
+	}
+
+	return sha[:7]
+}
+
+// underline a plain text heading
+func underline(heading string) string {
+	return strings.Repeat("-", utf8.RuneCountInString(heading))
+}
@@ -0,0 +1,158 @@
+package changelog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the language changelogs are written in unless another locale is selected
+const DefaultLocale = "en"
+
+// Messages are the headings and boilerplate sentences of changelogs in a single language. Headings and descriptions
+// are written without their markdown, which is the same in every language.
+type Messages struct {
+	Intro                   string
+	Hotfix                  string
+	Submodule               string
+	Breaking                string
+	BreakingDescription     string
+	Features                string
+	FeaturesDescription     string
+	Fixes                   string
+	FixesDescription        string
+	Refactors               string
+	RefactorsDescription    string
+	Dependencies            string
+	DependenciesDescription string
+	Contributors            string
+	ContributorsDescription string
+	// MigratedFrom notes the existing tag a migrated release was created from
+	MigratedFrom string
+	// Yanked warns that a version was withdrawn
+	Yanked string
+	// YankedReplacement names the version to use instead of a withdrawn one
+	YankedReplacement string
+}
+
+// catalog of the messages of each supported locale
+var catalog = map[string]Messages{
+	"en": {
+		Intro:                   "Below is the changelog for this version. Changes are categorised by the type of change (breaking change, new feature, or bugfix). If there isn't a heading for a type of change, there were no relevant changes.",
+		Hotfix:                  "This is a hotfix release from the `%s` branch. Its changes may not be on the default branch yet.",
+		Submodule:               "Updates the submodule `%s`: %s.",
+		Breaking:                "Breaking Changes",
+		BreakingDescription:     "Breaking changes indicate that an existing behaviour or feature no longer works as before. Pay close attention to any listed breaking changes, and make sure they are acknowledged or mitigated before deploying this version.",
+		Features:                "Features",
+		FeaturesDescription:     "Feature changes contain some new functionality. Existing behaviour should not be affected.",
+		Fixes:                   "Fixes",
+		FixesDescription:        "Fixes some unintended behaviour from a previous version. You should familiarise yourself with these changes to understand any problems you may have experienced in previous versions.",
+		Refactors:               "Refactoring",
+		RefactorsDescription:    "Changes or improvements to an existing implementation.",
+		Dependencies:            "Dependencies",
+		DependenciesDescription: "Updates to the component's dependencies, usually made by a dependency update bot.",
+		Contributors:            "Contributors",
+		ContributorsDescription: "These people contributed to this version of the component - thank you! Note: GitHub's auto-generated contributor list may also include contributors to other components.",
+		MigratedFrom:            "Migrated from the existing tag `%s`.",
+		Yanked:                  "This version has been yanked, and should no longer be used.",
+		YankedReplacement:       "Upgrade to %s instead.",
+	},
+	"de": {
+		Intro:                   "Unten steht das Änderungsprotokoll dieser Version. Die Änderungen sind nach ihrer Art gruppiert (inkompatible Änderung, neue Funktion oder Fehlerbehebung). Fehlt die Überschrift einer Art, gab es keine entsprechenden Änderungen.",
+		Hotfix:                  "Dies ist ein Hotfix-Release aus dem Branch `%s`. Seine Änderungen sind möglicherweise noch nicht im Standard-Branch.",
+		Submodule:               "Aktualisiert das Submodul `%s`: %s.",
+		Breaking:                "Inkompatible Änderungen",
+		BreakingDescription:     "Inkompatible Änderungen bedeuten, dass ein bestehendes Verhalten oder eine bestehende Funktion nicht mehr wie bisher funktioniert. Achte genau auf alle aufgeführten inkompatiblen Änderungen und stelle sicher, dass sie berücksichtigt oder abgefangen werden, bevor du diese Version auslieferst.",
+		Features:                "Neue Funktionen",
+		FeaturesDescription:     "Neue Funktionen fügen Funktionalität hinzu. Bestehendes Verhalten sollte nicht beeinträchtigt sein.",
+		Fixes:                   "Fehlerbehebungen",
+		FixesDescription:        "Behebt unbeabsichtigtes Verhalten einer früheren Version. Mache dich mit diesen Änderungen vertraut, um Probleme zu verstehen, die in früheren Versionen aufgetreten sein könnten.",
+		Refactors:               "Refactoring",
+		RefactorsDescription:    "Änderungen oder Verbesserungen an einer bestehenden Implementierung.",
+		Dependencies:            "Abhängigkeiten",
+		DependenciesDescription: "Aktualisierungen der Abhängigkeiten der Komponente, meist durch einen Bot für Abhängigkeitsaktualisierungen.",
+		Contributors:            "Mitwirkende",
+		ContributorsDescription: "Diese Personen haben zu dieser Version der Komponente beigetragen – danke! Hinweis: Die von GitHub automatisch erstellte Liste der Mitwirkenden kann auch Mitwirkende anderer Komponenten enthalten.",
+		MigratedFrom:            "Aus dem bestehenden Tag `%s` übernommen.",
+		Yanked:                  "Diese Version wurde zurückgezogen und sollte nicht mehr verwendet werden.",
+		YankedReplacement:       "Aktualisiere stattdessen auf %s.",
+	},
+	"fr": {
+		Intro:                   "Voici le journal des modifications de cette version. Les modifications sont classées par type (changement incompatible, nouvelle fonctionnalité ou correction). Si un type de modification n'a pas de titre, il n'y a eu aucune modification de ce type.",
+		Hotfix:                  "Ceci est une version corrective publiée depuis la branche `%s`. Ses modifications ne sont peut-être pas encore sur la branche par défaut.",
+		Submodule:               "Met à jour le sous-module `%s` : %s.",
+		Breaking:                "Changements incompatibles",
+		BreakingDescription:     "Les changements incompatibles signifient qu'un comportement ou une fonctionnalité existante ne fonctionne plus comme avant. Soyez attentif à chaque changement incompatible listé, et assurez-vous qu'il est pris en compte ou atténué avant de déployer cette version.",
+		Features:                "Fonctionnalités",
+		FeaturesDescription:     "Les fonctionnalités apportent de nouvelles possibilités. Le comportement existant ne devrait pas être affecté.",
+		Fixes:                   "Corrections",
+		FixesDescription:        "Corrige un comportement involontaire d'une version précédente. Prenez connaissance de ces modifications pour comprendre les problèmes que vous avez pu rencontrer dans les versions précédentes.",
+		Refactors:               "Refactorisation",
+		RefactorsDescription:    "Modifications ou améliorations d'une implémentation existante.",
+		Dependencies:            "Dépendances",
+		DependenciesDescription: "Mises à jour des dépendances du composant, généralement effectuées par un bot de mise à jour des dépendances.",
+		Contributors:            "Contributeurs",
+		ContributorsDescription: "Ces personnes ont contribué à cette version du composant, merci ! Remarque : la liste des contributeurs générée automatiquement par GitHub peut aussi inclure des contributeurs d'autres composants.",
+		MigratedFrom:            "Migré depuis le tag existant `%s`.",
+		Yanked:                  "Cette version a été retirée et ne devrait plus être utilisée.",
+		YankedReplacement:       "Passez plutôt à la version %s.",
+	},
+	"ja": {
+		Intro:                   "このバージョンの変更履歴です。変更は種類（破壊的変更、新機能、バグ修正）ごとに分類されています。見出しのない種類の変更はありません。",
+		Hotfix:                  "これは `%s` ブランチからのホットフィックスリリースです。変更はまだデフォルトブランチに含まれていない可能性があります。",
+		Submodule:               "サブモジュール `%s` を更新します: %s。",
+		Breaking:                "破壊的変更",
+		BreakingDescription:     "破壊的変更は、既存の動作や機能がこれまでどおりに動作しなくなることを示します。このバージョンをデプロイする前に、記載された破壊的変更をよく確認し、対応または緩和してください。",
+		Features:                "新機能",
+		FeaturesDescription:     "新しい機能が追加されています。既存の動作には影響しません。",
+		Fixes:                   "バグ修正",
+		FixesDescription:        "以前のバージョンの意図しない動作を修正しています。以前のバージョンで発生していた問題を理解するために、これらの変更を確認してください。",
+		Refactors:               "リファクタリング",
+		RefactorsDescription:    "既存の実装の変更または改善です。",
+		Dependencies:            "依存関係",
+		DependenciesDescription: "コンポーネントの依存関係の更新です。通常は依存関係更新ボットによって作成されます。",
+		Contributors:            "コントリビューター",
+		ContributorsDescription: "このバージョンのコンポーネントに貢献してくださった方々です。ありがとうございます！注: GitHub が自動生成するコントリビューター一覧には、他のコンポーネントへの貢献者も含まれる場合があります。",
+		MigratedFrom:            "既存のタグ `%s` から移行しました。",
+		Yanked:                  "このバージョンは取り下げられました。今後は使用しないでください。",
+		YankedReplacement:       "代わりに %s にアップグレードしてください。",
+	},
+}
+
+// Locales changelogs can be written in, sorted by name
+func Locales() []string {
+	var locales []string
+	for locale := range catalog {
+		locales = append(locales, locale)
+	}
+
+	sort.Strings(locales)
+	return locales
+}
+
+// MessagesFor a locale, eg: "de", falling back to the default locale
+func MessagesFor(locale string) Messages {
+	if messages, ok := catalog[locale]; ok {
+		return messages
+	}
+
+	return catalog[DefaultLocale]
+}
+
+// section of a type of change, without any entries, titled in the messages' language
+func (m Messages) section(sectionType string) Section {
+	switch sectionType {
+	case Breaking:
+		return Section{Type: Breaking, Title: m.Breaking, Description: m.BreakingDescription}
+	case Features:
+		return Section{Type: Features, Title: m.Features, Description: m.FeaturesDescription}
+	case Fixes:
+		return Section{Type: Fixes, Title: m.Fixes, Description: m.FixesDescription}
+	case Refactors:
+		return Section{Type: Refactors, Title: m.Refactors, Description: m.RefactorsDescription}
+	case Dependencies:
+		return Section{Type: Dependencies, Title: m.Dependencies, Description: m.DependenciesDescription}
+	default:
+		panic(fmt.Sprintf("Unknown changelog section %q, expected one of: %s", sectionType, strings.Join(SectionTypes, ", ")))
+	}
+}
@@ -0,0 +1,215 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// Rules the check enforces
+const (
+	// CheckRuleScope fails commits which were ignored, because they aren't conventional commits or have no scope
+	CheckRuleScope = "scope"
+	// CheckRuleMajorLabel fails major versions of pull requests without the major label
+	CheckRuleMajorLabel = "major-label"
+	// CheckRuleFrozen fails versions of frozen components, which wouldn't be released
+	CheckRuleFrozen = "frozen"
+	// CheckRuleApproval fails versions which were held, as their release was denied or wasn't approved in time
+	CheckRuleApproval = "approval"
+	// CheckRuleRelease fails versions which couldn't be released, eg: because they'd violate a constraint
+	CheckRuleRelease = "release"
+)
+
+// CheckConfig configures the rules of the check operation, beyond those it always enforces
+type CheckConfig struct {
+	// RequireScope fails every commit which isn't a conventional commit with a scope, rather than only those which
+	// mention a component
+	RequireScope bool `yaml:"require-scope,omitempty"`
+	// MajorLabel is the label a pull request needs to release a major version, eg: "breaking-change"
+	MajorLabel string `yaml:"major-label,omitempty"`
+}
+
+// CheckViolation is a broken rule, which fails the check
+type CheckViolation struct {
+	Rule      string `json:"rule"`
+	Component string `json:"component,omitempty"`
+	// SHA of the commit which broke the rule, if it was a single commit
+	SHA     string `json:"sha,omitempty"`
+	Message string `json:"message"`
+}
+
+// CheckReport is the outcome of checking the versions a pull request would release
+type CheckReport struct {
+	// Results of generating each component's version with a dry run
+	Results    []Result
+	Violations []CheckViolation
+}
+
+// Check generates the versions of several components with a dry run, as GenerateVersions would if the pull request
+// was merged, and reports every rule the commits break, so that pull requests fail before they're merged rather
+// than when they're released: commits which were ignored, major versions of pull requests without the configured
+// major label, versions of frozen components, versions held for approval, and versions which couldn't be released. Nothing is written to the
+// repository, so the token only needs read access. Labels are the pull request's labels. Each violation is logged
+// as an error, which GitHub Actions shows as an annotation.
+func Check(ctx context.Context, actions []VersioningAction, labels []string) CheckReport {
+	first := actions[0]
+	var report CheckReport
+	report.Results = checkedVersions(ctx, actions, &report.Violations)
+	rules := first.config.Check
+	ownCommits := first.pullRequestCommits(ctx)
+	// Commits already on the base branch aren't the pull request's to fix, even if they're not released yet
+	own := func(decision CommitDecision) bool {
+		return ownCommits == nil || ownCommits[decision.SHA]
+	}
+
+	checked := make(map[string]bool)
+	for _, result := range report.Results {
+		for _, problem := range skippedCommitProblems(result.Component, result.Commits) {
+			if own(problem.decision) {
+				checked[problem.decision.SHA] = true
+				report.Violations = append(report.Violations, CheckViolation{Rule: CheckRuleScope, Component: result.Component, SHA: problem.decision.SHA, Message: problem.message})
+			}
+		}
+
+		changed, breaking := false, false
+		for _, decision := range result.Commits {
+			if own(decision) && decision.MatchedScope {
+				changed = true
+				breaking = breaking || decision.Bump == BumpMajor
+			}
+		}
+
+		if result.Frozen != "" && changed {
+			report.Violations = append(report.Violations, CheckViolation{
+				Rule:      CheckRuleFrozen,
+				Component: result.Component,
+				Message:   fmt.Sprintf("%s %s wouldn't be released, as %s. Wait until it's released, or leave its changes out of the pull request", result.Component, result.Version, result.Frozen),
+			})
+		}
+
+		if result.Held != "" && changed {
+			report.Violations = append(report.Violations, CheckViolation{
+				Rule:      CheckRuleApproval,
+				Component: result.Component,
+				Message:   fmt.Sprintf("%s %s wouldn't be released, as %s. Get the release approved, or leave its changes out of the pull request", result.Component, result.Version, result.Held),
+			})
+		}
+
+		if result.Version != nil && result.Bump == BumpMajor && breaking && rules.MajorLabel != "" && !slices.Contains(labels, rules.MajorLabel) {
+			report.Violations = append(report.Violations, CheckViolation{
+				Rule:      CheckRuleMajorLabel,
+				Component: result.Component,
+				Message:   fmt.Sprintf("%s %s is a major version, so the pull request needs the %s label", result.Component, result.Version, rules.MajorLabel),
+			})
+		}
+	}
+
+	if rules.RequireScope {
+		// Commits are in the range of every component, so each is only reported once
+		for _, result := range report.Results {
+			for _, decision := range result.Commits {
+				// Dependency updates are recognised without a conventional message
+				if checked[decision.SHA] || !own(decision) || decision.Type == "dependencies" || (decision.Parsed && decision.Scope != "") {
+					continue
+				}
+
+				checked[decision.SHA] = true
+				message := fmt.Sprintf("Commit %s is not a conventional commit. Use a message like \"fix(<component>): ...\"", shortSHA(decision.SHA))
+				if decision.Parsed {
+					message = fmt.Sprintf("Commit %s has no scope. Use a message like \"%s(<component>): ...\"", shortSHA(decision.SHA), decision.Type)
+				}
+
+				report.Violations = append(report.Violations, CheckViolation{Rule: CheckRuleScope, SHA: decision.SHA, Message: message})
+			}
+		}
+	}
+
+	for _, violation := range report.Violations {
+		attrs := []any{"rule", violation.Rule}
+		if violation.Component != "" {
+			attrs = append(attrs, "component", violation.Component)
+		}
+
+		first.logger.Error(violation.Message, attrs...)
+	}
+
+	if len(report.Violations) == 0 {
+		first.logger.Info("Check passed", "components", len(report.Results))
+	}
+
+	return report
+}
+
+// pullRequestCommits are the SHAs of the commits of the pull request being checked, or nil if there isn't one, so
+// that every commit is checked
+func (a VersioningAction) pullRequestCommits(ctx context.Context) map[string]bool {
+	if a.pullRequest == 0 {
+		return nil
+	}
+
+	commits := make(map[string]bool)
+	for page := 1; ; page++ {
+		requestCtx, cancel := a.requestContext(ctx)
+		listed, _, err := a.client.PullRequests.ListCommits(requestCtx, a.owner, a.repository, a.pullRequest, &github.ListOptions{Page: page, PerPage: 100})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		if len(listed) == 0 {
+			return commits
+		}
+
+		for _, commit := range listed {
+			commits[commit.GetSHA()] = true
+		}
+	}
+}
+
+// checkedVersions generates the versions of the components with a dry run, recording a violation instead of
+// failing if a version couldn't be released, eg: because it'd violate a constraint between components. The results
+// of the other components are still checked.
+func checkedVersions(ctx context.Context, actions []VersioningAction, violations *[]CheckViolation) (results []Result) {
+	prepared, violation := checked("", func() []VersioningAction { return prepareVersions(ctx, actions) })
+	if violation != nil {
+		// Each component is still versioned on its own, without the groups and constraints which couldn't be kept
+		*violations = append(*violations, *violation)
+		prepared = actions
+	}
+
+	type checkedResult struct {
+		result    Result
+		violation *CheckViolation
+	}
+
+	checkedResults := forEachComponent(prepared, func(a VersioningAction) checkedResult {
+		a.logger.Info("Generating version", "component", a.component)
+		result, violation := checked(a.component, func() Result { return a.GenerateVersion(ctx, true) })
+		return checkedResult{result: result, violation: violation}
+	})
+
+	for _, checkedResult := range checkedResults {
+		if checkedResult.violation != nil {
+			*violations = append(*violations, *checkedResult.violation)
+		} else {
+			results = append(results, checkedResult.result)
+		}
+	}
+
+	return results
+}
+
+// checked calls fn, returning a violation of the release rule instead of failing if it panics, whether because a
+// rule stops the release or the API couldn't be called, as either way the version can't be released
+func checked[T any](component string, fn func() T) (value T, violation *CheckViolation) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			violation = &CheckViolation{Rule: CheckRuleRelease, Component: component, Message: strings.TrimSpace(fmt.Sprint(recovered))}
+		}
+	}()
+
+	return fn(), nil
+}
@@ -0,0 +1,35 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+)
+
+func TestCheckKeepsOtherResults(t *testing.T) {
+	server := newTestServer(t)
+	released := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat: add api and web"))[0]
+	server.AddRelease(githubtest.Release("api-1.0.0", released))
+	server.AddRelease(githubtest.Release("web-1.0.0", released))
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api)!: remove the v1 endpoints"), githubtest.Commit("fix(web): style the header"))
+	a := newTestAction(t, server, "api").WithConfig(Config{Components: map[string]ComponentConfig{
+		"web": {Constraints: []Constraint{{Component: "api"}}},
+	}})
+
+	report := Check(context.Background(), []VersioningAction{a, a.ForComponent("web", "")}, nil)
+	if len(report.Violations) != 1 || report.Violations[0].Rule != CheckRuleRelease || !strings.Contains(report.Violations[0].Message, "would violate the constraint") {
+		t.Fatalf("Expected a violation of the constraint, but got %+v", report.Violations)
+	}
+
+	// Both components are still versioned on their own, so their results can be previewed
+	versions := make(map[string]string)
+	for _, result := range report.Results {
+		versions[result.Component] = result.Version.String()
+	}
+
+	if len(versions) != 2 || versions["api"] != "2.0.0" || versions["web"] != "1.0.1" {
+		t.Errorf("Expected api 2.0.0 and web 1.0.1, but got %v", versions)
+	}
+}
@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// checkRunName is shown in the checks tab of pull requests and commits
+const checkRunName = "Versioning"
+
+// maxCheckRunText is the longest check run output text GitHub accepts
+const maxCheckRunText = 65535
+
+// CreateCheckRun reports the computed versions and release notes as a completed check run on the commit, so that
+// they show up in the checks tab rather than only in the workflow log. The check fails if any commit mentioning a
+// component was skipped because it isn't a valid conventional commit for the component. Returns the check run's
+// URL.
+func (a VersioningAction) CreateCheckRun(ctx context.Context, headSHA string, results []Result) string {
+	var problems []commitProblem
+	var versions []string
+	for _, result := range results {
+		problems = append(problems, skippedCommitProblems(result.Component, result.Commits)...)
+		if result.Version != nil {
+			versions = append(versions, fmt.Sprintf("%s %s", result.Component, result.Version.String()))
+		}
+	}
+
+	title := "No new versions"
+	if len(versions) > 0 {
+		title = strings.Join(versions, ", ")
+	}
+
+	conclusion := "success"
+	text := strings.Builder{}
+	if len(problems) > 0 {
+		conclusion = "failure"
+		title = fmt.Sprintf("%d invalid commits", len(problems))
+		text.WriteString("### :warning: Invalid commits\n")
+		for _, problem := range problems {
+			text.WriteString(fmt.Sprintf("* %s: %s\n", problem.message, problem.decision.Summary))
+		}
+	}
+
+	text.WriteString(releaseNotesDetails(results))
+	output := text.String()
+	if len(output) > maxCheckRunText {
+		output = output[:maxCheckRunText-len("\n…")] + "\n…"
+	}
+
+	a.logger.Info("Creating check run", "name", checkRunName, "sha", headSHA, "conclusion", conclusion)
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	checkRun, _, err := a.client.Checks.CreateCheckRun(requestCtx, a.owner, a.repository, github.CreateCheckRunOptions{
+		Name:        checkRunName,
+		HeadSHA:     headSHA,
+		Status:      github.String("completed"),
+		Conclusion:  &conclusion,
+		CompletedAt: &github.Timestamp{Time: a.clock.Now()},
+		Output: &github.CheckRunOutput{
+			Title:   &title,
+			Summary: github.String(versionsTable(results)),
+			Text:    &output,
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return checkRun.GetHTMLURL()
+}
@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// cherryPickTrailer matches the line "git cherry-pick -x" adds to the message of a copied commit, capturing the SHA
+// of the original commit
+var cherryPickTrailer = regexp.MustCompile(`(?m)^\(cherry picked from commit ([0-9a-f]{7,40})\)\s*$`)
+
+// withoutReleasedCommits leaves out the commits which are copies of commits already released from another branch,
+// eg: hotfixes cherry-picked back to the default branch, so they don't bump the version or appear in the notes
+// again. Copies are found by the original's SHA in a "cherry picked from commit" line, or by having the same
+// message and author. Returns the remaining commits, and the tag each left out commit was released in, keyed by
+// SHA.
+func (a VersioningAction) withoutReleasedCommits(ctx context.Context, commits []*github.RepositoryCommit, previous *changePoint, existingReleases []*github.RepositoryRelease) ([]*github.RepositoryCommit, map[string]string) {
+	releasedSHAs, releasedIdentities := a.releasedElsewhere(ctx, commits, previous, existingReleases)
+	if len(releasedSHAs) == 0 {
+		return commits, nil
+	}
+
+	var remaining []*github.RepositoryCommit
+	released := make(map[string]string)
+	for _, commit := range commits {
+		tagName, ok := releasedSHAs[commit.GetSHA()]
+		if !ok {
+			tagName, ok = releasedIdentities[commitIdentity(commit)]
+		}
+
+		if !ok {
+			tagName, ok = cherryPickedFrom(commit, releasedSHAs)
+		}
+
+		if ok {
+			a.logger.Info("Leaving out commit already released from another branch", "component", a.component, "sha", commit.GetSHA(), "tag", tagName)
+			released[commit.GetSHA()] = tagName
+			continue
+		}
+
+		remaining = append(remaining, commit)
+	}
+
+	return remaining, released
+}
+
+// releasedElsewhere finds the commits of the component's releases published since the new commits are counted
+// from, which aren't reachable from the revision, eg: releases from hotfix branches. Only patches of the newest release's minor
+// version are searched, as fixes backported to older versions are still new to newer ones. Returns the tag each
+// commit was released in, keyed by SHA and by commit identity.
+func (a VersioningAction) releasedElsewhere(ctx context.Context, commits []*github.RepositoryCommit, previous *changePoint, existingReleases []*github.RepositoryRelease) (map[string]string, map[string]string) {
+	if len(commits) == 0 || len(existingReleases) == 0 || previous == nil {
+		return nil, nil
+	}
+
+	newest := a.releaseVersion(existingReleases[0])
+	if newest == nil {
+		return nil, nil
+	}
+
+	bySHA, byIdentity := make(map[string]string), make(map[string]string)
+	for _, release := range existingReleases {
+		version := a.releaseVersion(release)
+		if version == nil || version.Major() != newest.Major() || version.Minor() != newest.Minor() {
+			continue
+		}
+
+		if release.GetDraft() || release.GetPublishedAt().Before(previous.time) {
+			// Released before the new commits were counted from, so its commits are already accounted for
+			continue
+		}
+
+		tagChange := a.getTagChange(ctx, release.GetTagName())
+		a.history.mu.Lock()
+		onBranch := len(a.history.commits.reachable(previous.sha)) > 0
+		a.history.mu.Unlock()
+		if tagChange.sha == previous.sha && onBranch {
+			// The new commits were made since this release, on the same branch
+			continue
+		}
+
+		// The release's commits are those made since the release it was based on, or if that isn't recorded,
+		// those made on or after the time the new commits are counted from, which includes the release's own commit
+		// if it's the previous change
+		var releasedCommits []*github.RepositoryCommit
+		if metadata, ok := ParseReleaseMetadata(release.GetBody()); ok && metadata.BaseSHA != "" {
+			base := a.getCommitChange(ctx, metadata.BaseSHA)
+			releasedCommits = a.listCommits(ctx, tagChange.sha, base.time).slice(base)
+		} else {
+			releasedCommits = a.listCommits(ctx, tagChange.sha, previous.time).commits
+		}
+
+		for _, commit := range releasedCommits {
+			bySHA[commit.GetSHA()] = release.GetTagName()
+			byIdentity[commitIdentity(commit)] = release.GetTagName()
+		}
+	}
+
+	return bySHA, byIdentity
+}
+
+// cherryPickedFrom finds the tag a commit's original was released in, if its message says which commit it was
+// cherry-picked from
+func cherryPickedFrom(commit *github.RepositoryCommit, releasedSHAs map[string]string) (string, bool) {
+	for _, match := range cherryPickTrailer.FindAllStringSubmatch(commit.GetCommit().GetMessage(), -1) {
+		for sha, tagName := range releasedSHAs {
+			if strings.HasPrefix(sha, match[1]) {
+				return tagName, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// getCommitChange gets a commit as a change point
+func (a VersioningAction) getCommitChange(ctx context.Context, sha string) *changePoint {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	commit, _, err := a.client.Git.GetCommit(requestCtx, a.owner, a.repository, sha)
+	if err != nil {
+		panic(err)
+	}
+
+	return &changePoint{sha: sha, time: commit.GetCommitter().GetDate().Time}
+}
+
+// commitIdentity identifies the copies of a commit, which have the same message and author but a different SHA
+func commitIdentity(commit *github.RepositoryCommit) string {
+	message := strings.TrimSpace(cherryPickTrailer.ReplaceAllString(commit.GetCommit().GetMessage(), ""))
+	author := commit.GetCommit().GetAuthor()
+	return fmt.Sprintf("%s\x00%s\x00%s", message, strings.ToLower(author.GetEmail()), author.GetName())
+}
+
+// skipReleasedDecisions records that the commits already released from another branch were left out of the
+// version, replacing the decisions to include them
+func skipReleasedDecisions(decisions []CommitDecision, released map[string]string) {
+	for i, decision := range decisions {
+		if tagName, ok := released[decision.SHA]; ok && decision.MatchedScope {
+			decisions[i].MatchedScope = false
+			decisions[i].Bump = BumpNone
+			decisions[i].Reason = fmt.Sprintf("skipped: already released in %s", tagName)
+		}
+	}
+}
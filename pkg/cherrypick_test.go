@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+	"github.com/google/go-github/v50/github"
+)
+
+func TestGenerateVersionLeavesOutReleasedCherryPicks(t *testing.T) {
+	tests := []struct {
+		name   string
+		commit func(hotfix string) githubtest.CommitFixture
+		// Whether the hotfix's release records the release it was based on
+		metadata bool
+		version  string
+	}{
+		{
+			name: "same message and author",
+			commit: func(string) githubtest.CommitFixture {
+				return githubtest.Commit("fix(api): handle crashes on startup")
+			},
+		},
+		{
+			name: "same message and author of a release with metadata",
+			commit: func(string) githubtest.CommitFixture {
+				return githubtest.Commit("fix(api): handle crashes on startup")
+			},
+			metadata: true,
+		},
+		{
+			name: "cherry-picked with the original's SHA",
+			commit: func(hotfix string) githubtest.CommitFixture {
+				return githubtest.Commit(fmt.Sprintf("fix(api): handle crashes at startup\n\n(cherry picked from commit %s)", hotfix[:12]))
+			},
+		},
+		{
+			name: "same message by another author",
+			commit: func(string) githubtest.CommitFixture {
+				return githubtest.Commit("fix(api): handle crashes on startup").By("hubot")
+			},
+			version: "1.2.2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestServer(t)
+			released := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add users"))
+			server.AddRelease(githubtest.Release("api-1.2.0", released[0]))
+
+			// The hotfix is released from a branch of 1.2.0, then merged back to the default branch
+			_, _, err := server.Client().Git.CreateRef(context.Background(), "octocat", "monorepo", &github.Reference{
+				Ref:    github.String("refs/heads/hotfix/1.2"),
+				Object: &github.GitObject{SHA: &released[0]},
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hotfix := server.Push("hotfix/1.2", githubtest.Commit("fix(api): handle crashes on startup"))
+			fixture := githubtest.Release("api-1.2.1", hotfix[0])
+			if test.metadata {
+				fixture = fixture.WithNotes(ReleaseMetadata{Component: "api", Version: "1.2.1", SHA: hotfix[0], BaseSHA: released[0], Bump: BumpPatch}.block())
+			}
+
+			server.AddRelease(fixture)
+			// Merged back after the hotfix was released
+			picked := server.Push(githubtest.DefaultBranch, test.commit(hotfix[0]).At(time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)))
+
+			result := newTestAction(t, server, "api").GenerateVersion(context.Background(), true)
+			if len(result.Commits) != 1 || result.Commits[0].SHA != picked[0] {
+				t.Fatalf("Expected a decision for the merged back commit, but got %+v", result.Commits)
+			}
+
+			// The new commits and the hotfix's are each listed once, which takes two pages
+			listed := 0
+			for _, request := range server.Requests() {
+				if request == "GET /repos/octocat/monorepo/commits" {
+					listed++
+				}
+			}
+
+			if listed != 4 {
+				t.Errorf("Expected 4 pages of commits to be listed, but %d were", listed)
+			}
+
+			decision := result.Commits[0]
+			if test.version == "" {
+				if result.Version != nil {
+					t.Errorf("Expected no version, as the hotfix was already released, but got %s", result.Version)
+				}
+
+				if decision.MatchedScope || decision.Reason != "skipped: already released in api-1.2.1" {
+					t.Errorf("Expected the commit to be skipped as it was released in api-1.2.1, but got %+v", decision)
+				}
+
+				return
+			}
+
+			if result.Version == nil || result.Version.String() != test.version {
+				t.Fatalf("Expected version %s, but got %v", test.version, result.Version)
+			}
+
+			if !decision.MatchedScope || decision.Bump != BumpPatch {
+				t.Errorf("Expected the commit to be included as a patch, but got %+v", decision)
+			}
+
+			if !strings.Contains(result.Preview.Notes, "handle crashes on startup") {
+				t.Errorf("Expected the commit in the release notes, but got %q", result.Preview.Notes)
+			}
+		})
+	}
+}
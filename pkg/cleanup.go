@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// CleanupPrereleases deletes the component's prereleases, and their tags, which have been superseded by a stable
+// release or which were published longer than maxAge ago. A maxAge of zero only deletes superseded prereleases.
+// If dryRun is true, the prereleases are only logged. The tags of the deleted prereleases are returned.
+func (a VersioningAction) CleanupPrereleases(ctx context.Context, maxAge time.Duration, dryRun bool) []string {
+	releases := a.getAllReleases(ctx)
+	stableReleases := publishedReleases(a.filterAndSortReleasesForComponent(releases))
+
+	var latestStableVersion *semver.Version
+	if len(stableReleases) > 0 {
+		latestStableVersion = a.releaseVersion(stableReleases[0])
+	}
+
+	var deletedTags []string
+	for _, release := range a.filterPrereleasesForComponent(releases) {
+		version := a.releaseVersion(release)
+		age := a.clock.Now().Sub(release.GetPublishedAt().Time)
+
+		var reason string
+		if latestStableVersion != nil && !version.GreaterThan(latestStableVersion) {
+			reason = fmt.Sprintf("superseded by %s", latestStableVersion.String())
+		} else if maxAge > 0 && age > maxAge {
+			reason = fmt.Sprintf("published %s ago", age.Round(time.Hour))
+		} else {
+			continue
+		}
+
+		if dryRun {
+			a.logger.Info("Would delete prerelease, but this is a dry run", "component", a.component, "tag", release.GetTagName(), "reason", reason)
+		} else {
+			a.logger.Info("Deleting prerelease", "component", a.component, "tag", release.GetTagName(), "reason", reason)
+			a.deleteRelease(ctx, release)
+		}
+
+		deletedTags = append(deletedTags, release.GetTagName())
+	}
+
+	return deletedTags
+}
+
+// deleteRelease deletes a release, and then its tag. GitHub doesn't delete the tag along with the release.
+func (a VersioningAction) deleteRelease(ctx context.Context, release *github.RepositoryRelease) {
+	requestCtx, cancel := a.requestContext(ctx)
+	_, err := a.client.Repositories.DeleteRelease(requestCtx, a.owner, a.repository, release.GetID())
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditDeleteRelease, Tag: release.GetTagName(), SHA: release.GetTargetCommitish()})
+	if release.GetDraft() {
+		// Drafts don't have a tag yet
+		return
+	}
+
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	_, err = a.client.Git.DeleteRef(requestCtx, a.owner, a.repository, fmt.Sprintf("tags/%s", release.GetTagName()))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// filterPrereleasesForComponent filters all the repository releases to only the published prereleases of the
+// component
+func (a VersioningAction) filterPrereleasesForComponent(releases []*github.RepositoryRelease) []*github.RepositoryRelease {
+	var matchingReleases []*github.RepositoryRelease
+	for _, release := range releases {
+		version := a.releaseVersion(release)
+		if release.GetPrerelease() && !release.GetDraft() && version != nil && version.Prerelease() != "" {
+			matchingReleases = append(matchingReleases, release)
+		}
+	}
+
+	return matchingReleases
+}
@@ -0,0 +1,47 @@
+package pkg
+
+import "time"
+
+// Clock is the source of the current time, so that it can be controlled in tests
+type Clock interface {
+	Now() time.Time
+	// After sends the time once the duration has passed, eg: between checks for a release's approval
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock tells the real time. It's the default clock.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// systemClock tells the time with the system clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// FrozenClock always tells the same time, so that runs which depend on the time, eg: cleaning up old prereleases,
+// are deterministic in tests and replays
+func FrozenClock(at time.Time) Clock {
+	return frozenClock{at: at}
+}
+
+type frozenClock struct {
+	at time.Time
+}
+
+func (c frozenClock) Now() time.Time {
+	return c.at
+}
+
+// After sends the time at once, as no time passes on a frozen clock, so that waits are instant
+func (c frozenClock) After(time.Duration) <-chan time.Time {
+	after := make(chan time.Time, 1)
+	after <- c.at
+	return after
+}
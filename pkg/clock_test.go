@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+)
+
+func TestFrozenClockDatesReleases(t *testing.T) {
+	server := newTestServer(t)
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+	frozen := time.Date(2024, time.February, 29, 23, 30, 0, 0, time.UTC)
+	a := newTestAction(t, server, "api", WithClock(FrozenClock(frozen)), WithTitleTemplate("{{.Label}} {{.Version}} ({{.Date}})"))
+
+	result := a.GenerateVersion(context.Background(), false)
+	if result.Release == nil {
+		t.Fatal("Expected a release")
+	}
+
+	releases := server.Releases()
+	if title := releases[0].GetName(); title != "api 1.0.0 (2024-02-29)" {
+		t.Errorf("Expected the release to be titled with the frozen clock's date, but got %q", title)
+	}
+}
+
+func TestFrozenClockFreezeWindows(t *testing.T) {
+	config := Config{Freezes: []FreezeWindow{{
+		Start:  time.Date(2024, time.December, 20, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+		Reason: "Holiday change freeze",
+	}}}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		frozen bool
+	}{
+		{name: "before the freeze", now: time.Date(2024, time.December, 19, 23, 59, 59, 0, time.UTC)},
+		{name: "when the freeze starts", now: time.Date(2024, time.December, 20, 0, 0, 0, 0, time.UTC), frozen: true},
+		{name: "during the freeze", now: time.Date(2024, time.December, 25, 12, 0, 0, 0, time.UTC), frozen: true},
+		{name: "when the freeze ends", now: time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+			a := newTestAction(t, server, "api", WithClock(FrozenClock(test.now))).WithConfig(config)
+
+			result := a.GenerateVersion(context.Background(), false)
+			if frozen := strings.HasPrefix(result.Frozen, "change freeze: Holiday change freeze"); frozen != test.frozen {
+				t.Errorf("Expected frozen to be %t at %s, but the result was frozen by %q", test.frozen, test.now, result.Frozen)
+			}
+
+			if released := len(server.Releases()) > 0; released == test.frozen {
+				t.Errorf("Expected a release only when not frozen, but released is %t", released)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// commentMarker identifies the action's comment on a pull request, so that the same comment is updated on every
+// push rather than adding a new one
+const commentMarker = "<!-- monorepo-versioning -->"
+
+// WithPullRequest previews the versions which merging a pull request into the branch would release. Commits are
+// listed from the revision, which for pull request events is the pull request's merge commit, so that commits from
+// forks are included.
+func (a VersioningAction) WithPullRequest(number int) VersioningAction {
+	a.pullRequest = number
+	return a
+}
+
+// CommentOnPullRequest posts the versions which merging the pull request would release as a comment on it, or
+// updates the existing comment. Results must be from a dry run, so that they include release previews. Returns
+// the comment's URL.
+func (a VersioningAction) CommentOnPullRequest(ctx context.Context, results []Result) string {
+	body := pullRequestCommentBody(results)
+
+	var existing *github.IssueComment
+	page := 1
+	for existing == nil {
+		requestCtx, cancel := a.requestContext(ctx)
+		comments, _, err := a.client.Issues.ListComments(requestCtx, a.owner, a.repository, a.pullRequest, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		if len(comments) == 0 {
+			break
+		}
+
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.GetBody(), commentMarker) {
+				existing = comment
+				break
+			}
+		}
+
+		page++
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	var comment *github.IssueComment
+	var err error
+	if existing != nil {
+		a.logger.Info("Updating pull request comment", "number", a.pullRequest, "comment", existing.GetID())
+		comment, _, err = a.client.Issues.EditComment(requestCtx, a.owner, a.repository, existing.GetID(), &github.IssueComment{Body: &body})
+	} else {
+		a.logger.Info("Commenting on pull request", "number", a.pullRequest)
+		comment, _, err = a.client.Issues.CreateComment(requestCtx, a.owner, a.repository, a.pullRequest, &github.IssueComment{Body: &body})
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return comment.GetHTMLURL()
+}
+
+// pullRequestCommentBody summarises the version of each component, followed by the release notes of each
+// component which would be released
+func pullRequestCommentBody(results []Result) string {
+	body := strings.Builder{}
+	body.WriteString(commentMarker + "\n")
+	body.WriteString("### :package: Versions released by merging this pull request\n\n")
+	body.WriteString(versionsTable(results))
+	body.WriteString(releaseNotesDetails(results))
+	return body.String()
+}
+
+// versionsTable summarises the current and next version of each component as a Markdown table
+func versionsTable(results []Result) string {
+	table := strings.Builder{}
+	table.WriteString("| Component | Current version | Next version | Bump |\n")
+	table.WriteString("| --------- | --------------- | ------------ | ---- |\n")
+	for _, result := range results {
+		previousVersion, version := "-", "-"
+		if result.PreviousVersion != nil {
+			previousVersion = result.PreviousVersion.String()
+		}
+
+		if result.Version != nil {
+			version = fmt.Sprintf("**%s**", result.Version.String())
+		}
+
+		table.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", result.Component, previousVersion, version, result.Bump))
+	}
+
+	return table.String()
+}
+
+// releaseNotesDetails are the release notes of each previewed release, collapsed so that they don't drown out
+// the summary
+func releaseNotesDetails(results []Result) string {
+	details := strings.Builder{}
+	for _, result := range results {
+		if result.Preview == nil {
+			continue
+		}
+
+		details.WriteString(fmt.Sprintf("\n<details>\n<summary>%s release notes</summary>\n\n%s\n</details>\n", result.Preview.Title, result.Preview.Notes))
+	}
+
+	return details.String()
+}
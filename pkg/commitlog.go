@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// CommitLogFormat is the git log format ParseCommitLog reads, which separates fields and commits with control
+// characters so that commit messages can contain anything. Add --name-only to the git log command so that
+// unscoped commits can be attributed by the files they changed, eg:
+//
+//	git log --name-only --format='%x1e%H%x1f%P%x1f%an%x1f%ae%x1f%cI%x1f%D%x1f%B%x1f'
+const CommitLogFormat = "%x1e%H%x1f%P%x1f%an%x1f%ae%x1f%cI%x1f%D%x1f%B%x1f"
+
+// commitLogFields is the number of fields of each commit in the log, including the changed files
+const commitLogFields = 8
+
+// CommitLog is a local git history, read with ParseCommitLog, ReadGitLog or ParseCommitHistory, which versions are
+// generated from instead of the GitHub API
+type CommitLog struct {
+	// Commits, newest first, as git log lists them
+	commits []*github.RepositoryCommit
+	// Paths of the files changed by each commit, keyed by commit SHA, if the log lists them
+	files map[string][]string
+	// SHAs of the commits tags point at, keyed by tag name
+	tags map[string]string
+}
+
+// ParseCommitLog reads a git log written with CommitLogFormat, eg: from standard input
+func ParseCommitLog(reader io.Reader) (CommitLog, error) {
+	contents, err := io.ReadAll(reader)
+	if err != nil {
+		return CommitLog{}, err
+	}
+
+	log := CommitLog{files: make(map[string][]string), tags: make(map[string]string)}
+	for _, entry := range strings.Split(string(contents), "\x1e") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, "\x1f")
+		if len(fields) != commitLogFields {
+			return CommitLog{}, fmt.Errorf("commit log entry %q isn't in the format %s", strings.SplitN(entry, "\n", 2)[0], CommitLogFormat)
+		}
+
+		sha, parents, authorName, authorEmail, committed, refs, message, files := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6], fields[7]
+		date, err := time.Parse(time.RFC3339, committed)
+		if err != nil {
+			return CommitLog{}, fmt.Errorf("commit %s has an invalid date: %w", sha, err)
+		}
+
+		commit := logCommit(sha, strings.Fields(parents), authorName, authorEmail, date, message)
+		// Without --name-only, the log has no files, so they can't be known
+		if strings.TrimSpace(files) != "" {
+			log.files[sha] = strings.Fields(files)
+		}
+
+		for _, ref := range strings.Split(refs, ",") {
+			if tag, ok := strings.CutPrefix(strings.TrimSpace(ref), "tag: "); ok {
+				log.tags[tag] = sha
+			}
+		}
+
+		log.commits = append(log.commits, commit)
+	}
+
+	if len(log.commits) == 0 {
+		return CommitLog{}, errors.New("commit log has no commits")
+	}
+
+	return log, nil
+}
+
+// logCommit is a commit of a local history, as the GitHub API would return it
+func logCommit(sha string, parents []string, authorName string, authorEmail string, committed time.Time, message string) *github.RepositoryCommit {
+	commit := &github.RepositoryCommit{
+		SHA: github.String(sha),
+		Commit: &github.Commit{
+			SHA:       github.String(sha),
+			Message:   github.String(strings.TrimRight(message, "\n")),
+			Author:    &github.CommitAuthor{Name: github.String(authorName), Email: github.String(authorEmail), Date: &github.Timestamp{Time: committed}},
+			Committer: &github.CommitAuthor{Date: &github.Timestamp{Time: committed}},
+		},
+	}
+
+	for _, parent := range parents {
+		commit.Parents = append(commit.Parents, &github.Commit{SHA: github.String(parent)})
+	}
+
+	return commit
+}
+
+// Head is the SHA of the newest commit in the log
+func (l CommitLog) Head() string {
+	return l.commits[0].GetSHA()
+}
+
+// WithCommitLog generates versions from a local git history instead of the GitHub API, so that they can be
+// previewed without a token, eg: in pre-merge checks. Tags in the log are the component's releases, and the
+// revision must be in the log. It must be the last option set, as the history is read as of the action's branch
+// and revision. Only dry runs can be made: every GitHub API call fails.
+func (a VersioningAction) WithCommitLog(log CommitLog) VersioningAction {
+	a.client = github.NewClient(&http.Client{Transport: offlineTransport{}})
+
+	history := newRepositoryHistory()
+	changeTimes := make(map[string]time.Time)
+	for _, commit := range log.commits {
+		changeTimes[commit.GetSHA()] = commit.GetCommit().GetCommitter().GetDate().Time
+	}
+
+	for sha, files := range log.files {
+		history.commitFiles[sha] = files
+	}
+
+	history.releasesListed = true
+	history.tagsListed = true
+	for tagName, sha := range log.tags {
+		history.tags = append(history.tags, &github.RepositoryTag{Name: github.String(tagName), Commit: &github.Commit{SHA: github.String(sha)}})
+		date := changeTimes[sha]
+		history.tagCommits["refs/tags/"+tagName] = changePoint{sha: sha, time: date}
+		history.releases = append(history.releases, &github.RepositoryRelease{
+			TagName:         github.String(tagName),
+			Name:            github.String(tagName),
+			TargetCommitish: github.String(sha),
+			PublishedAt:     &github.Timestamp{Time: date},
+		})
+	}
+
+	if _, ok := changeTimes[a.revision]; !ok {
+		panic(fmt.Sprintf("Revision %s isn't in the commit log", a.revision))
+	}
+
+	// The range is the revision's whole history, as git log lists it
+	logged := &commitRange{commits: log.commits}
+	reachable := logged.reachable(a.revision)
+	history.commits = &commitRange{head: a.revision, since: time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	for _, commit := range log.commits {
+		if reachable[commit.GetSHA()] {
+			history.commits.commits = append(history.commits.commits, commit)
+		}
+	}
+
+	a.history = history
+	a.commitSource = log
+	return a
+}
+
+// offlineTransport fails every request, so that anything needing the GitHub API fails clearly when versions are
+// generated from a commit log
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("versions generated from a commit log can't call the GitHub API, but %s %s was needed", request.Method, request.URL.Path)
+}
@@ -0,0 +1,330 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-github/v50/github"
+)
+
+// CommitSource is where the commits versions are generated from are read. The GitHub API is used unless another
+// source is given with WithCommitSource or WithCommitLog, eg: a local git history for offline runs and tests, or
+// another host mirroring the repository.
+type CommitSource interface {
+	// Commits reachable from head made on or after since, newest first
+	Commits(ctx context.Context, head string, since time.Time) ([]*github.RepositoryCommit, error)
+	// Files changed by a commit, including the previous paths of the files it moved
+	Files(ctx context.Context, sha string) ([]string, error)
+}
+
+// WithCommitSource reads commits from another source than the GitHub API. Releases and tags are still read from
+// GitHub, so the source must have the same commits; to generate versions without GitHub, use WithCommitLog instead.
+func (a VersioningAction) WithCommitSource(source CommitSource) VersioningAction {
+	a.commitSource = source
+	return a
+}
+
+// commits the action's versions are generated from
+func (a VersioningAction) commits() CommitSource {
+	if a.commitSource != nil {
+		return a.commitSource
+	}
+
+	return githubCommits{a}
+}
+
+// githubCommits reads commits from the GitHub API, page by page, stopping at the action's max-commits limit
+type githubCommits struct {
+	a VersioningAction
+}
+
+func (s githubCommits) Commits(ctx context.Context, head string, since time.Time) ([]*github.RepositoryCommit, error) {
+	var existingCommits []*github.RepositoryCommit
+	for page := 1; ; page++ {
+		requestCtx, cancel := s.a.requestContext(ctx)
+		commits, _, err := s.a.client.Repositories.ListCommits(requestCtx, s.a.owner, s.a.repository, &github.CommitsListOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+			Since: since,
+			SHA:   head,
+		})
+		cancel()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(commits) == 0 {
+			return existingCommits, nil
+		}
+
+		existingCommits = append(existingCommits, commits...)
+		if s.a.maxCommits > 0 && len(existingCommits) > s.a.maxCommits {
+			// Stop listing, as the range is too big to be used anyway
+			return existingCommits, nil
+		}
+	}
+}
+
+func (s githubCommits) Files(ctx context.Context, sha string) ([]string, error) {
+	requestCtx, cancel := s.a.requestContext(ctx)
+	defer cancel()
+	commit, _, err := s.a.client.Repositories.GetCommit(requestCtx, s.a.owner, s.a.repository, sha, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range commit.Files {
+		files = append(files, file.GetFilename())
+		if file.GetPreviousFilename() != "" {
+			// Moving a file out of a component changes the component too
+			files = append(files, file.GetPreviousFilename())
+		}
+	}
+
+	return files, nil
+}
+
+// Commits in the log reachable from head made on or after since, newest first
+func (l CommitLog) Commits(_ context.Context, head string, since time.Time) ([]*github.RepositoryCommit, error) {
+	reachable := (&commitRange{commits: l.commits}).reachable(head)
+	if len(reachable) == 0 {
+		return nil, fmt.Errorf("commit %s isn't in the commit log", head)
+	}
+
+	var commits []*github.RepositoryCommit
+	for _, commit := range l.commits {
+		if reachable[commit.GetSHA()] && !commit.GetCommit().GetCommitter().GetDate().Before(since) {
+			commits = append(commits, commit)
+		}
+	}
+
+	return commits, nil
+}
+
+// Files changed by a commit in the log, which are only known if the log lists them
+func (l CommitLog) Files(_ context.Context, sha string) ([]string, error) {
+	files, ok := l.files[sha]
+	if !ok {
+		return nil, fmt.Errorf("the commit log doesn't list the files changed by commit %s", sha)
+	}
+
+	return files, nil
+}
+
+// ReadGitLog reads the history of the git repository checked out in a directory, from its current HEAD, as a commit
+// log, the same as `git log --name-only --no-renames` lists it. It's read with go-git, so git needn't be installed,
+// and the user's git config, eg: mailmaps, can't change what's read. Tags must have been fetched for the components'
+// releases to be found, and the whole history for commits since them, eg: with `fetch-depth: 0` with
+// actions/checkout, so shallow clones aren't read, as commits since a release could be missing.
+func ReadGitLog(ctx context.Context, dir string) (CommitLog, error) {
+	repository, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return CommitLog{}, fmt.Errorf("could not read the git log of %s: %w", dir, err)
+	}
+
+	shallow, err := repository.Storer.Shallow()
+	if err != nil {
+		return CommitLog{}, fmt.Errorf("could not read the git log of %s: %w", dir, err)
+	}
+
+	if len(shallow) > 0 {
+		return CommitLog{}, fmt.Errorf("%s is a shallow clone, so its history can't be versioned: fetch the whole history, eg: with `git fetch --unshallow --tags`", dir)
+	}
+
+	head, err := repository.Head()
+	if err != nil {
+		return CommitLog{}, fmt.Errorf("could not read the git log of %s: %w", dir, err)
+	}
+
+	commits, err := repository.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return CommitLog{}, fmt.Errorf("could not read the git log of %s: %w", dir, err)
+	}
+
+	log := CommitLog{files: make(map[string][]string), tags: make(map[string]string)}
+	listed := make(map[string]bool)
+	err = commits.ForEach(func(commit *object.Commit) error {
+		files, err := changedFiles(ctx, commit)
+		if err != nil {
+			return err
+		}
+
+		sha := commit.Hash.String()
+		if len(files) > 0 {
+			log.files[sha] = files
+		}
+
+		var parents []string
+		for _, parent := range commit.ParentHashes {
+			parents = append(parents, parent.String())
+		}
+
+		log.commits = append(log.commits, logCommit(sha, parents, commit.Author.Name, commit.Author.Email, commit.Committer.When, commit.Message))
+		listed[sha] = true
+		return nil
+	})
+
+	if err != nil {
+		return CommitLog{}, fmt.Errorf("could not read the git log of %s: %w", dir, err)
+	}
+
+	tags, err := repository.Tags()
+	if err != nil {
+		return CommitLog{}, fmt.Errorf("could not read the tags of %s: %w", dir, err)
+	}
+
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		// Annotated tags are of the commits they point at, and tags of trees and blobs aren't releases
+		sha := ref.Hash().String()
+		if tag, err := repository.TagObject(ref.Hash()); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+
+			sha = commit.Hash.String()
+		}
+
+		// Like git log's decorations, only tags of the listed commits are read
+		if listed[sha] {
+			log.tags[ref.Name().Short()] = sha
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return CommitLog{}, fmt.Errorf("could not read the tags of %s: %w", dir, err)
+	}
+
+	return log, nil
+}
+
+// changedFiles by a commit, as `git log --name-only --no-renames` lists them: every file of a root commit, none of
+// a merge commit, and both paths of a moved file, as moving a file out of a component changes the component too
+func changedFiles(ctx context.Context, commit *object.Commit) ([]string, error) {
+	if commit.NumParents() > 1 {
+		return nil, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	parentTree := &object.Tree{}
+	if commit.NumParents() == 1 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without rename detection, a moved file is deleted from one path and added at the other
+	changes, err := object.DiffTreeWithOptions(ctx, parentTree, tree, &object.DiffTreeOptions{DetectRenames: false})
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, change := range changes {
+		for _, path := range []string{change.From.Name, change.To.Name} {
+			if path != "" && !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+		}
+	}
+
+	slices.Sort(files)
+	return files, nil
+}
+
+// CommitHistory is the JSON format ParseCommitHistory reads, eg: to write the history a test versions by hand
+type CommitHistory struct {
+	// Commits, newest first
+	Commits []HistoryCommit `json:"commits"`
+	// SHAs of the commits tags point at, keyed by tag name
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// HistoryCommit is a single commit of a commit history
+type HistoryCommit struct {
+	SHA     string   `json:"sha"`
+	Parents []string `json:"parents,omitempty"`
+	Author  string   `json:"author,omitempty"`
+	Email   string   `json:"email,omitempty"`
+	// Login of the author's GitHub account, if they have one, eg: to credit them or recognise dependency bots
+	Login   string    `json:"login,omitempty"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+	// Files changed by the commit, which are needed to attribute commits without a scope
+	Files []string `json:"files,omitempty"`
+}
+
+// ParseCommitHistory reads a commit log from JSON in the CommitHistory format
+func ParseCommitHistory(reader io.Reader) (CommitLog, error) {
+	var history CommitHistory
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&history); err != nil {
+		return CommitLog{}, fmt.Errorf("commit history isn't valid JSON: %w", err)
+	}
+
+	log := CommitLog{files: make(map[string][]string), tags: make(map[string]string)}
+	for _, historyCommit := range history.Commits {
+		if historyCommit.SHA == "" || historyCommit.Date.IsZero() {
+			return CommitLog{}, fmt.Errorf("commit %q in the commit history needs a sha and a date", historyCommit.SHA)
+		}
+
+		date := &github.Timestamp{Time: historyCommit.Date}
+		commit := &github.RepositoryCommit{
+			SHA: github.String(historyCommit.SHA),
+			Commit: &github.Commit{
+				SHA:       github.String(historyCommit.SHA),
+				Message:   github.String(historyCommit.Message),
+				Author:    &github.CommitAuthor{Name: github.String(historyCommit.Author), Email: github.String(historyCommit.Email), Date: date},
+				Committer: &github.CommitAuthor{Date: date},
+			},
+		}
+
+		if historyCommit.Login != "" {
+			commit.Author = &github.User{Login: github.String(historyCommit.Login)}
+		}
+
+		for _, parent := range historyCommit.Parents {
+			commit.Parents = append(commit.Parents, &github.Commit{SHA: github.String(parent)})
+		}
+
+		if historyCommit.Files != nil {
+			log.files[historyCommit.SHA] = historyCommit.Files
+		}
+
+		log.commits = append(log.commits, commit)
+	}
+
+	for tag, sha := range history.Tags {
+		log.tags[tag] = sha
+	}
+
+	if len(log.commits) == 0 {
+		return CommitLog{}, errors.New("commit history has no commits")
+	}
+
+	return log, nil
+}
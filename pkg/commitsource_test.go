@@ -0,0 +1,197 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// gitRepository is a git repository made in a test, whose commits are dated a minute apart from 2024-01-01
+type gitRepository struct {
+	t       *testing.T
+	dir     string
+	commits int
+}
+
+// newGitRepository with config which would change git log's output, which ReadGitLog mustn't read
+func newGitRepository(t *testing.T) *gitRepository {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	// The user's config mustn't be read either
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	repository := &gitRepository{t: t, dir: t.TempDir()}
+	repository.git("init", "--initial-branch=main")
+	repository.git("config", "log.showSignature", "true")
+	repository.git("config", "log.excludeDecoration", "refs/tags/")
+	repository.git("config", "log.mailmap", "true")
+	repository.git("config", "core.quotepath", "true")
+	repository.git("config", "diff.renames", "true")
+	repository.write(".mailmap", "Someone Else <someone@example.com> Mona <mona@example.com>\n")
+	return repository
+}
+
+func (r *gitRepository) git(arguments ...string) string {
+	r.t.Helper()
+	command := exec.Command("git", arguments...)
+	command.Dir = r.dir
+	date := time.Date(2024, time.January, 1, 0, r.commits, 0, 0, time.UTC).Format(time.RFC3339)
+	command.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Mona", "GIT_AUTHOR_EMAIL=mona@example.com", "GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME=Mona", "GIT_COMMITTER_EMAIL=mona@example.com", "GIT_COMMITTER_DATE="+date,
+	)
+	output, err := command.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s failed: %v: %s", strings.Join(arguments, " "), err, output)
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+func (r *gitRepository) write(path string, contents string) {
+	r.t.Helper()
+	path = filepath.Join(r.dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *gitRepository) move(from string, to string) {
+	r.t.Helper()
+	if err := os.MkdirAll(filepath.Join(r.dir, filepath.Dir(to)), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+
+	r.git("mv", from, to)
+}
+
+// commit everything changed in the working tree, returning the commit's SHA
+func (r *gitRepository) commit(message string) string {
+	r.t.Helper()
+	r.git("add", "--all")
+	r.git("commit", "--quiet", "--message", message)
+	r.commits++
+	return r.git("rev-parse", "HEAD")
+}
+
+func TestReadGitLog(t *testing.T) {
+	repository := newGitRepository(t)
+	repository.write("api/main.go", "package main\n")
+	added := repository.commit("feat(api): add users")
+	repository.git("tag", "api-1.0.0")
+	repository.write("web/café.html", "<h1>Café</h1>\n")
+	styled := repository.commit("fix(web): style the café\n\nWith a longer description")
+	repository.git("tag", "--annotate", "--message", "web 1.0.0", "web-1.0.0")
+	repository.move("api/main.go", "shared/main.go")
+	moved := repository.commit("fix: share the main package")
+
+	// The log is read without running git
+	t.Setenv("PATH", "")
+	log, err := ReadGitLog(context.Background(), repository.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log.Head() != moved {
+		t.Errorf("Expected the head of the log to be %s, but it's %s", moved, log.Head())
+	}
+
+	commits, err := log.Commits(context.Background(), moved, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shas := commitSHAs(commits); !slices.Equal(shas, []string{moved, styled, added}) {
+		t.Errorf("Expected the commits %v, but got %v", []string{moved, styled, added}, shas)
+	}
+
+	styledCommit := commits[1]
+	if message := styledCommit.GetCommit().GetMessage(); message != "fix(web): style the café\n\nWith a longer description" {
+		t.Errorf("Expected the message to be read as written, but got %q", message)
+	}
+
+	// The mailmap isn't used, so the author is as they were recorded
+	if author := styledCommit.GetCommit().GetAuthor(); author.GetName() != "Mona" || author.GetEmail() != "mona@example.com" {
+		t.Errorf("Expected the commit to be authored by Mona <mona@example.com>, but it was authored by %s <%s>", author.GetName(), author.GetEmail())
+	}
+
+	if date := styledCommit.GetCommit().GetCommitter().GetDate().Time; !date.Equal(time.Date(2024, time.January, 1, 0, 1, 0, 0, time.UTC)) {
+		t.Errorf("Expected the commit to be dated 2024-01-01T00:01:00Z, but it's dated %s", date)
+	}
+
+	expectedFiles := map[string][]string{
+		added:  {".mailmap", "api/main.go"},
+		styled: {"web/café.html"},
+		// Both paths of a moved file are listed, as moving a file out of a component changes the component too
+		moved: {"api/main.go", "shared/main.go"},
+	}
+
+	for sha, expected := range expectedFiles {
+		files, err := log.Files(context.Background(), sha)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(files, expected) {
+			t.Errorf("Expected commit %s to change %v, but it changed %v", sha, expected, files)
+		}
+	}
+
+	// Annotated tags are of the commit they point at
+	if tags := fmt.Sprint(log.tags); tags != fmt.Sprintf("map[api-1.0.0:%s web-1.0.0:%s]", added, styled) {
+		t.Errorf("Expected the tags api-1.0.0 of %s and web-1.0.0 of %s, but got %s", added, styled, tags)
+	}
+
+	a, err := New(
+		WithRepository("octocat/monorepo"),
+		// Replaced by WithCommitLog with a client which fails every request
+		WithClient(github.NewClient(nil)),
+		WithBranch("main", "main"),
+		WithRevision(log.Head()),
+		WithComponent("api", ""),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a = a.WithLogger(slog.New(slog.NewTextHandler(testLogWriter{t}, nil))).WithConfig(Config{
+		Components:      map[string]ComponentConfig{"api": {Path: "api"}},
+		UnscopedCommits: UnscopedCommitsConfig{Policy: UnscopedPaths},
+	})
+	result := a.WithCommitLog(log).GenerateVersion(context.Background(), true)
+	if result.Version == nil || result.Version.String() != "1.0.1" {
+		// The unscoped fix is attributed to api by the path it moved the file from
+		t.Errorf("Expected api to be versioned 1.0.1 as of the log, but got %v", result.Version)
+	}
+}
+
+func TestReadGitLogOfAShallowClone(t *testing.T) {
+	repository := newGitRepository(t)
+	repository.write("api/main.go", "package main\n")
+	repository.commit("feat(api): add users")
+	repository.write("api/main.go", "package main\n\n// users\n")
+	repository.commit("fix(api): validate users")
+	clone := t.TempDir()
+	repository.git("clone", "--quiet", "--depth=1", "file://"+repository.dir, clone)
+
+	if _, err := ReadGitLog(context.Background(), clone); err == nil || !strings.Contains(err.Error(), "shallow clone") {
+		t.Errorf("Expected reading the log of a shallow clone to fail, but got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// Release fields which older GitHub Enterprise Server versions reject, so are left out of requests to them
+const (
+	// FeatureMakeLatest is make_latest, which marks a release as the repository's latest
+	FeatureMakeLatest = "make-latest"
+	// FeatureGeneratedNotes is generate_release_notes, which the action always sets to false
+	FeatureGeneratedNotes = "generated-notes"
+	// FeatureReleaseDiscussions is discussion_category_name, which creates a discussion for a release
+	FeatureReleaseDiscussions = "release-discussions"
+)
+
+// featureMinimumVersions are the oldest GitHub Enterprise Server versions which support each feature
+var featureMinimumVersions = map[string]*semver.Version{
+	FeatureMakeLatest:         semver.MustParse("3.9.0"),
+	FeatureGeneratedNotes:     semver.MustParse("3.4.0"),
+	FeatureReleaseDiscussions: semver.MustParse("3.6.0"),
+}
+
+// Features which can be disabled with WithDisabledFeatures
+func Features() []string {
+	return []string{FeatureMakeLatest, FeatureGeneratedNotes, FeatureReleaseDiscussions}
+}
+
+// WithDisabledFeatures leaves the features' fields out of every request, eg: for a GitHub Enterprise Server whose
+// version is hidden or which backported a feature differently. Features are otherwise only left out if the server
+// reports a version older than the one which added them.
+func (a VersioningAction) WithDisabledFeatures(features []string) VersioningAction {
+	a.disabledFeatures = features
+	return a
+}
+
+// supports checks whether the GitHub server supports a feature. GitHub Enterprise Server reports its version in
+// the meta endpoint, which is requested once per run, while GitHub.com supports every feature.
+func (a VersioningAction) supports(ctx context.Context, feature string) bool {
+	for _, disabled := range a.disabledFeatures {
+		if disabled == feature {
+			return false
+		}
+	}
+
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	if !a.history.serverVersionChecked {
+		a.history.serverVersion = a.getServerVersion(ctx)
+		a.history.serverVersionChecked = true
+	}
+
+	return a.history.serverVersion == nil || !a.history.serverVersion.LessThan(featureMinimumVersions[feature])
+}
+
+// getServerVersion is the version of GitHub Enterprise Server, or nil for GitHub.com or if the version is unknown
+func (a VersioningAction) getServerVersion(ctx context.Context) *semver.Version {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	request, err := a.client.NewRequest(http.MethodGet, "meta", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	var meta struct {
+		InstalledVersion string `json:"installed_version"`
+	}
+
+	if _, err := a.client.Do(requestCtx, request, &meta); err != nil {
+		a.logger.Warn("Couldn't check the GitHub server's version, so assuming it supports every feature", "error", err)
+		return nil
+	}
+
+	if meta.InstalledVersion == "" {
+		return nil
+	}
+
+	version, err := semver.NewVersion(meta.InstalledVersion)
+	if err != nil {
+		a.logger.Warn("GitHub Enterprise Server reported an invalid version, so assuming it supports every feature", "version", meta.InstalledVersion)
+		return nil
+	}
+
+	a.logger.Info("Using GitHub Enterprise Server", "version", version.String())
+	return version
+}
+
+// withoutUnsupportedFields leaves the fields of features the server doesn't support out of a release request,
+// warning about the behaviour which is lost
+func (a VersioningAction) withoutUnsupportedFields(ctx context.Context, release *github.RepositoryRelease) *github.RepositoryRelease {
+	if release.MakeLatest != nil && !a.supports(ctx, FeatureMakeLatest) {
+		a.logger.Warn("The GitHub server doesn't support make_latest, so the latest release is chosen by GitHub", "component", a.component, "makeLatest", release.GetMakeLatest())
+		release.MakeLatest = nil
+	}
+
+	if release.GenerateReleaseNotes != nil && !a.supports(ctx, FeatureGeneratedNotes) {
+		// The notes are never generated, which is the default anyway
+		release.GenerateReleaseNotes = nil
+	}
+
+	if release.DiscussionCategoryName != nil && !a.supports(ctx, FeatureReleaseDiscussions) {
+		a.logger.Warn("The GitHub server doesn't support release discussions, so no discussion is created", "component", a.component, "category", release.GetDiscussionCategoryName())
+		release.DiscussionCategoryName = nil
+	}
+
+	return release
+}
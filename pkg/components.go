@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// componentTagPattern matches the tag of a version of any component with the tag template, eg: "api-1.5.0" or
+// "api-1.6.0-beta.1". The component name is matched lazily, so that names containing hyphens followed by letters
+// are kept whole.
+func (a VersioningAction) componentTagPattern() *regexp.Regexp {
+	expression := regexp.QuoteMeta(strings.ToLower(a.tagTemplate))
+	expression = strings.Replace(expression, regexp.QuoteMeta("{component}"), `(.+?)`, 1)
+	expression = strings.Replace(expression, regexp.QuoteMeta("{version}"), `(\d+\.\d+\.\d+(?:-.+)?)`, 1)
+	return regexp.MustCompile(fmt.Sprintf("^%s$", expression))
+}
+
+// releaseComponent names the component a release is of, from its metadata, or else from its tag
+func (a VersioningAction) releaseComponent(release *github.RepositoryRelease) (string, bool) {
+	if metadata, ok := ParseReleaseMetadata(release.GetBody()); ok {
+		return a.configuredName(metadata.Component), true
+	}
+
+	if !strings.Contains(a.tagTemplate, "{component}") {
+		// Tags don't name their component, so every release is of the action's component
+		return a.component, a.releaseVersion(release) != nil
+	}
+
+	if matches := a.componentTagPattern().FindStringSubmatch(strings.ToLower(release.GetTagName())); matches != nil {
+		return a.configuredName(matches[1]), true
+	}
+
+	return "", false
+}
+
+// configuredName of a component named in a release's metadata or tag, which is the name it's configured with if it
+// is configured, as tags may have been normalised, eg: "services-payment-api" for "services/Payment-API". Names
+// of components which aren't configured are lowercased, so that they're listed once whichever case they're in.
+func (a VersioningAction) configuredName(name string) string {
+	for _, configured := range a.config.ComponentNames() {
+		if strings.EqualFold(configured, name) || strings.EqualFold(a.ForComponent(configured, "").tagComponent(), name) {
+			return configured
+		}
+	}
+
+	return strings.ToLower(name)
+}
+
+// ListComponents infers which components exist from the metadata or tags of the repository's releases, and finds
+// the newest versions of each. Components are sorted by name.
+func (a VersioningAction) ListComponents(ctx context.Context) []CurrentVersions {
+	names := make(map[string]bool)
+	for _, release := range a.getAllReleases(ctx) {
+		if name, ok := a.releaseComponent(release); ok {
+			names[name] = true
+		}
+	}
+
+	if !strings.Contains(a.tagTemplate, "{component}") {
+		// Tags don't name their component, so the action's component is the only one
+		names[a.component] = true
+	}
+
+	var components []CurrentVersions
+	for name := range names {
+		components = append(components, a.ForComponent(name, "").CurrentVersions(ctx))
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].Component < components[j].Component
+	})
+
+	a.logger.Info("Found components", "count", len(components))
+	return components
+}
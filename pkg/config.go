@@ -0,0 +1,325 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the path of the configuration file, relative to the repository root
+const DefaultConfigFile = ".monorepo-versioning.yaml"
+
+// Config is the optional configuration file for behaviour which is too complex to configure with inputs
+type Config struct {
+	// Channels map branches to release channels. The first channel matching the branch is used.
+	Channels []Channel `yaml:"channels,omitempty"`
+	// Components configure each component, keyed by component name
+	Components map[string]ComponentConfig `yaml:"components,omitempty"`
+	// Discover components from the manifests of these ecosystems: go, npm, cargo, or python
+	Discover []string `yaml:"discover,omitempty"`
+	// UnscopedCommits configures which components commits without a scope affect
+	UnscopedCommits UnscopedCommitsConfig `yaml:"unscoped-commits,omitempty"`
+	// IgnorePaths are glob patterns of files in every component's path which unscoped commits are not attributed
+	// by, eg: "**/*.md"
+	IgnorePaths []string `yaml:"ignore-paths,omitempty"`
+	// MergeCommits configures how merge commits are handled: all, first-parent, or expand
+	MergeCommits string `yaml:"merge-commits,omitempty"`
+	// ReleasePlease reads components and their current versions from release-please's configuration
+	ReleasePlease *ReleasePleaseConfig `yaml:"release-please,omitempty"`
+	// DependencyUpdates configures how commits from dependency update bots are recognised and released
+	DependencyUpdates DependencyUpdatesConfig `yaml:"dependency-updates,omitempty"`
+	// VersionsManifest is the path of a JSON file on the default branch recording each component's current
+	// version, which is updated after each release, eg: ".versions.json"
+	VersionsManifest string `yaml:"versions-manifest,omitempty"`
+	// ContributorsFile is the path of a YAML file mapping git author emails to the contributors credited in
+	// release notes
+	ContributorsFile string `yaml:"contributors-file,omitempty"`
+	// Hooks are executables run at points of each component's release, eg: to sign or publish artifacts
+	Hooks HooksConfig `yaml:"hooks,omitempty"`
+	// ScopeMatching configures how commit scopes are compared with components
+	ScopeMatching ScopeMatchingConfig `yaml:"scope-matching,omitempty"`
+	// Naming configures how component names are normalised in tags
+	Naming ComponentNamesConfig `yaml:"component-names,omitempty"`
+	// Freezes are change freezes, during which the components they freeze are versioned but not released
+	Freezes []FreezeWindow `yaml:"freezes,omitempty"`
+	// Groups of components which are always released together, keyed by group name
+	Groups map[string][]string `yaml:"groups,omitempty"`
+	// Check configures the rules the check operation enforces on pull requests
+	Check CheckConfig `yaml:"check,omitempty"`
+	// Contributors read from the contributors file, keyed by lowercase email
+	contributors map[string]Contributor
+}
+
+// ComponentConfig configures a single component
+type ComponentConfig struct {
+	// Path of the component's directory, relative to the repository root
+	Path string `yaml:"path,omitempty"`
+	// Type of the component, which follows that ecosystem's release conventions, eg: ComponentHelm
+	Type string `yaml:"type,omitempty"`
+	// ChartRegistry is the OCI registry a Helm chart is pushed to after each release, eg: "oci://ghcr.io/owner/charts"
+	ChartRegistry string `yaml:"chart-registry,omitempty"`
+	// VersionFiles are updated with each new version of the component
+	VersionFiles []VersionFile `yaml:"version-files,omitempty"`
+	// Package is the name other components use to depend on the component, eg: a Go module path or npm package
+	Package string `yaml:"package,omitempty"`
+	// Dependents are the components whose manifests pin the component's version
+	Dependents []string `yaml:"dependents,omitempty"`
+	// Constraints keep parts of the component's version equal to other components', eg: its major version
+	Constraints []Constraint `yaml:"constraints,omitempty"`
+	// Notifications announcing releases of the component in chat services
+	Notifications []NotificationConfig `yaml:"notifications,omitempty"`
+	// Webhook notifying other services about releases of the component
+	Webhook *WebhookConfig `yaml:"webhook,omitempty"`
+	// Scopes are glob patterns of extra commit scopes which refer to the component, eg: "api-*"
+	Scopes []string `yaml:"scopes,omitempty"`
+	// ScopeRegex matches extra commit scopes which refer to the component, eg: "^api/.*$"
+	ScopeRegex string `yaml:"scope-regex,omitempty"`
+	// IgnorePaths are glob patterns of files in the component's path which unscoped commits are not attributed by,
+	// in addition to the global patterns
+	IgnorePaths []string `yaml:"ignore-paths,omitempty"`
+	// BaselineVersion is the component's current version, released by another tool, which is used until the
+	// action first releases the component
+	BaselineVersion string `yaml:"baseline-version,omitempty"`
+	// BaselineTag is the tag of the baseline version, which commits are considered since
+	BaselineTag string `yaml:"baseline-tag,omitempty"`
+	// Frozen components are versioned but not released, until they're unfrozen
+	Frozen bool `yaml:"frozen,omitempty"`
+	// Approval required before each release of the component, eg: for production-critical components
+	Approval *ApprovalConfig `yaml:"approval,omitempty"`
+}
+
+// Component gets the configuration of a component. Component names are matched case-insensitively, preferring
+// the component named in the same case, so that components differing only by case can be configured when scope
+// matching is case-sensitive.
+func (c Config) Component(component string) ComponentConfig {
+	if config, ok := c.Components[component]; ok {
+		return config
+	}
+
+	for name, config := range c.Components {
+		if strings.EqualFold(name, component) {
+			return config
+		}
+	}
+
+	return ComponentConfig{}
+}
+
+// LoadConfig reads the configuration file at path. If the file doesn't exist, an empty configuration is returned.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+
+	if err != nil {
+		return config, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	// Catch typos in the configuration rather than silently ignoring them
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	config, err = config.withReleasePlease()
+	if err != nil {
+		return config, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	config, err = config.withContributors()
+	if err != nil {
+		return config, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// validate the configuration, so that mistakes are reported before any work is done
+func (c Config) validate() error {
+	for i, channel := range c.Channels {
+		if channel.Branch == "" {
+			return fmt.Errorf("channel %d has no branch pattern", i+1)
+		}
+
+		if _, err := path.Match(channel.Branch, ""); err != nil {
+			return fmt.Errorf("channel %s has invalid branch pattern %q: %w", channel.Name, channel.Branch, err)
+		}
+
+		if channel.Name == "" {
+			return fmt.Errorf("channel for branches %s has no name", channel.Branch)
+		}
+
+		if channel.MaxBump != "" && !channel.MaxBump.Greater(BumpNone) {
+			return fmt.Errorf("channel %s has invalid max-bump %q, expected one of: %s, %s, %s", channel.Name, channel.MaxBump, BumpMajor, BumpMinor, BumpPatch)
+		}
+	}
+
+	if bump := c.DependencyUpdates.Bump; bump != "" && bump != BumpNone && bump != BumpPatch {
+		return fmt.Errorf("invalid dependency-updates bump %q, expected one of: %s, %s", bump, BumpNone, BumpPatch)
+	}
+
+	for _, pattern := range c.DependencyUpdates.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("dependency-updates has invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	if path.IsAbs(c.VersionsManifest) {
+		return fmt.Errorf("versions-manifest %q must be relative to the repository root", c.VersionsManifest)
+	}
+
+	switch c.MergeCommits {
+	case "", MergeCommitsAll, MergeCommitsFirstParent, MergeCommitsExpand:
+	default:
+		return fmt.Errorf("invalid merge-commits %q, expected one of: %s, %s, %s", c.MergeCommits, MergeCommitsAll, MergeCommitsFirstParent, MergeCommitsExpand)
+	}
+
+	switch c.UnscopedCommits.Policy {
+	case "", UnscopedIgnore, UnscopedPaths:
+	case UnscopedRoot:
+		if c.UnscopedCommits.Component == "" {
+			return errors.New("unscoped-commits policy root needs a component")
+		}
+	default:
+		return fmt.Errorf("invalid unscoped-commits policy %q, expected one of: %s, %s, %s", c.UnscopedCommits.Policy, UnscopedIgnore, UnscopedRoot, UnscopedPaths)
+	}
+
+	if err := validateGlobs("ignore-paths", c.IgnorePaths); err != nil {
+		return err
+	}
+
+	if err := c.Hooks.validate(); err != nil {
+		return err
+	}
+
+	for _, ecosystem := range c.Discover {
+		if _, ok := discoveryManifests[ecosystem]; !ok {
+			return fmt.Errorf("invalid discover ecosystem %q, expected one of: go, npm, cargo, python", ecosystem)
+		}
+	}
+
+	if err := c.Naming.validate(); err != nil {
+		return err
+	}
+
+	if err := c.ScopeMatching.validate(); err != nil {
+		return err
+	}
+
+	for _, window := range c.Freezes {
+		if err := window.validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := validateGroups(c.Groups); err != nil {
+		return err
+	}
+
+	for name, component := range c.Components {
+		if len(component.Dependents) > 0 && component.Package == "" {
+			return fmt.Errorf("component %s has dependents, so needs a package name", name)
+		}
+
+		if err := validateGlobs("ignore-paths", component.IgnorePaths); err != nil {
+			return fmt.Errorf("component %s: %w", name, err)
+		}
+
+		for _, constraint := range component.Constraints {
+			if err := constraint.validate(name); err != nil {
+				return fmt.Errorf("component %s: %w", name, err)
+			}
+		}
+
+		if _, err := semver.NewVersion(component.BaselineVersion); component.BaselineVersion != "" && err != nil {
+			return fmt.Errorf("component %s has invalid baseline-version %q: %w", name, component.BaselineVersion, err)
+		}
+
+		if component.BaselineTag != "" && component.BaselineVersion == "" {
+			return fmt.Errorf("component %s has a baseline-tag, but no baseline-version", name)
+		}
+
+		for _, pattern := range component.Scopes {
+			if _, err := compileGlob(pattern); err != nil {
+				return fmt.Errorf("component %s has invalid scope pattern %q: %w", name, pattern, err)
+			}
+		}
+
+		if _, err := regexp.Compile(component.ScopeRegex); err != nil {
+			return fmt.Errorf("component %s has invalid scope-regex %q: %w", name, component.ScopeRegex, err)
+		}
+
+		for _, notification := range component.Notifications {
+			if _, ok := notificationFormats[notification.Type]; !ok {
+				return fmt.Errorf("component %s has a notification with invalid type %q, expected one of: %s, %s, %s", name, notification.Type, NotificationSlack, NotificationTeams, NotificationDiscord)
+			}
+
+			if notification.WebhookURL == "" {
+				return fmt.Errorf("component %s has a %s notification, but no webhook-url", name, notification.Type)
+			}
+		}
+
+		if component.Webhook != nil && (component.Webhook.URL == "" || component.Webhook.Secret == "") {
+			return fmt.Errorf("component %s has a webhook, but no url or secret", name)
+		}
+
+		if component.Approval != nil {
+			if err := component.Approval.validate(); err != nil {
+				return fmt.Errorf("component %s: %w", name, err)
+			}
+		}
+
+		switch component.Type {
+		case "", ComponentHelm, ComponentNpm:
+		case ComponentTerraform:
+			if path.Clean(strings.Trim(component.Path, "/")) == "." {
+				return fmt.Errorf("component %s is a %s module, so must have a path to name its tags after", name, ComponentTerraform)
+			}
+		case ComponentSubmodule:
+			if path.Clean(strings.Trim(component.Path, "/")) == "." {
+				return fmt.Errorf("component %s is a %s, so must have the submodule's path", name, ComponentSubmodule)
+			}
+		default:
+			return fmt.Errorf("component %s has invalid type %q, expected one of: %s, %s, %s, %s", name, component.Type, ComponentHelm, ComponentNpm, ComponentSubmodule, ComponentTerraform)
+		}
+
+		if component.ChartRegistry != "" && (component.Type != ComponentHelm || !strings.HasPrefix(component.ChartRegistry, "oci://")) {
+			return fmt.Errorf("component %s has a chart-registry, so must be a %s component with an oci:// registry", name, ComponentHelm)
+		}
+
+		for _, file := range component.VersionFiles {
+			if _, err := file.withDefaults(); err != nil {
+				return fmt.Errorf("component %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateGlobs checks that every glob pattern of a setting compiles
+func validateGlobs(setting string, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := compileGlob(pattern); err != nil {
+			return fmt.Errorf("%s has invalid pattern %q: %w", setting, pattern, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver"
+)
+
+// Parts of the versions a constraint keeps equal
+const (
+	// ConstraintMajor keeps the major versions equal
+	ConstraintMajor = "major"
+	// ConstraintMinor keeps the major and minor versions equal
+	ConstraintMinor = "minor"
+)
+
+// What happens when a release would violate a constraint
+const (
+	// ConstraintFail fails the run before anything is released
+	ConstraintFail = "fail"
+	// ConstraintBump bumps the lagging component to match, if it's versioned in the same run
+	ConstraintBump = "bump"
+)
+
+// Constraint keeps part of a component's version equal to another component's, eg: so that a web UI's major version
+// always matches the major version of the API it's built for. Constraints are checked by GenerateVersions whenever
+// either component is released.
+type Constraint struct {
+	// Component whose version the constrained component's must match
+	Component string `yaml:"component"`
+	// Match is the part of the versions which must be equal: major (the default), or minor
+	Match string `yaml:"match,omitempty"`
+	// OnViolation is what happens when a release would violate the constraint: fail (the default), or bump
+	OnViolation string `yaml:"on-violation,omitempty"`
+}
+
+// validate the constraint of a component
+func (c Constraint) validate(component string) error {
+	if c.Component == "" || c.Component == component {
+		return fmt.Errorf("constraint needs another component than %s", component)
+	}
+
+	switch c.Match {
+	case "", ConstraintMajor, ConstraintMinor:
+	default:
+		return fmt.Errorf("constraint on %s has invalid match %q, expected one of: %s, %s", c.Component, c.Match, ConstraintMajor, ConstraintMinor)
+	}
+
+	switch c.OnViolation {
+	case "", ConstraintFail, ConstraintBump:
+	default:
+		return fmt.Errorf("constraint on %s has invalid on-violation %q, expected one of: %s, %s", c.Component, c.OnViolation, ConstraintFail, ConstraintBump)
+	}
+
+	return nil
+}
+
+// matchOrDefault is the part of the versions which must be equal
+func (c Constraint) matchOrDefault() string {
+	if c.Match == "" {
+		return ConstraintMajor
+	}
+
+	return c.Match
+}
+
+// compare the constrained parts of two versions, returning -1, 0 or 1 if the first is lower, equal or higher
+func (c Constraint) compare(version *semver.Version, other *semver.Version) int {
+	if version.Major() != other.Major() || c.matchOrDefault() == ConstraintMajor {
+		return compareNumbers(version.Major(), other.Major())
+	}
+
+	return compareNumbers(version.Minor(), other.Minor())
+}
+
+// floor is the lowest version which keeps the constraint with a version, eg: 2.0.0 for 2.3.1 when matching major
+// versions
+func (c Constraint) floor(version *semver.Version) *semver.Version {
+	if c.matchOrDefault() == ConstraintMajor {
+		return semver.MustParse(fmt.Sprintf("%d.0.0", version.Major()))
+	}
+
+	return semver.MustParse(fmt.Sprintf("%d.%d.0", version.Major(), version.Minor()))
+}
+
+func compareNumbers(number int64, other int64) int {
+	switch {
+	case number < other:
+		return -1
+	case number > other:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// withMinimumVersion releases at least a version, eg: to keep up with a component the component is constrained to.
+// A version is released even if no commits changed the component.
+func (a VersioningAction) withMinimumVersion(version *semver.Version) VersioningAction {
+	a.minimumVersion = version
+	return a
+}
+
+// constrainVersions plans the versions of the actions' components, and keeps the constraints between them and any
+// other components: a component which would lag behind one it's constrained to is bumped to match if its constraint
+// allows it, and is released in the run, or the run fails before anything is released. Returns the actions, with
+// each bumped component's minimum version set.
+func constrainVersions(ctx context.Context, actions []VersioningAction) []VersioningAction {
+	type edge struct {
+		component string
+		Constraint
+	}
+
+	first := actions[0]
+	var constraints []edge
+	for name, component := range first.config.Components {
+		for _, constraint := range component.Constraints {
+			constraints = append(constraints, edge{component: name, Constraint: constraint})
+		}
+	}
+
+	inRun := make(map[string]int)
+	for i, a := range actions {
+		inRun[a.component] = i
+	}
+
+	var relevant []edge
+	for _, constraint := range constraints {
+		_, constrainedInRun := inRun[constraint.component]
+		_, otherInRun := inRun[constraint.Component]
+		if constrainedInRun || otherInRun {
+			relevant = append(relevant, constraint)
+		}
+	}
+
+	if len(relevant) == 0 {
+		return actions
+	}
+
+	// The versions each component would be released as, or their current version if they wouldn't be released
+	versions := make(map[string]*semver.Version)
+	releasing := make(map[string]bool)
+	planned := forEachComponent(actions, func(a VersioningAction) Result {
+		a.logger.Info("Planning version to check its constraints", "component", a.component)
+		return a.GenerateVersion(ctx, true)
+	})
+
+	for _, result := range planned {
+		versions[result.Component], releasing[result.Component] = result.PreviousVersion, false
+		if result.Version != nil && result.Frozen == "" && result.Held == "" {
+			versions[result.Component], releasing[result.Component] = result.Version, true
+		}
+	}
+
+	for _, constraint := range relevant {
+		for _, component := range []string{constraint.component, constraint.Component} {
+			if _, ok := versions[component]; !ok {
+				other := first.ForComponent(component, "")
+				if version, firstVersionCreated := other.existingVersionOrBaseline(other.baselineReleases(other.getAllReleases(ctx))); !firstVersionCreated {
+					versions[component] = version
+				}
+			}
+		}
+	}
+
+	// Bumping one component can make it lead another, so keep going until every constraint is kept
+	minimumVersions := make(map[string]*semver.Version)
+	for changed := true; changed; {
+		changed = false
+		for _, constraint := range relevant {
+			version, other := versions[constraint.component], versions[constraint.Component]
+			if version == nil || other == nil || (!releasing[constraint.component] && !releasing[constraint.Component]) {
+				continue
+			}
+
+			lagging, leading := constraint.component, constraint.Component
+			switch constraint.compare(version, other) {
+			case 0:
+				continue
+			case 1:
+				lagging, leading = leading, lagging
+			}
+
+			index, laggingInRun := inRun[lagging]
+			if constraint.OnViolation != ConstraintBump || !laggingInRun {
+				panic(fmt.Sprintf("Releasing %s %s would violate the constraint that %s's %s version must equal %s's, as %s would be %s. "+
+					"Version %s in the same run with the constraint's on-violation set to bump, or release it first",
+					leading, versions[leading], constraint.component, constraint.matchOrDefault(), constraint.Component, lagging, versions[lagging], lagging))
+			}
+
+			floor := constraint.floor(versions[leading])
+			actions[index].logger.Info(fmt.Sprintf("Bumping %s to %s to keep up with %s %s", lagging, floor, leading, versions[leading]), "component", lagging)
+			versions[lagging], releasing[lagging], minimumVersions[lagging] = floor, true, floor
+			changed = true
+		}
+	}
+
+	constrained := make([]VersioningAction, len(actions))
+	for i, a := range actions {
+		constrained[i] = a
+		if minimumVersion, ok := minimumVersions[a.component]; ok {
+			constrained[i] = a.withMinimumVersion(minimumVersion)
+		}
+	}
+
+	return constrained
+}
@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+	"gopkg.in/yaml.v3"
+)
+
+// Contributor is who a git author email belongs to, for crediting them in release notes
+type Contributor struct {
+	// Login of the contributor's GitHub account, credited as @login
+	Login string `yaml:"login,omitempty"`
+	// Name credited when the contributor has no GitHub account, or doesn't want to be mentioned
+	Name string `yaml:"name,omitempty"`
+}
+
+// withContributors reads the contributors file, which maps git author emails to contributors, eg:
+//
+//	jane@example.com:
+//	  login: janedoe
+//	bob@example.com:
+//	  name: Bob Smith
+func (c Config) withContributors() (Config, error) {
+	if c.ContributorsFile == "" {
+		return c, nil
+	}
+
+	contents, err := os.ReadFile(c.ContributorsFile)
+	if err != nil {
+		return c, err
+	}
+
+	var contributors map[string]Contributor
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&contributors); err != nil {
+		return c, fmt.Errorf("invalid contributors file %s: %w", c.ContributorsFile, err)
+	}
+
+	c.contributors = make(map[string]Contributor)
+	for email, contributor := range contributors {
+		if contributor.Login == "" && contributor.Name == "" {
+			return c, fmt.Errorf("contributors file %s has no login or name for %s", c.ContributorsFile, email)
+		}
+
+		// Emails are matched case-insensitively, as git doesn't normalise them
+		c.contributors[strings.ToLower(email)] = contributor
+	}
+
+	return c, nil
+}
+
+// contributor credited for a commit. The contributors file is preferred, so that authors are credited the same
+// way whichever email or account they committed with. Otherwise the author's GitHub account is credited, falling
+// back to their git name when the commit's email isn't linked to an account. Returns empty if the commit has no
+// author.
+func (a VersioningAction) contributor(commit *github.RepositoryCommit) string {
+	if contributor, ok := a.config.contributors[strings.ToLower(commit.GetCommit().GetAuthor().GetEmail())]; ok {
+		if contributor.Login != "" {
+			return fmt.Sprintf("@%s", contributor.Login)
+		}
+
+		return contributor.Name
+	}
+
+	if login := commit.GetAuthor().GetLogin(); login != "" {
+		return fmt.Sprintf("@%s", login)
+	}
+
+	return commit.GetCommit().GetAuthor().GetName()
+}
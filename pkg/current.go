@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// CurrentVersions are the newest released versions of a component
+type CurrentVersions struct {
+	// Repository the component is in, in the format "owner/repository"
+	Repository string `json:"repository"`
+	Component  string `json:"component"`
+	// Newest stable version, or nil if the component has no stable releases
+	Stable *semver.Version `json:"version"`
+	// Newest prerelease version, or nil if the component has no prereleases
+	Prerelease *semver.Version `json:"prereleaseVersion"`
+	// Prefix of the component's tags, which the versions are appended to
+	tagPrefix string
+	// Tags of the newest releases, which may not follow the tag template if it changed since they were released
+	stableTag     string
+	prereleaseTag string
+}
+
+// CurrentVersions finds the newest stable and prerelease versions of the component which have been published,
+// without generating or releasing anything
+func (a VersioningAction) CurrentVersions(ctx context.Context) CurrentVersions {
+	current := CurrentVersions{Repository: a.Repository(), Component: a.component, tagPrefix: a.tagPrefix()}
+	releases := a.getAllReleases(ctx)
+	if stable := publishedReleases(a.filterAndSortReleasesForComponent(releases)); len(stable) > 0 {
+		current.Stable, current.stableTag = a.releaseVersion(stable[0]), stable[0].GetTagName()
+	}
+
+	for _, release := range a.filterPrereleasesForComponent(releases) {
+		if version := a.releaseVersion(release); current.Prerelease == nil || version.GreaterThan(current.Prerelease) {
+			current.Prerelease, current.prereleaseTag = version, release.GetTagName()
+		}
+	}
+
+	a.logger.Info("Found current versions", "component", a.component, "stable", versionOrNone(current.Stable), "prerelease", versionOrNone(current.Prerelease))
+	return current
+}
+
+// StableTagName of the newest stable version, or empty if there isn't one
+func (c CurrentVersions) StableTagName() string {
+	if c.stableTag != "" {
+		return c.stableTag
+	}
+
+	return tagNameOrNone(c.tagPrefix, c.Component, c.Stable)
+}
+
+// PrereleaseTagName of the newest prerelease version, or empty if there isn't one
+func (c CurrentVersions) PrereleaseTagName() string {
+	if c.prereleaseTag != "" {
+		return c.prereleaseTag
+	}
+
+	return tagNameOrNone(c.tagPrefix, c.Component, c.Prerelease)
+}
+
+// tagNameOrNone for a version of a component, or empty if there's no version. Without a tag prefix, the default
+// tag template is used.
+func tagNameOrNone(prefix string, component string, version *semver.Version) string {
+	if version == nil {
+		return ""
+	}
+
+	if prefix == "" {
+		prefix = strings.ToLower(tagPrefix(DefaultTagTemplate, component))
+	}
+
+	return prefix + strings.ToLower(version.String())
+}
+
+// versionOrNone formats a version, or empty if there's no version
+func versionOrNone(version *semver.Version) string {
+	if version == nil {
+		return ""
+	}
+
+	return version.String()
+}
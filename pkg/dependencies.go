@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// dependencyManifests are the files of dependent components which pin the versions of other components, along with
+// how to match a pinned version of a package. The first capture group is kept, and the version after it replaced.
+var dependencyManifests = map[string]func(pkg string) (*regexp.Regexp, string){
+	"go.mod": func(pkg string) (*regexp.Regexp, string) {
+		return regexp.MustCompile(fmt.Sprintf(`(?m)^(\s*(?:require\s+)?%s\s+)v[^\s]+`, regexp.QuoteMeta(pkg))), "v"
+	},
+	"package.json": func(pkg string) (*regexp.Regexp, string) {
+		// Keep any range operator, eg: "^1.2.3" becomes "^1.3.0"
+		return regexp.MustCompile(fmt.Sprintf(`("%s"\s*:\s*"[\^~]?)[0-9][^"]*`, regexp.QuoteMeta(pkg))), ""
+	},
+}
+
+// updatePinnedVersion of a package in a dependency manifest
+func updatePinnedVersion(manifest string, contents string, pkg string, version *semver.Version) string {
+	pattern, prefix := dependencyManifests[manifest](pkg)
+	return pattern.ReplaceAllString(contents, fmt.Sprintf("${1}%s%s", prefix, version.String()))
+}
+
+// propagateVersion opens a pull request which updates the version of the component pinned in the manifests of
+// the components which depend on it. If the pull request already exists, for example when re-running a release,
+// its branch is updated instead. Returns the pull request's URL, or empty if no manifests pin the component.
+func (a VersioningAction) propagateVersion(ctx context.Context, version *semver.Version) string {
+	config := a.componentConfig()
+	requestCtx, cancel := a.requestContext(ctx)
+	baseRef, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, fmt.Sprintf("refs/heads/%s", a.defaultBranch))
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	baseSHA := baseRef.GetObject().GetSHA()
+	var entries []*github.TreeEntry
+	for _, dependent := range config.Dependents {
+		for manifest := range dependencyManifests {
+			manifestPath := path.Join(a.config.Component(dependent).Path, manifest)
+			current, ok := a.findFileContents(ctx, manifestPath, baseSHA)
+			if !ok {
+				continue
+			}
+
+			updated := updatePinnedVersion(manifest, current, config.Package, version)
+			if updated == current {
+				continue
+			}
+
+			a.logger.Info("Updating dependency version", "component", a.component, "dependent", dependent, "path", manifestPath, "version", version.String())
+			entries = append(entries, &github.TreeEntry{
+				Path:    github.String(manifestPath),
+				Mode:    github.String("100644"),
+				Type:    github.String("blob"),
+				Content: github.String(updated),
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		a.logger.Info("No dependent components pin this component, so no pull request is needed", "component", a.component)
+		return ""
+	}
+
+	title := fmt.Sprintf("chore(deps): bump %s to %s", a.component, version.String())
+	commitSHA := a.createCommit(ctx, baseSHA, title, entries)
+	branch := fmt.Sprintf("monorepo-versioning/%s-%s", strings.ToLower(a.component), version.String())
+	a.forcePushBranch(ctx, branch, commitSHA)
+
+	body := fmt.Sprintf("Updates the version of %s pinned by its dependents to %s.\n\nThis pull request was opened automatically when %s was released.", a.component, version.String(), a.tagName(version.String()))
+	return a.openOrUpdatePullRequest(ctx, branch, a.defaultBranch, title, body).GetHTMLURL()
+}
+
+// forcePushBranch points a branch at a commit, creating the branch if it doesn't exist
+func (a VersioningAction) forcePushBranch(ctx context.Context, branch string, commitSHA string) {
+	ref := &github.Reference{
+		Ref: github.String(fmt.Sprintf("refs/heads/%s", branch)),
+		Object: &github.GitObject{
+			SHA: &commitSHA,
+		},
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	_, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, ref.GetRef())
+	cancel()
+
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	action := AuditUpdateBranch
+	if isNotFound(err) {
+		action = AuditCreateBranch
+		_, _, err = a.client.Git.CreateRef(requestCtx, a.owner, a.repository, ref)
+	} else if err == nil {
+		_, _, err = a.client.Git.UpdateRef(requestCtx, a.owner, a.repository, ref, true)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: action, Branch: branch, SHA: commitSHA})
+}
+
+// openOrUpdatePullRequest from branch into base. If a pull request is already open for the branch, its title and
+// body are updated.
+func (a VersioningAction) openOrUpdatePullRequest(ctx context.Context, branch string, base string, title string, body string) *github.PullRequest {
+	requestCtx, cancel := a.requestContext(ctx)
+	existing, _, err := a.client.PullRequests.List(requestCtx, a.owner, a.repository, &github.PullRequestListOptions{
+		State: "open",
+		Head:  fmt.Sprintf("%s:%s", a.owner, branch),
+		Base:  base,
+	})
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	var pullRequest *github.PullRequest
+	if len(existing) > 0 {
+		a.logger.Info("Updating pull request", "number", existing[0].GetNumber(), "title", title)
+		pullRequest, _, err = a.client.PullRequests.Edit(requestCtx, a.owner, a.repository, existing[0].GetNumber(), &github.PullRequest{
+			Title: &title,
+			Body:  &body,
+		})
+	} else {
+		a.logger.Info("Opening pull request", "branch", branch, "title", title)
+		pullRequest, _, err = a.client.PullRequests.Create(requestCtx, a.owner, a.repository, &github.NewPullRequest{
+			Title: &title,
+			Head:  &branch,
+			Base:  &base,
+			Body:  &body,
+		})
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return pullRequest
+}
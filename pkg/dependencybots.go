@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// DependencyUpdatesConfig configures how commits from dependency update bots, such as Dependabot and Renovate,
+// are recognised and released
+type DependencyUpdatesConfig struct {
+	// Bump caused by dependency updates: none (the default), or patch
+	Bump Bump `yaml:"bump,omitempty"`
+	// Authors are the logins of dependency update bots. Defaults to Dependabot and Renovate.
+	Authors []string `yaml:"authors,omitempty"`
+	// Patterns are regexes matching the messages of dependency updates. Defaults to the messages of Dependabot and
+	// Renovate.
+	Patterns []string `yaml:"patterns,omitempty"`
+}
+
+var (
+	defaultDependencyBotAuthors     = []string{"dependabot[bot]", "renovate[bot]"}
+	defaultDependencyUpdatePatterns = []string{
+		`(?i)^bump \S+ from \S+ to \S+`,
+		`(?i)^update dependency \S+`,
+		`(?i)^\w+\(deps(-dev)?\): `,
+	}
+)
+
+// isDependencyUpdate checks whether a commit was made by a dependency update bot, or has a dependency update
+// message
+func (a VersioningAction) isDependencyUpdate(commit *github.RepositoryCommit) bool {
+	config := a.config.DependencyUpdates
+	authors, patterns := config.Authors, config.Patterns
+	if len(authors) == 0 {
+		authors = defaultDependencyBotAuthors
+	}
+
+	if len(patterns) == 0 {
+		patterns = defaultDependencyUpdatePatterns
+	}
+
+	for _, author := range authors {
+		if strings.EqualFold(commit.GetAuthor().GetLogin(), author) {
+			return true
+		}
+	}
+
+	for _, pattern := range patterns {
+		if regexp.MustCompile(pattern).MatchString(commit.GetCommit().GetMessage()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// includesDependencyUpdate checks whether a dependency update affects the component. Dependency updates rarely
+// have a useful scope, so they are attributed by the files they changed in the component's path.
+func (a VersioningAction) includesDependencyUpdate(ctx context.Context, commit *github.RepositoryCommit) (bool, string) {
+	if a.componentConfig().Path == "" {
+		return false, "dependency update, but the component has no path to attribute it by"
+	}
+
+	componentPath := path.Clean(a.componentConfig().Path)
+	for _, file := range a.getCommitFiles(ctx, commit.GetSHA()) {
+		if file == componentPath || strings.HasPrefix(file, componentPath+"/") {
+			return true, fmt.Sprintf("dependency update changed %s", file)
+		}
+	}
+
+	return false, "dependency update changed no files in the component's path"
+}
@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// WithDeploymentEnvironment records each published release as a deployment to the environment, so that the
+// environment's deployment history shows which version of the component it has. "{component}" in the environment
+// is replaced with the component name, eg: "{component}-production".
+func (a VersioningAction) WithDeploymentEnvironment(environment string) VersioningAction {
+	a.deploymentEnvironment = environment
+	return a
+}
+
+// recordDeployment of a published release to the deployment environment, if one was set
+func (a VersioningAction) recordDeployment(ctx context.Context, result Result) {
+	if a.deploymentEnvironment == "" {
+		return
+	}
+
+	environment := strings.ReplaceAll(a.deploymentEnvironment, "{component}", a.component)
+	tagName := result.TagName()
+	a.logger.Info("Creating deployment", "component", a.component, "environment", environment, "tag", tagName)
+	requestCtx, cancel := a.requestContext(ctx)
+	deployment, _, err := a.client.Repositories.CreateDeployment(requestCtx, a.owner, a.repository, &github.DeploymentRequest{
+		Ref:         &tagName,
+		Task:        github.String("release"),
+		Environment: &environment,
+		Description: github.String(fmt.Sprintf("Release %s", tagName)),
+		Payload:     map[string]string{"component": a.component, "version": result.Version.String()},
+		// The tag was just created from a commit which was already checked, so don't merge or wait for checks
+		AutoMerge:        github.Bool(false),
+		RequiredContexts: &[]string{},
+	})
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	var environmentURL *string
+	if result.Release != nil {
+		environmentURL = &result.Release.HTMLURL
+	}
+
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	_, _, err = a.client.Repositories.CreateDeploymentStatus(requestCtx, a.owner, a.repository, deployment.GetID(), &github.DeploymentStatusRequest{
+		State:          github.String("success"),
+		EnvironmentURL: environmentURL,
+		Description:    github.String(fmt.Sprintf("Released %s", tagName)),
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// discoveryManifests are the files marking a component's directory in each supported ecosystem
+var discoveryManifests = map[string]string{
+	"go":     "go.mod",
+	"npm":    "package.json",
+	"cargo":  "Cargo.toml",
+	"python": "pyproject.toml",
+}
+
+// ignoredDiscoveryDirectories contain dependencies or fixtures rather than components
+var ignoredDiscoveryDirectories = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"testdata":     true,
+}
+
+// DiscoverComponents scans the repository tree at the current revision for the manifests of the configured
+// ecosystems, and adds a component for each directory containing one, named after the directory. Components
+// which are already configured, or whose path is already configured, are left as they are. The manifest at the
+// repository root describes the workspace rather than a component, so it is skipped.
+func (a VersioningAction) DiscoverComponents(ctx context.Context) Config {
+	config := a.config
+	if len(config.Discover) == 0 {
+		return config
+	}
+
+	manifests := make(map[string]bool)
+	for _, ecosystem := range config.Discover {
+		manifests[discoveryManifests[ecosystem]] = true
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	tree, _, err := a.client.Git.GetTree(requestCtx, a.owner, a.repository, a.revision, true)
+	if err != nil {
+		panic(err)
+	}
+
+	if tree.GetTruncated() {
+		a.logger.Warn("The repository tree is too large to list in full, so some components may not be discovered")
+	}
+
+	components := make(map[string]ComponentConfig)
+	configuredPaths := make(map[string]bool)
+	for name, component := range config.Components {
+		components[name] = component
+		configuredPaths[path.Clean(component.Path)] = true
+	}
+
+	var directories []string
+	for _, entry := range tree.Entries {
+		directory := path.Dir(entry.GetPath())
+		if entry.GetType() != "blob" || !manifests[path.Base(entry.GetPath())] || directory == "." || isIgnoredDiscoveryDirectory(directory) {
+			continue
+		}
+
+		directories = append(directories, directory)
+	}
+
+	// Sort so that the same component wins a name clash on every run
+	sort.Strings(directories)
+	for _, directory := range directories {
+		name := strings.ToLower(path.Base(directory))
+		if configuredPaths[directory] {
+			continue
+		}
+
+		if existing, ok := components[name]; ok {
+			if existing.Path != directory {
+				a.logger.Warn(fmt.Sprintf("Discovered component %s in %s, but a component with the same name is in %s, so it was skipped. Configure it with a different name", name, directory, existing.Path))
+			}
+
+			continue
+		}
+
+		a.logger.Info("Discovered component", "component", name, "path", directory)
+		components[name] = ComponentConfig{Path: directory}
+		configuredPaths[directory] = true
+	}
+
+	config.Components = components
+	return config
+}
+
+// isIgnoredDiscoveryDirectory checks whether any directory in the path contains dependencies or fixtures
+func isIgnoredDiscoveryDirectory(directory string) bool {
+	for _, name := range strings.Split(directory, "/") {
+		if ignoredDiscoveryDirectories[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ComponentNames of every configured component, sorted by name
+func (c Config) ComponentNames() []string {
+	var names []string
+	for name := range c.Components {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
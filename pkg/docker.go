@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DockerTags are the container image tags recommended for the generated version, eg: "1", "1.4", "1.4.2",
+// "sha-abc1234" and "latest" for a stable version. Prereleases are only tagged with their full version and commit,
+// so that they never replace a stable tag, and "latest" is only recommended outside maintenance branches, as older
+// lines shouldn't replace the newest version. Returns nil if no version was generated.
+func (r Result) DockerTags() []string {
+	if r.Version == nil {
+		return nil
+	}
+
+	// Build metadata isn't allowed in image tags, so it's joined with a dash like docker/metadata-action does
+	tags := []string{strings.ReplaceAll(r.Version.String(), "+", "-")}
+	if r.Version.Prerelease() == "" && r.Version.Metadata() == "" {
+		tags = []string{fmt.Sprintf("%d.%d", r.Version.Major(), r.Version.Minor()), r.Version.String()}
+		// 0.x versions may break compatibility in any minor version, so there's no major tag to track
+		if r.Version.Major() > 0 {
+			tags = append([]string{fmt.Sprintf("%d", r.Version.Major())}, tags...)
+		}
+	}
+
+	sha := r.revision
+	if r.Release != nil && r.Release.SHA != "" {
+		sha = r.Release.SHA
+	}
+
+	if sha != "" {
+		tags = append(tags, "sha-"+shortSHA(sha))
+	}
+
+	if r.Version.Prerelease() == "" && r.latest {
+		tags = append(tags, "latest")
+	}
+
+	return tags
+}
@@ -0,0 +1,262 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/ellisto/monorepo-versioning/pkg/policy"
+	"github.com/google/go-github/v50/github"
+)
+
+// Bump is the part of a semantic version incremented by a change
+type Bump = policy.Bump
+
+const (
+	BumpMajor = policy.Major
+	BumpMinor = policy.Minor
+	BumpPatch = policy.Patch
+	BumpNone  = policy.None
+)
+
+// Result of generating a version for a component, including the decision made for each commit in the range
+// so that users can see why a commit did or did not trigger a release
+type Result struct {
+	Component       string           `json:"component"`
+	Channel         string           `json:"channel,omitempty"`
+	PreviousVersion *semver.Version  `json:"previousVersion,omitempty"`
+	Version         *semver.Version  `json:"version,omitempty"`
+	Bump            Bump             `json:"bump"`
+	Commits         []CommitDecision `json:"commits"`
+	// The GitHub release created for the version, if one was created
+	Release *Release `json:"release,omitempty"`
+	// The release published to the mirror repository, if there is one and the version was released
+	MirrorRelease *Release `json:"mirrorRelease,omitempty"`
+	// What is released for the version, or would have been released in a dry run
+	Preview *ReleasePreview `json:"preview,omitempty"`
+	// URL of the release pull request opened instead of releasing the version, if one was opened
+	ReleasePullRequestURL string `json:"releasePullRequestUrl,omitempty"`
+	// URL of the pull request updating the version pinned by dependent components, if one was opened
+	DependencyPullRequestURL string `json:"dependencyPullRequestUrl,omitempty"`
+	// Reference of the Helm chart pushed for the version, if one was pushed, eg: "oci://ghcr.io/owner/charts/api:1.2.3"
+	Chart string `json:"chart,omitempty"`
+	// Source of the Terraform module at the version, if the component is one, eg:
+	// "git::https://github.com/owner/repository.git//modules/vpc?ref=modules/vpc/v1.2.3"
+	ModuleSource string `json:"moduleSource,omitempty"`
+	// npm dist-tag the package should be published with, if the component is an npm package and its version was
+	// released, eg: "latest" or "beta"
+	DistTag string `json:"distTag,omitempty"`
+	// Why the version was generated but not released, if the component is frozen, eg: "the component is frozen"
+	Frozen string `json:"frozen,omitempty"`
+	// Why the version was generated but not released, if its release needed approval and was denied or wasn't
+	// approved in time
+	Held string `json:"held,omitempty"`
+	// Prefix of the component's tags, which the version is appended to
+	tagPrefix string
+	// Commit SHA the version is generated for, unless a release records a different one
+	revision string
+	// Whether the version is the newest of the component, rather than from a maintenance branch
+	latest bool
+	// GitHub release created for the version, which completeRelease follows up
+	created *github.RepositoryRelease
+}
+
+// TagName of the generated version, or empty if no version was generated
+func (r Result) TagName() string {
+	return tagNameOrNone(r.tagPrefix, r.Component, r.Version)
+}
+
+// IncludedCommits counts the commits in the range which are scoped to the component
+func (r Result) IncludedCommits() int {
+	count := 0
+	for _, decision := range r.Commits {
+		if decision.MatchedScope {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Release identifies a GitHub release, so that follow-up steps can reference it without querying the API
+type Release struct {
+	ID        int64  `json:"id"`
+	UploadURL string `json:"uploadUrl"`
+	HTMLURL   string `json:"htmlUrl"`
+	// SHA of the commit released
+	SHA string `json:"sha"`
+	// Download URL of the release's provenance statement, if one was attached
+	ProvenanceURL string `json:"provenanceUrl,omitempty"`
+	// Download URL of the release's SBOM, if one was attached
+	SBOMURL string `json:"sbomUrl,omitempty"`
+	// Drafts aren't released until they're published
+	draft bool
+}
+
+// newRelease from the GitHub API representation of a release
+func newRelease(release *github.RepositoryRelease) *Release {
+	return &Release{
+		ID:        release.GetID(),
+		UploadURL: release.GetUploadURL(),
+		HTMLURL:   release.GetHTMLURL(),
+		SHA:       release.GetTargetCommitish(),
+		draft:     release.GetDraft(),
+	}
+}
+
+// CommitDecision explains how a single commit affected a component's version
+type CommitDecision struct {
+	SHA          string `json:"sha"`
+	Summary      string `json:"summary"`
+	Parsed       bool   `json:"parsed"`
+	Type         string `json:"type,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	Breaking     bool   `json:"breaking,omitempty"`
+	MatchedScope bool   `json:"matchedScope"`
+	Bump         Bump   `json:"bump"`
+	Reason       string `json:"reason"`
+}
+
+// newCommitDecision for a commit which has not yet been parsed
+func newCommitDecision(commit *github.RepositoryCommit) CommitDecision {
+	summary, _, _ := strings.Cut(commit.GetCommit().GetMessage(), "\n")
+	return CommitDecision{
+		SHA:     commit.GetSHA(),
+		Summary: summary,
+		Bump:    BumpNone,
+	}
+}
+
+// policyCommit is the commit the decision is for, as the version policy sees it
+func (d CommitDecision) policyCommit() policy.Commit {
+	return policy.Commit{
+		SHA:              d.SHA,
+		Summary:          d.Summary,
+		Type:             d.Type,
+		Scope:            d.Scope,
+		Breaking:         d.Breaking,
+		DependencyUpdate: !d.Parsed,
+	}
+}
+
+// policyCommits are the commits included in the component's version, which the version policy decides its bump
+// from. Commits which were skipped, eg: because they're scoped to another component, are left out.
+func policyCommits(decisions []CommitDecision) []policy.Commit {
+	var commits []policy.Commit
+	for _, decision := range decisions {
+		if decision.MatchedScope {
+			commits = append(commits, decision.policyCommit())
+		}
+	}
+
+	return commits
+}
+
+// WithPolicy decides the version bump of each component with a custom policy rather than the Conventional
+// Commits specification, eg: to cap the bump or require sign-off for major versions. Explanations show the bump
+// the policy decides for each commit on its own.
+func (a VersioningAction) WithPolicy(bumpPolicy policy.Policy) VersioningAction {
+	a.bumpPolicy = bumpPolicy
+	return a
+}
+
+// versionPolicy which decides the version bump from the component's commits
+func (a VersioningAction) versionPolicy() policy.Policy {
+	if a.bumpPolicy != nil {
+		return a.bumpPolicy
+	}
+
+	return a.conventionalPolicy()
+}
+
+// commitBump is the bump a single commit causes under the policy which decides the version, so that explanations
+// and checks agree with the version released
+func (a VersioningAction) commitBump(commit policy.Commit) Bump {
+	return a.versionPolicy().Decide([]policy.Commit{commit})
+}
+
+// conventionalPolicy bumps versions following the Conventional Commits specification, with the configured bump for
+// dependency updates
+func (a VersioningAction) conventionalPolicy() policy.Conventional {
+	return policy.Conventional{DependencyUpdates: a.config.DependencyUpdates.Bump}
+}
+
+// warnAboutSkippedCommits logs a warning for each commit which looks like it was meant for the component, but
+// was skipped because it isn't a conventional commit or has no scope. When running in GitHub Actions, the
+// warnings are shown as annotations in the workflow summary so that commit authors can see the problem.
+func warnAboutSkippedCommits(logger *slog.Logger, component string, decisions []CommitDecision) {
+	for _, problem := range skippedCommitProblems(component, decisions) {
+		logger.Warn(problem.message, "summary", problem.decision.Summary)
+	}
+}
+
+// commitProblem is a commit which was probably meant to affect a component's version, but didn't
+type commitProblem struct {
+	decision CommitDecision
+	message  string
+}
+
+// skippedCommitProblems finds the commits which mention the component, but were skipped because they aren't
+// conventional commits or have no scope
+func skippedCommitProblems(component string, decisions []CommitDecision) []commitProblem {
+	mentionsComponent := regexp.MustCompile(fmt.Sprintf(`(?i)\b%s\b`, regexp.QuoteMeta(component)))
+	var problems []commitProblem
+	for _, decision := range decisions {
+		if decision.MatchedScope || decision.Scope != "" || !mentionsComponent.MatchString(decision.Summary) {
+			continue
+		}
+
+		if !decision.Parsed {
+			problems = append(problems, commitProblem{decision, fmt.Sprintf("Commit %s mentions %s but is not a conventional commit, so it was ignored. Use a message like \"fix(%s): ...\"", shortSHA(decision.SHA), component, component)})
+		} else {
+			problems = append(problems, commitProblem{decision, fmt.Sprintf("Commit %s mentions %s but has no scope, so it was ignored. Use a message like \"%s(%s): ...\"", shortSHA(decision.SHA), component, decision.Type, component)})
+		}
+	}
+
+	return problems
+}
+
+// shortSHA shortens a commit SHA to 7 characters to match how GitHub usually displays it
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
+
+// LogExplanation logs the decision made for every commit in the range at the given level, followed by the
+// resulting version
+func (r Result) LogExplanation(logger *slog.Logger, level slog.Level) {
+	for _, decision := range r.Commits {
+		logger.Log(context.Background(), level, "Commit decision",
+			"component", r.Component,
+			"sha", decision.SHA,
+			"summary", decision.Summary,
+			"parsed", decision.Parsed,
+			"matchedScope", decision.MatchedScope,
+			"bump", string(decision.Bump),
+			"reason", decision.Reason)
+	}
+
+	if r.Version == nil {
+		logger.Log(context.Background(), level, "No new version", "component", r.Component, "bump", string(r.Bump))
+	} else {
+		logger.Log(context.Background(), level, "Computed version", "component", r.Component, "version", r.Version.String(), "bump", string(r.Bump))
+	}
+}
+
+// WriteExplanation writes the results as a JSON document to the file at path
+func WriteExplanation(path string, results []Result) error {
+	contents, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, contents, 0644)
+}
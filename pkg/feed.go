@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// atomFeed is an Atom feed of a component's releases, see RFC 4287
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	// Release notes are markdown, which feed readers show as text
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteFeeds writes an Atom feed of each component's releases to a directory, named after the component, eg:
+// "api.atom", so that consumers can subscribe to new versions without watching the repository. History must be
+// sorted newest first, as it is by ReleaseHistory.
+func (a VersioningAction) WriteFeeds(dir string, history []HistoricalRelease) error {
+	byComponent := make(map[string][]HistoricalRelease)
+	for _, release := range history {
+		byComponent[release.Component] = append(byComponent[release.Component], release)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for component, releases := range byComponent {
+		contents, err := xml.MarshalIndent(a.feed(component, releases), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		// Component names may contain slashes, which aren't allowed in file names
+		name := strings.ReplaceAll(component, "/", "-") + ".atom"
+		if err := os.WriteFile(filepath.Join(dir, name), append([]byte(xml.Header), contents...), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// feed of a component's releases, which are sorted newest first
+func (a VersioningAction) feed(component string, releases []HistoricalRelease) atomFeed {
+	// Releases link to their page on the GitHub server the repository is on, which the releases page is beside
+	repositoryURL, _, ok := strings.Cut(releases[0].URL, "/releases/")
+	if !ok {
+		repositoryURL = fmt.Sprintf("https://github.com/%s/%s", a.owner, a.repository)
+	}
+
+	releasesURL := repositoryURL + "/releases"
+	feed := atomFeed{
+		// The ID must never change, so it's built from the repository and component rather than the feed's location
+		ID:      fmt.Sprintf("%s#%s", releasesURL, component),
+		Title:   fmt.Sprintf("%s releases of %s/%s", component, a.owner, a.repository),
+		Updated: releases[0].Date.UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: releasesURL},
+		Author:  atomAuthor{Name: a.owner},
+	}
+
+	for _, release := range releases {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      release.URL,
+			Title:   fmt.Sprintf("%s %s", component, release.Version.String()),
+			Updated: release.Date.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: release.URL},
+			Content: atomContent{Type: "text", Body: release.Notes},
+		})
+	}
+
+	return feed
+}
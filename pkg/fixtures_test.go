@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+)
+
+// testLogWriter writes the action's logs to the test's log, so that they're shown when a test fails
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// newTestServer starts a fake GitHub repository, which is closed when the test finishes
+func newTestServer(t *testing.T) *githubtest.Server {
+	t.Helper()
+	server := githubtest.NewServer("octocat", "monorepo")
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestAction versions a component of the fake repository at the head of its default branch
+func newTestAction(t *testing.T, server *githubtest.Server, component string, opts ...Option) VersioningAction {
+	t.Helper()
+	opts = append([]Option{
+		WithRepository("octocat/monorepo"),
+		WithClient(server.Client()),
+		WithBranch(githubtest.DefaultBranch, githubtest.DefaultBranch),
+		WithRevision(server.Head(githubtest.DefaultBranch)),
+		WithComponent(component, ""),
+	}, opts...)
+
+	a, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.WithLogger(slog.New(slog.NewTextHandler(testLogWriter{t}, nil)))
+}
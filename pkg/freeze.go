@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FreezeWindow is a change freeze, during which versions are generated but not released
+type FreezeWindow struct {
+	// Start and End of the freeze, eg: 2024-12-20T00:00:00Z. The freeze ends at End, exclusive.
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+	// Reason for the freeze, which is logged when a release is held back, eg: "Holiday change freeze"
+	Reason string `yaml:"reason,omitempty"`
+	// Components frozen by the window. Every component is frozen if empty.
+	Components []string `yaml:"components,omitempty"`
+}
+
+// validate the freeze window
+func (w FreezeWindow) validate() error {
+	if w.Start.IsZero() || w.End.IsZero() {
+		return errors.New("freeze window needs a start and an end")
+	}
+
+	if !w.End.After(w.Start) {
+		return fmt.Errorf("freeze window %s ends before it starts", w.describe())
+	}
+
+	return nil
+}
+
+// freezes checks whether the window freezes a component at a time
+func (w FreezeWindow) freezes(component string, now time.Time) bool {
+	if now.Before(w.Start) || !now.Before(w.End) {
+		return false
+	}
+
+	if len(w.Components) == 0 {
+		return true
+	}
+
+	for _, frozen := range w.Components {
+		if strings.EqualFold(frozen, component) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describe the window for logs, eg: "Holiday change freeze (2024-12-20T00:00:00Z to 2025-01-06T00:00:00Z)"
+func (w FreezeWindow) describe() string {
+	period := fmt.Sprintf("%s to %s", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339))
+	if w.Reason == "" {
+		return period
+	}
+
+	return fmt.Sprintf("%s (%s)", w.Reason, period)
+}
+
+// frozen explains why the component's releases are held back, either because it's configured as frozen or because
+// a freeze window is in effect, or returns an empty string if the component can be released
+func (a VersioningAction) frozen() string {
+	if a.componentConfig().Frozen {
+		return "the component is frozen"
+	}
+
+	now := a.clock.Now()
+	for _, window := range a.config.Freezes {
+		if window.freezes(a.component, now) {
+			return "change freeze: " + window.describe()
+		}
+	}
+
+	return ""
+}
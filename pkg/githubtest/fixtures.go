@@ -0,0 +1,106 @@
+// Package githubtest is a fake of the parts of the GitHub REST API used by the action, backed by an in-memory
+// repository, so that programs embedding the action can run it end to end in their tests without calling GitHub
+// or needing a token.
+package githubtest
+
+import (
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// CommitFixture is a commit to push to a branch of the fake repository. Create one with Commit.
+type CommitFixture struct {
+	message string
+	author  string
+	date    time.Time
+	files   map[string]*string
+}
+
+// Commit with a message, eg: "feat(api): add an endpoint". Unless it's dated with At, the commit is made one
+// minute after its parent, so that histories are deterministic.
+func Commit(message string) CommitFixture {
+	return CommitFixture{message: message, author: "octocat", files: make(map[string]*string)}
+}
+
+// By sets the login of the commit's author, eg: "dependabot[bot]"
+func (c CommitFixture) By(login string) CommitFixture {
+	c.author = login
+	return c
+}
+
+// At sets the time the commit was made
+func (c CommitFixture) At(date time.Time) CommitFixture {
+	c.date = date
+	return c
+}
+
+// WithFile adds or changes a file in the commit
+func (c CommitFixture) WithFile(path string, contents string) CommitFixture {
+	c.files = copyFiles(c.files)
+	c.files[path] = &contents
+	return c
+}
+
+// WithoutFile deletes a file in the commit
+func (c CommitFixture) WithoutFile(path string) CommitFixture {
+	c.files = copyFiles(c.files)
+	c.files[path] = nil
+	return c
+}
+
+// ReleaseFixture is a release to add to the fake repository. Create one with Release.
+type ReleaseFixture struct {
+	tagName    string
+	commitSHA  string
+	prerelease bool
+	draft      bool
+	body       string
+}
+
+// Release of a tag at a commit, eg: Release("api-1.0.0", sha). The tag is created along with the release, unless
+// it's a draft.
+func Release(tagName string, commitSHA string) ReleaseFixture {
+	return ReleaseFixture{tagName: tagName, commitSHA: commitSHA}
+}
+
+// AsPrerelease marks the release as a prerelease
+func (r ReleaseFixture) AsPrerelease() ReleaseFixture {
+	r.prerelease = true
+	return r
+}
+
+// AsDraft marks the release as a draft, which has no tag until it's published
+func (r ReleaseFixture) AsDraft() ReleaseFixture {
+	r.draft = true
+	return r
+}
+
+// WithNotes sets the release notes
+func (r ReleaseFixture) WithNotes(body string) ReleaseFixture {
+	r.body = body
+	return r
+}
+
+// release converts the fixture into the release returned by the API
+func (r ReleaseFixture) release(id int64, htmlURL string) *github.RepositoryRelease {
+	return &github.RepositoryRelease{
+		ID:              github.Int64(id),
+		TagName:         github.String(r.tagName),
+		Name:            github.String(r.tagName),
+		TargetCommitish: github.String(r.commitSHA),
+		Body:            github.String(r.body),
+		Prerelease:      github.Bool(r.prerelease),
+		Draft:           github.Bool(r.draft),
+		HTMLURL:         github.String(htmlURL),
+	}
+}
+
+func copyFiles(files map[string]*string) map[string]*string {
+	copied := make(map[string]*string, len(files))
+	for path, contents := range files {
+		copied[path] = contents
+	}
+
+	return copied
+}
@@ -0,0 +1,1199 @@
+package githubtest
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// DefaultBranch of the fake repository
+const DefaultBranch = "main"
+
+// epoch is when the first commit of a fake repository is made, unless it's dated
+var epoch = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// commit in the fake repository
+type commit struct {
+	sha     string
+	message string
+	author  string
+	date    time.Time
+	parents []string
+	tree    string
+}
+
+// Server is a fake of the GitHub REST API for a single repository. It implements the meta, repository, releases,
+// release assets, commits, compare, contents, tags, issues and git database endpoints used by the action. Requests to any other
+// endpoint fail with a 404, so that a test relying on one fails loudly rather than passing by accident.
+type Server struct {
+	*httptest.Server
+	owner      string
+	repository string
+
+	mu       sync.Mutex
+	counter  int
+	commits  map[string]*commit
+	trees    map[string]map[string]string
+	refs     map[string]string
+	tags     map[string]*github.Tag
+	releases []*github.RepositoryRelease
+	// Contents of release assets, by release ID and asset name
+	assets map[int64]map[string][]byte
+	// ID of the last release created, so that IDs aren't reused after a release is deleted
+	releaseID int64
+	issues    []*github.Issue
+	// Comments on each issue, by issue number, oldest first
+	comments map[int][]*github.IssueComment
+	requests []string
+	// Version of GitHub Enterprise Server faked, or empty for GitHub.com
+	installedVersion string
+}
+
+// NewServer starts a fake of the GitHub API for an empty repository. Close it when the test finishes.
+func NewServer(owner string, repository string) *Server {
+	s := &Server{
+		owner:      owner,
+		repository: repository,
+		commits:    make(map[string]*commit),
+		trees:      map[string]map[string]string{emptyTreeSHA: {}},
+		refs:       make(map[string]string),
+		tags:       make(map[string]*github.Tag),
+		assets:     make(map[int64]map[string][]byte),
+		comments:   make(map[int][]*github.IssueComment),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// emptyTreeSHA is git's SHA of a tree with no files
+const emptyTreeSHA = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// Client creates a GitHub client which calls the fake
+func (s *Server) Client() *github.Client {
+	client := github.NewClient(s.Server.Client())
+	client.BaseURL, _ = url.Parse(s.URL + "/")
+	client.UploadURL = client.BaseURL
+	return client
+}
+
+// SetInstalledVersion fakes a GitHub Enterprise Server of the version, eg: "3.8.4", which the meta endpoint
+// reports
+func (s *Server) SetInstalledVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.installedVersion = version
+}
+
+// Push commits onto a branch, creating the branch if it doesn't exist. Returns the SHAs of the commits, in the
+// order they were pushed.
+func (s *Server) Push(branch string, fixtures ...CommitFixture) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var shas []string
+	for _, fixture := range fixtures {
+		var parents []string
+		if head, ok := s.refs["refs/heads/"+branch]; ok {
+			parents = []string{head}
+		}
+
+		sha := s.createCommit(fixture.message, fixture.author, fixture.date, parents, s.applyFiles(s.parentTree(parents), fixture.files))
+		s.refs["refs/heads/"+branch] = sha
+		shas = append(shas, sha)
+	}
+
+	return shas
+}
+
+// Merge creates a merge commit on branch which merges the head of another branch, where the merged branch's
+// files win any conflict. Returns the SHA of the merge commit.
+func (s *Server) Merge(branch string, merged string, message string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base, merging := s.refs["refs/heads/"+branch], s.refs["refs/heads/"+merged]
+	if base == "" || merging == "" {
+		panic(fmt.Sprintf("githubtest can't merge %s into %s, as both branches must exist", merged, branch))
+	}
+
+	files := make(map[string]*string)
+	for path, contents := range s.trees[s.commits[merging].tree] {
+		contents := contents
+		files[path] = &contents
+	}
+
+	sha := s.createCommit(message, "octocat", time.Time{}, []string{base, merging}, s.applyFiles(s.commits[base].tree, files))
+	s.refs["refs/heads/"+branch] = sha
+	return sha
+}
+
+// AddRelease adds a release, and its tag unless it's a draft
+func (s *Server) AddRelease(fixture ReleaseFixture) *github.RepositoryRelease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.releaseID++
+	release := fixture.release(s.releaseID, s.releaseURL(fixture.tagName))
+	if !fixture.draft && !s.publish(release) {
+		panic(fmt.Sprintf("githubtest can't release %s, as commit %s does not exist", fixture.tagName, fixture.commitSHA))
+	}
+
+	s.releases = append(s.releases, release)
+	return release
+}
+
+// Issues in the fake repository, in the order they were opened
+func (s *Server) Issues() []*github.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*github.Issue{}, s.issues...)
+}
+
+// Comments on an issue, oldest first
+func (s *Server) Comments(number int) []*github.IssueComment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*github.IssueComment{}, s.comments[number]...)
+}
+
+// Comment on an issue as a user, eg: to approve a release
+func (s *Server) Comment(number int, login string, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addComment(number, login, body)
+}
+
+// Releases in the fake repository, in the order they were created
+func (s *Server) Releases() []*github.RepositoryRelease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*github.RepositoryRelease{}, s.releases...)
+}
+
+// Head of a branch, or an empty string if the branch doesn't exist
+func (s *Server) Head(branch string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refs["refs/heads/"+branch]
+}
+
+// Tag gets the SHA of the commit a tag points at, following annotated tags
+func (s *Server) Tag(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sha, ok := s.refs["refs/tags/"+name]
+	if tag, annotated := s.tags[sha]; annotated {
+		sha = tag.GetObject().GetSHA()
+	}
+
+	return sha, ok
+}
+
+// File gets the contents of a file at a branch, tag or commit
+func (s *Server) File(ref string, path string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	commit, ok := s.resolve(ref)
+	if !ok {
+		return "", false
+	}
+
+	contents, ok := s.trees[commit.tree][path]
+	return contents, ok
+}
+
+// Asset gets the contents of an asset uploaded to the release of a tag
+func (s *Server) Asset(tagName string, name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, release := range s.releases {
+		if release.GetTagName() == tagName {
+			contents, ok := s.assets[release.GetID()][name]
+			return contents, ok
+		}
+	}
+
+	return nil, false
+}
+
+// Requests made to the fake, formatted like "POST /repos/owner/repository/releases", so that tests can check
+// that a dry run doesn't write anything
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// handle routes a request to the fake endpoint for its method and path
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+	if r.Method == http.MethodGet && r.URL.Path == "/meta" {
+		writeJSON(w, http.StatusOK, map[string]string{"installed_version": s.installedVersion})
+		return
+	}
+
+	prefix := fmt.Sprintf("/repos/%s/%s", s.owner, s.repository)
+	path, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("githubtest only fakes the repository %s/%s", s.owner, s.repository))
+		return
+	}
+
+	route := fmt.Sprintf("%s %s", r.Method, path)
+	switch {
+	case route == "GET ":
+		s.getRepository(w)
+	case route == "GET /releases":
+		writeJSON(w, http.StatusOK, paginate(reversed(s.releases), r))
+	case route == "POST /releases":
+		s.createRelease(w, r)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/releases/") && strings.HasSuffix(path, "/assets"):
+		s.uploadAsset(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/releases/"), "/assets"))
+	case strings.HasPrefix(path, "/releases/"):
+		s.handleRelease(w, r, strings.TrimPrefix(path, "/releases/"))
+	case route == "GET /commits":
+		s.listCommits(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/commits/") && strings.HasSuffix(path, "/pulls"):
+		writeJSON(w, http.StatusOK, []*github.PullRequest{})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/commits/"):
+		s.getCommit(w, strings.TrimPrefix(path, "/commits/"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/compare/"):
+		s.compareCommits(w, strings.TrimPrefix(path, "/compare/"))
+	case route == "GET /tags":
+		s.listTags(w, r)
+	case route == "GET /issues":
+		s.listIssues(w, r)
+	case route == "POST /issues":
+		s.createIssue(w, r)
+	case strings.HasPrefix(path, "/issues/"):
+		s.handleIssue(w, r, strings.TrimPrefix(path, "/issues/"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/contents/"):
+		s.getContents(w, r, strings.TrimPrefix(path, "/contents/"))
+	case route == "POST /git/commits":
+		s.createGitCommit(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/commits/"):
+		s.getGitCommit(w, strings.TrimPrefix(path, "/git/commits/"))
+	case route == "POST /git/trees":
+		s.createTree(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/trees/"):
+		s.getTree(w, strings.TrimPrefix(path, "/git/trees/"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/ref/"):
+		s.getRef(w, "refs/"+strings.TrimPrefix(path, "/git/ref/"))
+	case route == "POST /git/refs":
+		s.createRef(w, r)
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/git/refs/"):
+		s.updateRef(w, r, "refs/"+strings.TrimPrefix(path, "/git/refs/"))
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/git/refs/"):
+		s.deleteRef(w, "refs/"+strings.TrimPrefix(path, "/git/refs/"))
+	case route == "POST /git/tags":
+		s.createTag(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/tags/"):
+		s.getTag(w, strings.TrimPrefix(path, "/git/tags/"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("githubtest doesn't fake %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) getRepository(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, &github.Repository{
+		ID:            github.Int64(1),
+		Name:          github.String(s.repository),
+		FullName:      github.String(fmt.Sprintf("%s/%s", s.owner, s.repository)),
+		DefaultBranch: github.String(DefaultBranch),
+		HTMLURL:       github.String(fmt.Sprintf("https://github.com/%s/%s", s.owner, s.repository)),
+		Permissions:   map[string]bool{"admin": true, "push": true, "pull": true},
+	})
+}
+
+func (s *Server) createRelease(w http.ResponseWriter, r *http.Request) {
+	var release github.RepositoryRelease
+	if !readJSON(w, r, &release) {
+		return
+	}
+
+	for _, existing := range s.releases {
+		if existing.GetTagName() == release.GetTagName() {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Release with tag %s already_exists", release.GetTagName()))
+			return
+		}
+	}
+
+	s.releaseID++
+	release.ID = github.Int64(s.releaseID)
+	release.HTMLURL = github.String(s.releaseURL(release.GetTagName()))
+	release.Draft = github.Bool(release.GetDraft())
+	release.Prerelease = github.Bool(release.GetPrerelease())
+	if !release.GetDraft() && !s.publish(&release) {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("target_commitish %s does not exist", release.GetTargetCommitish()))
+		return
+	}
+
+	s.releases = append(s.releases, &release)
+	writeJSON(w, http.StatusCreated, &release)
+}
+
+func (s *Server) handleRelease(w http.ResponseWriter, r *http.Request, id string) {
+	index := -1
+	for i, release := range s.releases {
+		if strconv.FormatInt(release.GetID(), 10) == id {
+			index = i
+		}
+	}
+
+	if index < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Release %s does not exist", id))
+		return
+	}
+
+	release := s.releases[index]
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, release)
+	case http.MethodDelete:
+		s.releases = append(s.releases[:index], s.releases[index+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPatch:
+		var update github.RepositoryRelease
+		if !readJSON(w, r, &update) {
+			return
+		}
+
+		wasDraft := release.GetDraft()
+		if update.TagName != nil {
+			release.TagName = update.TagName
+		}
+
+		if update.Name != nil {
+			release.Name = update.Name
+		}
+
+		if update.Body != nil {
+			release.Body = update.Body
+		}
+
+		if update.TargetCommitish != nil {
+			release.TargetCommitish = update.TargetCommitish
+		}
+
+		if update.Draft != nil {
+			release.Draft = update.Draft
+		}
+
+		if update.Prerelease != nil {
+			release.Prerelease = update.Prerelease
+		}
+
+		if wasDraft && !release.GetDraft() && !s.publish(release) {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("target_commitish %s does not exist", release.GetTargetCommitish()))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, release)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("githubtest doesn't fake %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// uploadAsset to a release, named by the name query parameter. Asset names must be unique within a release.
+func (s *Server) uploadAsset(w http.ResponseWriter, r *http.Request, id string) {
+	var release *github.RepositoryRelease
+	for _, existing := range s.releases {
+		if strconv.FormatInt(existing.GetID(), 10) == id {
+			release = existing
+		}
+	}
+
+	if release == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Release %s does not exist", id))
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if _, exists := s.assets[release.GetID()][name]; exists || name == "" {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Asset name %q is missing or already_exists", name))
+		return
+	}
+
+	contents, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if s.assets[release.GetID()] == nil {
+		s.assets[release.GetID()] = make(map[string][]byte)
+	}
+
+	s.assets[release.GetID()][name] = contents
+	asset := &github.ReleaseAsset{
+		ID:                 github.Int64(int64(len(release.Assets) + 1)),
+		Name:               github.String(name),
+		ContentType:        github.String(r.Header.Get("Content-Type")),
+		Size:               github.Int(len(contents)),
+		BrowserDownloadURL: github.String(fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", s.owner, s.repository, release.GetTagName(), name)),
+	}
+
+	release.Assets = append(release.Assets, asset)
+	writeJSON(w, http.StatusCreated, asset)
+}
+
+// publish a release by creating its tag at the target commit, unless the tag already exists. Returns false if
+// the target doesn't exist.
+func (s *Server) publish(release *github.RepositoryRelease) bool {
+	tagRef := "refs/tags/" + release.GetTagName()
+	if _, ok := s.refs[tagRef]; !ok {
+		target := release.GetTargetCommitish()
+		if target == "" {
+			target = DefaultBranch
+		}
+
+		commit, ok := s.resolve(target)
+		if !ok {
+			return false
+		}
+
+		s.refs[tagRef] = commit.sha
+	}
+
+	commit, _ := s.resolve(tagRef)
+	release.PublishedAt = &github.Timestamp{Time: commit.date}
+	release.CreatedAt = release.PublishedAt
+	return true
+}
+
+// listCommits lists the commits reachable from a branch or commit, newest first, made between the since and
+// until query parameters inclusive
+func (s *Server) listCommits(w http.ResponseWriter, r *http.Request) {
+	ref := r.URL.Query().Get("sha")
+	if ref == "" {
+		ref = DefaultBranch
+	}
+
+	head, ok := s.resolve(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No commit found for SHA: %s", ref))
+		return
+	}
+
+	since, _ := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	until, err := time.Parse(time.RFC3339, r.URL.Query().Get("until"))
+	if err != nil {
+		until = time.Unix(1<<62, 0)
+	}
+
+	var commits []*github.RepositoryCommit
+	for _, commit := range s.ancestors(head.sha) {
+		if !commit.date.Before(since) && !commit.date.After(until) {
+			commits = append(commits, s.repositoryCommit(commit))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, paginate(commits, r))
+}
+
+// getCommit gets a commit with the files it changed from its first parent
+func (s *Server) getCommit(w http.ResponseWriter, ref string) {
+	commit, ok := s.resolve(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No commit found for SHA: %s", ref))
+		return
+	}
+
+	repositoryCommit := s.repositoryCommit(commit)
+	before := s.parentTree(commit.parents)
+	after := s.trees[commit.tree]
+	paths := make(map[string]bool)
+	for path := range s.trees[before] {
+		paths[path] = true
+	}
+
+	for path := range after {
+		paths[path] = true
+	}
+
+	for _, path := range sortedKeys(paths) {
+		previous, existed := s.trees[before][path]
+		current, exists := after[path]
+		status := "modified"
+		switch {
+		case !existed:
+			status = "added"
+		case !exists:
+			status = "removed"
+		case previous == current:
+			continue
+		}
+
+		repositoryCommit.Files = append(repositoryCommit.Files, &github.CommitFile{Filename: github.String(path), Status: github.String(status)})
+	}
+
+	writeJSON(w, http.StatusOK, repositoryCommit)
+}
+
+// compareCommits reports how far the head of a "base...head" comparison is ahead of and behind its base
+func (s *Server) compareCommits(w http.ResponseWriter, basehead string) {
+	baseRef, headRef, _ := strings.Cut(basehead, "...")
+	base, baseFound := s.resolve(baseRef)
+	head, headFound := s.resolve(headRef)
+	if !baseFound || !headFound {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No common ancestor between %s and %s.", baseRef, headRef))
+		return
+	}
+
+	count := func(from *commit, excluded *commit) int {
+		reachable := make(map[string]bool)
+		for _, ancestor := range s.ancestors(excluded.sha) {
+			reachable[ancestor.sha] = true
+		}
+
+		n := 0
+		for _, ancestor := range s.ancestors(from.sha) {
+			if !reachable[ancestor.sha] {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	aheadBy, behindBy := count(head, base), count(base, head)
+	status := "diverged"
+	switch {
+	case aheadBy == 0 && behindBy == 0:
+		status = "identical"
+	case behindBy == 0:
+		status = "ahead"
+	case aheadBy == 0:
+		status = "behind"
+	}
+
+	writeJSON(w, http.StatusOK, &github.CommitsComparison{
+		BaseCommit: s.repositoryCommit(base),
+		Status:     github.String(status),
+		AheadBy:    github.Int(aheadBy),
+		BehindBy:   github.Int(behindBy),
+	})
+}
+
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	for ref := range s.refs {
+		if name, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+			names = append(names, name)
+		}
+	}
+
+	// GitHub lists the newest tags first, which for versions is roughly reverse order of name
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	var tags []*github.RepositoryTag
+	for _, name := range names {
+		commit, _ := s.resolve("refs/tags/" + name)
+		tags = append(tags, &github.RepositoryTag{Name: github.String(name), Commit: &github.Commit{SHA: github.String(commit.sha)}})
+	}
+
+	writeJSON(w, http.StatusOK, paginate(tags, r))
+}
+
+func (s *Server) getContents(w http.ResponseWriter, r *http.Request, path string) {
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		ref = DefaultBranch
+	}
+
+	commit, ok := s.resolve(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No commit found for the ref %s", ref))
+		return
+	}
+
+	contents, ok := s.trees[commit.tree][path]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &github.RepositoryContent{
+		Type:     github.String("file"),
+		Encoding: github.String("base64"),
+		Name:     github.String(path[strings.LastIndex(path, "/")+1:]),
+		Path:     github.String(path),
+		Content:  github.String(base64.StdEncoding.EncodeToString([]byte(contents))),
+		SHA:      github.String(s.hash("blob", contents)),
+	})
+}
+
+func (s *Server) createGitCommit(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Message   string               `json:"message"`
+		Tree      string               `json:"tree"`
+		Parents   []string             `json:"parents"`
+		Committer *github.CommitAuthor `json:"committer"`
+	}
+
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if _, ok := s.trees[request.Tree]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Tree SHA does not exist: %s", request.Tree))
+		return
+	}
+
+	for _, parent := range request.Parents {
+		if _, ok := s.commits[parent]; !ok {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Parent SHA does not exist or is not a commit object: %s", parent))
+			return
+		}
+	}
+
+	var date time.Time
+	if request.Committer != nil && request.Committer.Date != nil {
+		date = request.Committer.Date.Time
+	}
+
+	sha := s.createCommit(request.Message, "github-actions[bot]", date, request.Parents, request.Tree)
+	writeJSON(w, http.StatusCreated, s.gitCommit(s.commits[sha]))
+}
+
+func (s *Server) getGitCommit(w http.ResponseWriter, sha string) {
+	commit, ok := s.commits[sha]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.gitCommit(commit))
+}
+
+func (s *Server) createTree(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string  `json:"path"`
+			Content *string `json:"content"`
+			SHA     *string `json:"sha"`
+		} `json:"tree"`
+	}
+
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	base := request.BaseTree
+	if base == "" {
+		base = emptyTreeSHA
+	}
+
+	if _, ok := s.trees[base]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("base_tree %s does not exist", base))
+		return
+	}
+
+	files := make(map[string]*string)
+	for _, entry := range request.Tree {
+		if entry.SHA != nil {
+			writeError(w, http.StatusUnprocessableEntity, "githubtest only supports tree entries with content, or deleting files")
+			return
+		}
+
+		files[entry.Path] = entry.Content
+	}
+
+	sha := s.applyFiles(base, files)
+	writeJSON(w, http.StatusCreated, &github.Tree{SHA: github.String(sha)})
+}
+
+// getTree gets every file of a tree, or of a commit's tree, as if listed recursively
+func (s *Server) getTree(w http.ResponseWriter, sha string) {
+	if commit, ok := s.commits[sha]; ok {
+		sha = commit.tree
+	}
+
+	files, ok := s.trees[sha]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	tree := &github.Tree{SHA: github.String(sha), Truncated: github.Bool(false)}
+	for _, path := range sortedKeys(files) {
+		tree.Entries = append(tree.Entries, &github.TreeEntry{
+			Path: github.String(path),
+			Mode: github.String("100644"),
+			Type: github.String("blob"),
+			SHA:  github.String(s.hash("blob", files[path])),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, tree)
+}
+
+func (s *Server) getRef(w http.ResponseWriter, ref string) {
+	if _, ok := s.refs[ref]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.reference(ref))
+}
+
+func (s *Server) createRef(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	}
+
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if _, ok := s.refs[request.Ref]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference already exists")
+		return
+	}
+
+	if !s.objectExists(request.SHA) {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+
+	s.refs[request.Ref] = request.SHA
+	writeJSON(w, http.StatusCreated, s.reference(request.Ref))
+}
+
+// updateRef moves a ref, which must be a fast-forward for branches unless forced
+func (s *Server) updateRef(w http.ResponseWriter, r *http.Request, ref string) {
+	var request struct {
+		SHA   string `json:"sha"`
+		Force bool   `json:"force"`
+	}
+
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	current, ok := s.refs[ref]
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+
+	if !s.objectExists(request.SHA) {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+
+	if strings.HasPrefix(ref, "refs/heads/") && !request.Force && !s.isAncestor(current, request.SHA) {
+		writeError(w, http.StatusUnprocessableEntity, "Update is not a fast forward")
+		return
+	}
+
+	s.refs[ref] = request.SHA
+	writeJSON(w, http.StatusOK, s.reference(ref))
+}
+
+func (s *Server) deleteRef(w http.ResponseWriter, ref string) {
+	if _, ok := s.refs[ref]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+
+	delete(s.refs, ref)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) createTag(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Tag     string               `json:"tag"`
+		Message string               `json:"message"`
+		Object  string               `json:"object"`
+		Tagger  *github.CommitAuthor `json:"tagger"`
+	}
+
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if _, ok := s.commits[request.Object]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+
+	sha := s.hash("tag", request.Tag+request.Message+request.Object)
+	s.tags[sha] = &github.Tag{
+		SHA:     github.String(sha),
+		Tag:     github.String(request.Tag),
+		Message: github.String(request.Message),
+		Tagger:  request.Tagger,
+		Object:  &github.GitObject{Type: github.String("commit"), SHA: github.String(request.Object)},
+	}
+
+	writeJSON(w, http.StatusCreated, s.tags[sha])
+}
+
+// listIssues which are open, or in the state asked for, and have every label asked for
+func (s *Server) listIssues(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	if state == "" {
+		state = "open"
+	}
+
+	var labels []string
+	if r.URL.Query().Get("labels") != "" {
+		labels = strings.Split(r.URL.Query().Get("labels"), ",")
+	}
+
+	var issues []*github.Issue
+	for _, issue := range reversed(s.issues) {
+		if state != "all" && issue.GetState() != state {
+			continue
+		}
+
+		labelled := true
+		for _, label := range labels {
+			found := false
+			for _, issueLabel := range issue.Labels {
+				found = found || issueLabel.GetName() == label
+			}
+
+			labelled = labelled && found
+		}
+
+		if labelled {
+			issues = append(issues, issue)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, paginate(issues, r))
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, r *http.Request) {
+	var request github.IssueRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	number := len(s.issues) + 1
+	issue := &github.Issue{
+		Number:  github.Int(number),
+		Title:   request.Title,
+		Body:    request.Body,
+		State:   github.String("open"),
+		HTMLURL: github.String(fmt.Sprintf("https://github.com/%s/%s/issues/%d", s.owner, s.repository, number)),
+	}
+
+	for _, label := range request.GetLabels() {
+		issue.Labels = append(issue.Labels, &github.Label{Name: github.String(label)})
+	}
+
+	s.issues = append(s.issues, issue)
+	writeJSON(w, http.StatusCreated, issue)
+}
+
+// handleIssue serves an issue's endpoints, where path is the issue's number and anything after it
+func (s *Server) handleIssue(w http.ResponseWriter, r *http.Request, path string) {
+	number, rest, _ := strings.Cut(path, "/")
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 || n > len(s.issues) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	issue := s.issues[n-1]
+	switch {
+	case r.Method == http.MethodPatch && rest == "":
+		var request github.IssueRequest
+		if !readJSON(w, r, &request) {
+			return
+		}
+
+		if request.State != nil {
+			issue.State = request.State
+			issue.StateReason = request.StateReason
+		}
+
+		writeJSON(w, http.StatusOK, issue)
+	case r.Method == http.MethodGet && rest == "comments":
+		writeJSON(w, http.StatusOK, paginate(s.comments[n], r))
+	case r.Method == http.MethodPost && rest == "comments":
+		var request github.IssueComment
+		if !readJSON(w, r, &request) {
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, s.addComment(n, "github-actions[bot]", request.GetBody()))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("githubtest doesn't fake %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// addComment to an issue by a user, who is a bot if their login says so
+func (s *Server) addComment(number int, login string, body string) *github.IssueComment {
+	userType := "User"
+	if strings.HasSuffix(login, "[bot]") {
+		userType = "Bot"
+	}
+
+	comment := &github.IssueComment{
+		ID:   github.Int64(int64(len(s.comments[number]) + 1)),
+		Body: github.String(body),
+		User: &github.User{Login: github.String(login), Type: github.String(userType)},
+	}
+
+	s.comments[number] = append(s.comments[number], comment)
+	return comment
+}
+
+func (s *Server) getTag(w http.ResponseWriter, sha string) {
+	tag, ok := s.tags[sha]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+// createCommit adds a commit. An undated commit is made one minute after its newest parent.
+func (s *Server) createCommit(message string, author string, date time.Time, parents []string, tree string) string {
+	if date.IsZero() {
+		date = epoch
+		for _, parent := range parents {
+			if next := s.commits[parent].date.Add(time.Minute); next.After(date) {
+				date = next
+			}
+		}
+	}
+
+	sha := s.hash("commit", fmt.Sprintf("%s\x00%s\x00%s", message, tree, strings.Join(parents, ",")))
+	s.commits[sha] = &commit{sha: sha, message: message, author: author, date: date, parents: parents, tree: tree}
+	return sha
+}
+
+// parentTree is the tree of the first parent, or the empty tree for a root commit
+func (s *Server) parentTree(parents []string) string {
+	if len(parents) == 0 {
+		return emptyTreeSHA
+	}
+
+	return s.commits[parents[0]].tree
+}
+
+// applyFiles creates a tree from a base tree with files added, changed, or deleted where their contents are nil
+func (s *Server) applyFiles(base string, files map[string]*string) string {
+	tree := make(map[string]string)
+	for path, contents := range s.trees[base] {
+		tree[path] = contents
+	}
+
+	for path, contents := range files {
+		if contents == nil {
+			delete(tree, path)
+		} else {
+			tree[path] = *contents
+		}
+	}
+
+	var listing strings.Builder
+	for _, path := range sortedKeys(tree) {
+		listing.WriteString(fmt.Sprintf("%s\x00%s\x00", path, tree[path]))
+	}
+
+	sha := s.hash("tree", listing.String())
+	s.trees[sha] = tree
+	return sha
+}
+
+// resolve a branch name, tag name, full ref or commit SHA to a commit
+func (s *Server) resolve(ref string) (*commit, bool) {
+	for _, candidate := range []string{ref, "refs/heads/" + ref, "refs/tags/" + ref} {
+		if sha, ok := s.refs[candidate]; ok {
+			ref = sha
+			break
+		}
+	}
+
+	if tag, ok := s.tags[ref]; ok {
+		ref = tag.GetObject().GetSHA()
+	}
+
+	commit, ok := s.commits[ref]
+	return commit, ok
+}
+
+// ancestors of a commit including itself, newest first
+func (s *Server) ancestors(sha string) []*commit {
+	seen := make(map[string]bool)
+	var commits []*commit
+	pending := []string{sha}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if seen[current] {
+			continue
+		}
+
+		seen[current] = true
+		commits = append(commits, s.commits[current])
+		pending = append(pending, s.commits[current].parents...)
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].date.After(commits[j].date)
+	})
+
+	return commits
+}
+
+// isAncestor checks whether ancestor is reachable from sha
+func (s *Server) isAncestor(ancestor string, sha string) bool {
+	if _, ok := s.commits[sha]; !ok {
+		return false
+	}
+
+	for _, commit := range s.ancestors(sha) {
+		if commit.sha == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) objectExists(sha string) bool {
+	_, isCommit := s.commits[sha]
+	_, isTag := s.tags[sha]
+	return isCommit || isTag
+}
+
+func (s *Server) reference(ref string) *github.Reference {
+	objectType := "commit"
+	if _, ok := s.tags[s.refs[ref]]; ok {
+		objectType = "tag"
+	}
+
+	return &github.Reference{
+		Ref:    github.String(ref),
+		Object: &github.GitObject{Type: github.String(objectType), SHA: github.String(s.refs[ref])},
+	}
+}
+
+func (s *Server) repositoryCommit(commit *commit) *github.RepositoryCommit {
+	gitCommit := s.gitCommit(commit)
+	var parents []*github.Commit
+	for _, parent := range commit.parents {
+		parents = append(parents, &github.Commit{SHA: github.String(parent)})
+	}
+
+	return &github.RepositoryCommit{
+		SHA:       github.String(commit.sha),
+		Commit:    gitCommit,
+		Author:    &github.User{Login: github.String(commit.author)},
+		Committer: &github.User{Login: github.String(commit.author)},
+		Parents:   parents,
+		HTMLURL:   gitCommit.HTMLURL,
+	}
+}
+
+func (s *Server) gitCommit(commit *commit) *github.Commit {
+	var parents []*github.Commit
+	for _, parent := range commit.parents {
+		parents = append(parents, &github.Commit{SHA: github.String(parent)})
+	}
+
+	author := &github.CommitAuthor{Name: github.String(commit.author), Date: &github.Timestamp{Time: commit.date}}
+	return &github.Commit{
+		SHA:       github.String(commit.sha),
+		Message:   github.String(commit.message),
+		Author:    author,
+		Committer: author,
+		Tree:      &github.Tree{SHA: github.String(commit.tree)},
+		Parents:   parents,
+		HTMLURL:   github.String(fmt.Sprintf("https://github.com/%s/%s/commit/%s", s.owner, s.repository, commit.sha)),
+	}
+}
+
+func (s *Server) releaseURL(tagName string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", s.owner, s.repository, tagName)
+}
+
+// hash creates a unique SHA for a git object, so that identical objects created twice are still distinct
+func (s *Server) hash(kind string, contents string) string {
+	s.counter++
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("%s\x00%d\x00%s", kind, s.counter, contents))))
+}
+
+// paginate items with the page and per_page query parameters, the same as the GitHub API
+func paginate[T any](items []T, r *http.Request) []T {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 30
+	}
+
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return []T{}
+	}
+
+	return items[start:min(start+perPage, len(items))]
+}
+
+func reversed[T any](items []T) []T {
+	reversed := make([]T, len(items))
+	for i, item := range items {
+		reversed[len(items)-1-i] = item
+	}
+
+	return reversed
+}
+
+func sortedKeys[T any](items map[string]T) []string {
+	var keys []string
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, value any) bool {
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Problems parsing JSON: %s", err))
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		panic(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,177 @@
+package githubtest_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/ellisto/monorepo-versioning/pkg"
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+)
+
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// newAction versions a component of the fake repository at the head of its default branch
+func newAction(t *testing.T, server *githubtest.Server, component string) pkg.VersioningAction {
+	t.Helper()
+	a, err := pkg.New(
+		pkg.WithRepository("octocat/monorepo"),
+		pkg.WithClient(server.Client()),
+		pkg.WithBranch(githubtest.DefaultBranch, githubtest.DefaultBranch),
+		pkg.WithRevision(server.Head(githubtest.DefaultBranch)),
+		pkg.WithComponent(component, ""),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.WithLogger(slog.New(slog.NewTextHandler(testLogWriter{t}, nil)))
+}
+
+func TestGenerateVersions(t *testing.T) {
+	server := githubtest.NewServer("octocat", "monorepo")
+	defer server.Close()
+	released := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat: initial commit"))
+	server.AddRelease(githubtest.Release("api-1.2.3", released[0]))
+	server.AddRelease(githubtest.Release("web-0.4.1", released[0]))
+	server.AddRelease(githubtest.Release("docs-2.0.0", released[0]))
+	server.Push(githubtest.DefaultBranch,
+		githubtest.Commit("fix(api): handle empty requests"),
+		githubtest.Commit("feat(web): add a settings page"),
+		githubtest.Commit("fix(web): align the header"),
+		githubtest.Commit("feat(cli): add a login command"),
+		githubtest.Commit("chore: update the readme"),
+	)
+	head := server.Head(githubtest.DefaultBranch)
+
+	tests := []struct {
+		component string
+		bump      pkg.Bump
+		previous  string
+		version   string
+	}{
+		{component: "api", bump: pkg.BumpPatch, previous: "1.2.3", version: "1.2.4"},
+		{component: "web", bump: pkg.BumpMinor, previous: "0.4.1", version: "0.5.0"},
+		// A new component is released at the initial version
+		{component: "cli", bump: pkg.BumpNone, version: pkg.DefaultInitialVersion},
+		// No commits changed docs, so it isn't released
+		{component: "docs", bump: pkg.BumpNone, previous: "2.0.0"},
+	}
+
+	first := newAction(t, server, tests[0].component)
+	actions := make([]pkg.VersioningAction, len(tests))
+	for i, test := range tests {
+		actions[i] = first.ForComponent(test.component, "")
+	}
+
+	results := pkg.GenerateVersions(context.Background(), actions, false)
+	for i, test := range tests {
+		t.Run(test.component, func(t *testing.T) {
+			result := results[i]
+			if result.Bump != test.bump {
+				t.Errorf("Expected a %s bump, but got %s", test.bump, result.Bump)
+			}
+
+			if previous := versionOrEmpty(result.PreviousVersion); previous != test.previous {
+				t.Errorf("Expected previous version %q, but got %q", test.previous, previous)
+			}
+
+			if version := versionOrEmpty(result.Version); version != test.version {
+				t.Fatalf("Expected version %q, but got %q", test.version, version)
+			}
+
+			if test.version == "" {
+				if result.Release != nil {
+					t.Errorf("Expected no release, but got %d", result.Release.ID)
+				}
+
+				return
+			}
+
+			tagName := fmt.Sprintf("%s-%s", test.component, test.version)
+			if sha, ok := server.Tag(tagName); !ok || sha != head {
+				t.Errorf("Expected tag %s at %s, but got %q", tagName, head, sha)
+			}
+
+			if result.Release == nil || result.Release.SHA != head {
+				t.Errorf("Expected a release of %s, but got %+v", head, result.Release)
+			}
+		})
+	}
+
+	if releases := server.Releases(); len(releases) != 6 {
+		t.Errorf("Expected 3 new releases, but the repository has %d releases", len(releases))
+	}
+}
+
+func TestGenerateVersionFindsReleasesOnLaterPages(t *testing.T) {
+	server := githubtest.NewServer("octocat", "monorepo")
+	defer server.Close()
+	released := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+	// Releases are listed newest first, 100 to a page, so api's release is on the second page
+	server.AddRelease(githubtest.Release("api-2.1.0", released[0]))
+	for patch := 0; patch < 120; patch++ {
+		server.AddRelease(githubtest.Release(fmt.Sprintf("web-1.0.%d", patch), released[0]))
+	}
+
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("fix(api): handle timeouts"))
+	result := newAction(t, server, "api").GenerateVersion(context.Background(), false)
+	if previous := versionOrEmpty(result.PreviousVersion); previous != "2.1.0" {
+		t.Errorf("Expected previous version 2.1.0 from the second page of releases, but got %q", previous)
+	}
+
+	if version := versionOrEmpty(result.Version); version != "2.1.1" {
+		t.Errorf("Expected version 2.1.1, but got %q", version)
+	}
+
+	pages := 0
+	for _, request := range server.Requests() {
+		if request == "GET /repos/octocat/monorepo/releases" {
+			pages++
+		}
+	}
+
+	if pages < 2 {
+		t.Errorf("Expected the releases to be listed in several pages, but they were listed in %d", pages)
+	}
+}
+
+func TestGenerateVersionDryRunChangesNothing(t *testing.T) {
+	server := githubtest.NewServer("octocat", "monorepo")
+	defer server.Close()
+	released := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+	server.AddRelease(githubtest.Release("api-1.0.0", released[0]))
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api)!: remove the v1 endpoints"))
+
+	result := newAction(t, server, "api").GenerateVersion(context.Background(), true)
+	if version := versionOrEmpty(result.Version); version != "2.0.0" || result.Bump != pkg.BumpMajor {
+		t.Errorf("Expected a major bump to 2.0.0, but got a %s bump to %q", result.Bump, version)
+	}
+
+	if releases := server.Releases(); len(releases) != 1 {
+		t.Errorf("Expected a dry run not to release anything, but the repository has %d releases", len(releases))
+	}
+
+	if _, ok := server.Tag("api-2.0.0"); ok {
+		t.Error("Expected a dry run not to tag anything")
+	}
+}
+
+func versionOrEmpty(version *semver.Version) string {
+	if version == nil {
+		return ""
+	}
+
+	return version.String()
+}
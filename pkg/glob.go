@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"regexp"
+	"strings"
+)
+
+// compileGlob converts a glob pattern into a regex matching whole paths. "**" matches any number of directories,
+// "*" matches anything except "/", and "?" matches a single character except "/", eg: "**/*.md" matches
+// "README.md" and "docs/guide/setup.md".
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	expression := strings.Builder{}
+	expression.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expression.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expression.WriteString("[^/]")
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	expression.WriteString("$")
+	return regexp.Compile(expression.String())
+}
+
+// matchesAnyGlob checks whether a path matches any of the glob patterns, which must be valid
+func matchesAnyGlob(patterns []string, filePath string) bool {
+	for _, pattern := range patterns {
+		compiled, err := compileGlob(pattern)
+		if err != nil {
+			panic(err)
+		}
+
+		if compiled.MatchString(filePath) {
+			return true
+		}
+	}
+
+	return false
+}
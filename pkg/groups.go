@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ellisto/monorepo-versioning/pkg/policy"
+	"github.com/google/go-github/v50/github"
+	"github.com/leodido/go-conventionalcommits"
+)
+
+// componentGroup is a named group of components which are always released together, by the highest bump of any
+// member
+type componentGroup struct {
+	name    string
+	members []string
+	bump    Bump
+}
+
+// validateGroups checks that each group has at least two members, and that no component is in more than one group
+func validateGroups(groups map[string][]string) error {
+	grouped := make(map[string]string)
+	for _, name := range sortedGroupNames(groups) {
+		if len(groups[name]) < 2 {
+			return fmt.Errorf("group %s needs at least two components", name)
+		}
+
+		for _, member := range groups[name] {
+			if other, ok := grouped[member]; ok {
+				return fmt.Errorf("component %s is in groups %s and %s, but can only be in one", member, other, name)
+			}
+
+			grouped[member] = name
+		}
+	}
+
+	return nil
+}
+
+func sortedGroupNames(groups map[string][]string) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// groupVersions plans the versions of the actions' components which are in groups, and releases every member of a
+// group by the highest bump of any member, if any member would be released. Every member of a group with members in
+// the run must be versioned in the run. Returns the actions, with each grouped component's group set.
+func groupVersions(ctx context.Context, actions []VersioningAction) []VersioningAction {
+	groups := actions[0].config.Groups
+	inRun := make(map[string]int)
+	for i, a := range actions {
+		inRun[a.component] = i
+	}
+
+	grouped := make([]VersioningAction, len(actions))
+	copy(grouped, actions)
+	for _, name := range sortedGroupNames(groups) {
+		members := groups[name]
+		var missing []string
+		var memberActions []VersioningAction
+		for _, member := range members {
+			if index, ok := inRun[member]; ok {
+				memberActions = append(memberActions, actions[index])
+			} else {
+				missing = append(missing, member)
+			}
+		}
+
+		if len(memberActions) == 0 {
+			continue
+		}
+
+		if len(missing) > 0 {
+			panic(fmt.Sprintf("Components of group %s are always released together, but the run doesn't version %s. Version every component of the group: %s",
+				name, strings.Join(missing, ", "), strings.Join(members, ", ")))
+		}
+
+		planned := forEachComponent(memberActions, func(a VersioningAction) Result {
+			a.logger.Info("Planning version to release its group together", "component", a.component, "group", name)
+			return a.GenerateVersion(ctx, true)
+		})
+
+		bump := BumpNone
+		for _, result := range planned {
+			bump = policy.Highest(bump, result.Bump)
+			if result.Version != nil {
+				// A member's first version still releases the rest of the group
+				bump = policy.Highest(bump, BumpPatch)
+			}
+		}
+
+		if bump == BumpNone {
+			continue
+		}
+
+		actions[0].logger.Info(fmt.Sprintf("Releasing group %s together with a %s bump", name, bump), "group", name)
+		for _, member := range members {
+			grouped[inRun[member]].group = &componentGroup{name: name, members: members, bump: bump}
+		}
+	}
+
+	return grouped
+}
+
+// groupBump is the bump of the component's group, or none if it isn't released with a group
+func (a VersioningAction) groupBump() Bump {
+	if a.group == nil {
+		return BumpNone
+	}
+
+	return a.group.bump
+}
+
+// groupMemberChange finds which other member of the component's group a commit changes, so that the release notes
+// of each member list the changes of the whole group. Returns false if the commit doesn't change another member.
+func (a VersioningAction) groupMemberChange(ctx context.Context, commit *github.RepositoryCommit) (string, *conventionalcommits.ConventionalCommit, bool) {
+	if a.group == nil {
+		return "", nil, false
+	}
+
+	for _, member := range a.group.members {
+		if member == a.component {
+			continue
+		}
+
+		m := a.ForComponent(member, "")
+		conventionalCommit, err := m.parseCommitForComponent(ctx, commit)
+		if err != nil {
+			continue
+		}
+
+		if included, _ := m.includesCommit(ctx, commit, conventionalCommit); included {
+			return member, conventionalCommit, true
+		}
+	}
+
+	return "", nil, false
+}
@@ -0,0 +1,229 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// ComponentHelm is the type of components which are Helm charts, whose Chart.yaml version and appVersion are
+// updated with each new version
+const ComponentHelm = "helm"
+
+// chartVersionFile updates both the chart's version and appVersion, so that the chart and the application it
+// deploys are released together
+var chartVersionFile = VersionFile{Path: "Chart.yaml", Regex: `(?m)^(?:version|appVersion):\s*["']?([^"'\s]+)`}
+
+const (
+	// helmConfigMediaType is the media type of a chart's metadata in an OCI registry
+	helmConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+	// helmChartMediaType is the media type of a packaged chart in an OCI registry
+	helmChartMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+)
+
+// RegistryCredentials authenticate pushes to a container registry
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// WithChartRegistryCredentials authenticates pushes of Helm charts to their components' chart-registry
+func (a VersioningAction) WithChartRegistryCredentials(credentials RegistryCredentials) VersioningAction {
+	a.chartRegistryCredentials = credentials
+	return a
+}
+
+// pushChart packages the component's Helm chart at the new version and pushes it to the component's chart
+// registry, if it has one. Returns the chart's reference, eg: "oci://ghcr.io/owner/charts/api:1.2.3", or an empty
+// string if it has no registry. The chart is packaged from the checked out component directory, with Chart.yaml
+// updated to the new version.
+func (a VersioningAction) pushChart(ctx context.Context, version *semver.Version) string {
+	config := a.componentConfig()
+	if config.ChartRegistry == "" {
+		return ""
+	}
+
+	dir := config.Path
+	if dir == "" {
+		dir = "."
+	}
+
+	chartYAML, err := os.ReadFile(filepath.Join(dir, chartVersionFile.Path))
+	if err != nil {
+		panic(fmt.Sprintf("Could not read the Helm chart of %s: %s", a.component, err))
+	}
+
+	chartYAML, err = chartVersionFile.update(chartYAML, version.String())
+	if err != nil {
+		panic(err)
+	}
+
+	var metadata map[string]any
+	if err := yaml.Unmarshal(chartYAML, &metadata); err != nil {
+		panic(fmt.Sprintf("Could not read the Helm chart of %s: %s", a.component, err))
+	}
+
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		panic(fmt.Sprintf("The Helm chart of %s has no name", a.component))
+	}
+
+	chart, err := packageChart(dir, name, chartYAML)
+	if err != nil {
+		panic(fmt.Sprintf("Could not package the Helm chart of %s: %s", a.component, err))
+	}
+
+	chartConfig, err := json.Marshal(metadata)
+	if err != nil {
+		panic(err)
+	}
+
+	// Helm replaces "+" in versions, which isn't allowed in OCI tags, with "_"
+	tag := strings.ReplaceAll(version.String(), "+", "_")
+	registry, namespace, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(config.ChartRegistry, "oci://"), "/"), "/")
+	repository := path.Join(namespace, name)
+	reference := fmt.Sprintf("oci://%s/%s:%s", registry, repository, tag)
+	a.logger.Info("Pushing Helm chart", "component", a.component, "chart", reference)
+	pusher := newOCIPusher(registry, repository, a.chartRegistryCredentials)
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	err = pusher.push(requestCtx, tag, ociArtifact{
+		configMediaType: helmConfigMediaType,
+		config:          chartConfig,
+		layerMediaType:  helmChartMediaType,
+		layer:           chart,
+		annotations: map[string]string{
+			"org.opencontainers.image.title":   name,
+			"org.opencontainers.image.version": version.String(),
+		},
+	})
+
+	if err != nil {
+		panic(fmt.Sprintf("Could not push the Helm chart of %s to %s: %s", a.component, config.ChartRegistry, err))
+	}
+
+	return reference
+}
+
+// packageChart archives a chart directory like "helm package", with its files under a directory named after the
+// chart and Chart.yaml replaced. Files matching the chart's .helmignore are left out.
+func packageChart(dir string, name string, chartYAML []byte) ([]byte, error) {
+	ignored, err := readHelmIgnore(filepath.Join(dir, ".helmignore"))
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	compressed := gzip.NewWriter(&archive)
+	writer := tar.NewWriter(compressed)
+	err = filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, _ := filepath.Rel(dir, filePath)
+		relative = filepath.ToSlash(relative)
+		if relative == "." {
+			return nil
+		}
+
+		if entry.Name() == ".git" || helmIgnored(ignored, relative, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		contents, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		if relative == chartVersionFile.Path {
+			contents = chartYAML
+		}
+
+		header := &tar.Header{Name: path.Join(name, relative), Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+
+		_, err = writer.Write(contents)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := compressed.Close(); err != nil {
+		return nil, err
+	}
+
+	return archive.Bytes(), nil
+}
+
+// readHelmIgnore reads the patterns of a .helmignore file, if the chart has one
+func readHelmIgnore(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+
+	return patterns, scanner.Err()
+}
+
+// helmIgnored checks whether a file or directory of a chart matches a .helmignore pattern. Patterns match either
+// the whole relative path or the file's name, and patterns ending with "/" only match directories.
+func helmIgnored(patterns []string, relative string, isDir bool) bool {
+	for _, pattern := range patterns {
+		pattern, dirOnly := strings.CutSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, relative); matched {
+			return true
+		}
+
+		if matched, _ := path.Match(pattern, path.Base(relative)); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// repositoryHistory caches the releases and commits fetched from GitHub, so that they can be shared between
+// all of the components versioned in a single run instead of being fetched again for each component.
+type repositoryHistory struct {
+	// Guards the history, as components can be versioned concurrently
+	mu             sync.Mutex
+	releases       []*github.RepositoryRelease
+	releasesListed bool
+	tags           []*github.RepositoryTag
+	tagsListed     bool
+	// Commits tags point at, keyed by tag reference
+	tagCommits map[string]changePoint
+	commits    *commitRange
+	// Paths of the files changed by each commit, keyed by commit SHA
+	commitFiles map[string][]string
+	// Version of GitHub Enterprise Server, or nil for GitHub.com
+	serverVersion        *semver.Version
+	serverVersionChecked bool
+	// Active rulesets of the repository, or nil if they can't be read
+	rulesets       []ruleset
+	rulesetsListed bool
+	// Serialises pushing commits, so that components released concurrently don't race to update the same branch
+	pushes sync.Mutex
+	// Serialises parsing commit messages, as the parser keeps the state of the message it's parsing
+	parsing sync.Mutex
+}
+
+// changePoint is a commit which later commits are listed since, eg: the commit a component was last released at
+type changePoint struct {
+	sha  string
+	time time.Time
+}
+
+// commitRange is the list of commits reachable from a head commit, made on or after a point in time
+type commitRange struct {
+	head    string
+	since   time.Time
+	commits []*github.RepositoryCommit
+}
+
+func newRepositoryHistory() *repositoryHistory {
+	return &repositoryHistory{
+		tagCommits:  make(map[string]changePoint),
+		commitFiles: make(map[string][]string),
+	}
+}
+
+// covers checks whether the range contains every commit reachable from head made on or after since
+func (r *commitRange) covers(head string, since time.Time) bool {
+	return r != nil && r.head == head && !r.since.After(since)
+}
+
+// slice the range to the commits made since a previous change, leaving out the previous change's commit and its
+// ancestors. Commits are told apart by SHA rather than time, as several commits can be made in the same second.
+// If the previous change's commit isn't in the range, eg: it was released from another branch, only the commits
+// made after it are kept.
+func (r *commitRange) slice(since *changePoint) []*github.RepositoryCommit {
+	if since == nil {
+		return r.commits
+	}
+
+	previous := r.reachable(since.sha)
+	var commits []*github.RepositoryCommit
+	for _, commit := range r.commits {
+		commitTime := commit.GetCommit().GetCommitter().GetDate().Time
+		if previous[commit.GetSHA()] || commitTime.Before(since.time) {
+			continue
+		}
+
+		if len(previous) == 0 && commitTime.Equal(since.time) {
+			// Without the previous change's commit, commits made in the same second can't be told apart from it
+			continue
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits
+}
+
+// reachable finds the commits in the range which are reachable from a commit, including itself
+func (r *commitRange) reachable(sha string) map[string]bool {
+	bySHA := make(map[string]*github.RepositoryCommit)
+	for _, commit := range r.commits {
+		bySHA[commit.GetSHA()] = commit
+	}
+
+	reachable := make(map[string]bool)
+	pending := []string{sha}
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		commit, ok := bySHA[current]
+		if !ok || reachable[current] {
+			continue
+		}
+
+		reachable[current] = true
+		for _, parent := range commit.Parents {
+			pending = append(pending, parent.GetSHA())
+		}
+	}
+
+	return reachable
+}
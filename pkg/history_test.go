@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+	"github.com/google/go-github/v50/github"
+)
+
+func testCommit(sha string, committed time.Time, parents ...string) *github.RepositoryCommit {
+	commit := &github.RepositoryCommit{
+		SHA: github.String(sha),
+		Commit: &github.Commit{
+			Committer: &github.CommitAuthor{Date: &github.Timestamp{Time: committed}},
+		},
+	}
+
+	for _, parent := range parents {
+		commit.Parents = append(commit.Parents, &github.Commit{SHA: github.String(parent)})
+	}
+
+	return commit
+}
+
+func commitSHAs(commits []*github.RepositoryCommit) []string {
+	var shas []string
+	for _, commit := range commits {
+		shas = append(shas, commit.GetSHA())
+	}
+
+	return shas
+}
+
+func TestCommitRangeSlice(t *testing.T) {
+	released := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	// Newest first, like the commits API. b is released, and a to d were all made in the same second.
+	r := &commitRange{
+		head: "e",
+		commits: []*github.RepositoryCommit{
+			testCommit("e", released.Add(time.Second), "d"),
+			testCommit("d", released, "c"),
+			testCommit("c", released, "b"),
+			testCommit("b", released, "a"),
+			testCommit("a", released, "root"),
+			testCommit("root", released.Add(-time.Hour)),
+		},
+	}
+
+	tests := []struct {
+		name  string
+		since *changePoint
+		shas  []string
+	}{
+		{name: "never released", shas: []string{"e", "d", "c", "b", "a", "root"}},
+		{name: "released in the same second as other commits", since: &changePoint{sha: "b", time: released}, shas: []string{"e", "d", "c"}},
+		{name: "released at the head", since: &changePoint{sha: "e", time: released.Add(time.Second)}},
+		// Commits in the same second can't be told apart from a release of a commit which isn't in the range
+		{name: "released from another branch", since: &changePoint{sha: "other", time: released}, shas: []string{"e"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if shas := commitSHAs(r.slice(test.since)); !slices.Equal(shas, test.shas) {
+				t.Errorf("Expected commits %v, but got %v", test.shas, shas)
+			}
+		})
+	}
+}
+
+func TestGenerateVersionsWithCommitsInTheSameSecondAsTheRelease(t *testing.T) {
+	server := newTestServer(t)
+	released := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	shas := server.Push(githubtest.DefaultBranch,
+		githubtest.Commit("feat(api): add users").At(released),
+		githubtest.Commit("fix(api): validate names").At(released),
+		githubtest.Commit("fix(api): handle timeouts").At(released),
+		githubtest.Commit("fix(web): align the header").At(released),
+		githubtest.Commit("feat(api): add search").At(released.Add(time.Second)),
+	)
+	server.AddRelease(githubtest.Release("api-1.0.0", shas[1]))
+
+	// web has never been released, so the range both components share starts at the first commit
+	api := newTestAction(t, server, "api")
+	results := GenerateVersions(context.Background(), []VersioningAction{api, api.ForComponent("web", "")}, true)
+
+	var apiSHAs []string
+	for _, decision := range results[0].Commits {
+		apiSHAs = append(apiSHAs, decision.SHA)
+	}
+
+	slices.Sort(apiSHAs)
+	expected := append([]string{}, shas[2:]...)
+	slices.Sort(expected)
+	if !slices.Equal(apiSHAs, expected) {
+		t.Errorf("Expected api's commits to be the 3 after its release, each once, but got %v", apiSHAs)
+	}
+
+	if version := results[0].Version.String(); version != "1.1.0" || results[0].IncludedCommits() != 2 {
+		t.Errorf("Expected api 1.1.0 from 2 commits, but got %s from %d", version, results[0].IncludedCommits())
+	}
+
+	if commits := len(results[1].Commits); commits != len(shas) {
+		t.Errorf("Expected web's first version to be decided from all %d commits, but got %d", len(shas), commits)
+	}
+}
@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Points of each component's versioning which hooks can be run at
+const (
+	// HookPreVersion runs once the previous version is found, before the next version is generated, including in
+	// dry runs
+	HookPreVersion = "pre-version"
+	// HookPreRelease runs once the version files are committed, before the release is created. Failing stops the
+	// release.
+	HookPreRelease = "pre-release"
+	// HookPostRelease runs once the release is published, after it's announced
+	HookPostRelease = "post-release"
+)
+
+// HooksConfig configures the executables run at each hook point, in order
+type HooksConfig struct {
+	PreVersion  []Hook `yaml:"pre-version,omitempty"`
+	PreRelease  []Hook `yaml:"pre-release,omitempty"`
+	PostRelease []Hook `yaml:"post-release,omitempty"`
+}
+
+// Hook is an executable run with the versioning context as JSON on its standard input. It's run from the current
+// directory, which is usually the checked out repository, without a shell, as the action's image doesn't have one.
+type Hook struct {
+	// Command is the executable and its arguments, eg: ["./scripts/publish", "--registry", "internal"]
+	Command []string `yaml:"command,omitempty"`
+}
+
+// hookPayload is the versioning context passed to hooks. The version and release aren't known by every hook
+// point, so are left out until they are.
+type hookPayload struct {
+	Hook            string   `json:"hook"`
+	Repository      string   `json:"repository"`
+	Component       string   `json:"component"`
+	Branch          string   `json:"branch"`
+	Revision        string   `json:"revision"`
+	DryRun          bool     `json:"dryRun"`
+	PreviousVersion string   `json:"previousVersion,omitempty"`
+	Version         string   `json:"version,omitempty"`
+	Tag             string   `json:"tag,omitempty"`
+	Bump            Bump     `json:"bump,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
+	Release         *Release `json:"release,omitempty"`
+}
+
+// hooks configured for a hook point
+func (c HooksConfig) hooks(point string) []Hook {
+	switch point {
+	case HookPreVersion:
+		return c.PreVersion
+	case HookPreRelease:
+		return c.PreRelease
+	case HookPostRelease:
+		return c.PostRelease
+	}
+
+	panic(fmt.Sprintf("Unknown hook point %q", point))
+}
+
+// runHooks configured for a hook point with what's known about the result so far. A hook failing panics, so that
+// the run fails before anything else is done.
+func (a VersioningAction) runHooks(ctx context.Context, point string, result Result, dryRun bool, notes string) {
+	hooks := a.config.Hooks.hooks(point)
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload := hookPayload{
+		Hook:       point,
+		Repository: fmt.Sprintf("%s/%s", a.owner, a.repository),
+		Component:  a.component,
+		Branch:     a.branch,
+		Revision:   a.revision,
+		DryRun:     dryRun,
+		Tag:        result.TagName(),
+		Bump:       result.Bump,
+		Notes:      notes,
+		Release:    result.Release,
+	}
+
+	if result.PreviousVersion != nil {
+		payload.PreviousVersion = result.PreviousVersion.String()
+	}
+
+	if result.Version != nil {
+		payload.Version = result.Version.String()
+	}
+
+	input, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, hook := range hooks {
+		a.logger.Info("Running hook", "component", a.component, "hook", point, "command", strings.Join(hook.Command, " "))
+		command := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+		command.Stdin = bytes.NewReader(input)
+		output, err := command.CombinedOutput()
+		if len(output) > 0 {
+			a.logger.Info(strings.TrimRight(string(output), "\n"), "component", a.component, "hook", point)
+		}
+
+		if err != nil {
+			panic(fmt.Sprintf("The %s hook %q failed for %s: %s", point, hook.Command[0], a.component, err))
+		}
+	}
+}
+
+// validate the hooks of every hook point
+func (c HooksConfig) validate() error {
+	for _, point := range []string{HookPreVersion, HookPreRelease, HookPostRelease} {
+		for _, hook := range c.hooks(point) {
+			if len(hook.Command) == 0 || hook.Command[0] == "" {
+				return fmt.Errorf("%s hook has no command", point)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package pkg
+
+import "github.com/ellisto/monorepo-versioning/pkg/changelog"
+
+// DefaultLocale is the language release notes are written in unless another locale is selected
+const DefaultLocale = changelog.DefaultLocale
+
+// Locales release notes can be written in, sorted by name
+func Locales() []string {
+	return changelog.Locales()
+}
+
+// WithLocale writes release notes in the language of a locale, eg: "de". Defaults to DefaultLocale.
+func (a VersioningAction) WithLocale(locale string) VersioningAction {
+	a.locale = locale
+	return a
+}
+
+// messages of release notes in the action's locale, falling back to the default locale
+func (a VersioningAction) messages() changelog.Messages {
+	return changelog.MessagesFor(a.locale)
+}
@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Log formats supported by NewLogger
+const (
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+	LogFormatGitHub = "github"
+)
+
+// NewLogger creates a structured logger writing to w in the given format. The "github" format writes
+// debug, warning and error records as GitHub Actions workflow commands, so that they are collapsed or
+// highlighted in the workflow log.
+func NewLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case LogFormatText:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case LogFormatGitHub:
+		return slog.New(&githubHandler{w: w, level: level, mu: &sync.Mutex{}}), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected one of: %s, %s, %s", format, LogFormatText, LogFormatJSON, LogFormatGitHub)
+	}
+}
+
+// ParseLogLevel parses a log level name such as "debug" or "info"
+func ParseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf("unknown log level %q, expected one of: debug, info, warn, error", name)
+	}
+
+	return level, nil
+}
+
+// githubHandler formats log records as GitHub Actions workflow commands. See:
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+type githubHandler struct {
+	w      io.Writer
+	level  slog.Level
+	attrs  []slog.Attr
+	prefix string
+	mu     *sync.Mutex
+}
+
+func (h *githubHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *githubHandler) Handle(_ context.Context, record slog.Record) error {
+	line := strings.Builder{}
+	switch {
+	case record.Level >= slog.LevelError:
+		line.WriteString("::error::")
+	case record.Level >= slog.LevelWarn:
+		line.WriteString("::warning::")
+	case record.Level < slog.LevelInfo:
+		line.WriteString("::debug::")
+	}
+
+	line.WriteString(record.Message)
+	for _, attr := range h.attrs {
+		writeGitHubAttr(&line, "", attr)
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		writeGitHubAttr(&line, h.prefix, attr)
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintln(h.w, escapeWorkflowCommand(line.String()))
+	return err
+}
+
+func (h *githubHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	handler.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		handler.attrs = append(handler.attrs, attr)
+	}
+
+	return &handler
+}
+
+func (h *githubHandler) WithGroup(name string) slog.Handler {
+	handler := *h
+	handler.prefix = h.prefix + name + "."
+	return &handler
+}
+
+// writeGitHubAttr writes an attribute as key=value, flattening groups into dotted keys
+func writeGitHubAttr(line *strings.Builder, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		for _, groupAttr := range value.Group() {
+			writeGitHubAttr(line, prefix+attr.Key+".", groupAttr)
+		}
+
+		return
+	}
+
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	str := value.String()
+	if strings.ContainsAny(str, " =\"") {
+		str = strconv.Quote(str)
+	}
+
+	line.WriteString(fmt.Sprintf(" %s%s=%s", prefix, attr.Key, str))
+}
+
+// escapeWorkflowCommand escapes the characters which would otherwise end a workflow command early
+func escapeWorkflowCommand(str string) string {
+	str = strings.ReplaceAll(str, "%", "%25")
+	str = strings.ReplaceAll(str, "\r", "%0D")
+	return strings.ReplaceAll(str, "\n", "%0A")
+}
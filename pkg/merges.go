@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// Ways of handling merge commits in the commit range
+const (
+	// Use every commit in the range, including both merge commits and the commits they merged
+	MergeCommitsAll = "all"
+	// Only use the commits on the first-parent chain of the branch, so each merged pull request counts once. Merge
+	// commits are parsed by the pull request title in their message, eg: "Merge pull request #1 from x/y" followed
+	// by "feat(api): add users".
+	MergeCommitsFirstParent = "first-parent"
+	// Skip merge commits, and only use the commits they merged
+	MergeCommitsExpand = "expand"
+)
+
+// applyMergeCommitPolicy filters the commits in the range according to how merge commits are configured to be
+// handled. Commits must be in descending order, as listed by GitHub.
+func (a VersioningAction) applyMergeCommitPolicy(commits []*github.RepositoryCommit) []*github.RepositoryCommit {
+	switch a.config.MergeCommits {
+	case MergeCommitsFirstParent:
+		return firstParentCommits(commits, a.revision)
+	case MergeCommitsExpand:
+		var expanded []*github.RepositoryCommit
+		for _, commit := range commits {
+			if len(commit.Parents) < 2 {
+				expanded = append(expanded, commit)
+			}
+		}
+
+		return expanded
+	default:
+		return commits
+	}
+}
+
+// firstParentCommits follows the first parent of each commit from head, or the newest commit if head isn't in the
+// range. The messages of merge commits are replaced by the pull request title and description they contain.
+func firstParentCommits(commits []*github.RepositoryCommit, head string) []*github.RepositoryCommit {
+	if len(commits) == 0 {
+		return nil
+	}
+
+	bySHA := make(map[string]*github.RepositoryCommit)
+	for _, commit := range commits {
+		bySHA[commit.GetSHA()] = commit
+	}
+
+	if _, ok := bySHA[head]; !ok {
+		head = commits[0].GetSHA()
+	}
+
+	var firstParent []*github.RepositoryCommit
+	for commit, ok := bySHA[head]; ok; commit, ok = bySHA[commit.Parents[0].GetSHA()] {
+		if len(commit.Parents) > 1 {
+			commit = withMergedMessage(commit)
+		}
+
+		firstParent = append(firstParent, commit)
+		if len(commit.Parents) == 0 {
+			break
+		}
+	}
+
+	return firstParent
+}
+
+// withMergedMessage copies a merge commit, replacing its message with the merged pull request's title and
+// description, which GitHub adds after the first line of the message. Merge commits without a description keep
+// their message.
+func withMergedMessage(commit *github.RepositoryCommit) *github.RepositoryCommit {
+	_, merged, ok := strings.Cut(commit.GetCommit().GetMessage(), "\n\n")
+	if !ok || strings.TrimSpace(merged) == "" {
+		return commit
+	}
+
+	copied := *commit
+	message := *commit.GetCommit()
+	message.Message = github.String(strings.TrimSpace(merged))
+	copied.Commit = &message
+	return &copied
+}
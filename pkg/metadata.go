@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// metadataMarker starts the hidden block of release notes which records the release's metadata
+const metadataMarker = "<!-- monorepo-versioning:metadata"
+
+// metadataBlock matches the hidden metadata block of release notes, capturing its JSON
+var metadataBlock = regexp.MustCompile(`(?s)\s*` + regexp.QuoteMeta(metadataMarker) + `\s*(\{.*?\})\s*-->\s*`)
+
+// ReleaseMetadata is a machine-readable record of a release, embedded in its release notes as an HTML comment so
+// that it isn't rendered. It identifies the component and version of the release whatever its tag is named.
+type ReleaseMetadata struct {
+	Component string `json:"component"`
+	Version   string `json:"version"`
+	// SHA of the commit released
+	SHA string `json:"sha"`
+	// BaseSHA is the commit of the previous release the version was based on, or empty for the first release
+	BaseSHA string `json:"baseSha,omitempty"`
+	Bump    Bump   `json:"bump"`
+	// ActionVersion is the version of the action which created the release
+	ActionVersion string `json:"actionVersion,omitempty"`
+}
+
+// ParseReleaseMetadata reads the metadata embedded in the notes of a release. Returns false if the release has no
+// metadata, eg: because it was created before metadata was recorded.
+func ParseReleaseMetadata(body string) (ReleaseMetadata, bool) {
+	var metadata ReleaseMetadata
+	matches := metadataBlock.FindStringSubmatch(body)
+	if matches == nil {
+		return metadata, false
+	}
+
+	if err := json.Unmarshal([]byte(matches[1]), &metadata); err != nil || metadata.Component == "" || metadata.Version == "" {
+		return metadata, false
+	}
+
+	return metadata, true
+}
+
+// block of release notes embedding the metadata
+func (m ReleaseMetadata) block() string {
+	contents, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("\n\n%s\n%s\n-->\n", metadataMarker, contents)
+}
+
+// stripReleaseMetadata removes the metadata block from release notes, so that they can be shown elsewhere
+func stripReleaseMetadata(body string) string {
+	return metadataBlock.ReplaceAllString(body, "\n")
+}
+
+// WithActionVersion records the version of the action in the metadata of each release, eg: "v2"
+func (a VersioningAction) WithActionVersion(version string) VersioningAction {
+	a.actionVersion = version
+	return a
+}
+
+// releaseMetadata for a new version of the component released at the current revision
+func (a VersioningAction) releaseMetadata(ctx context.Context, version *semver.Version, bump Bump, existingReleases []*github.RepositoryRelease) ReleaseMetadata {
+	metadata := ReleaseMetadata{
+		Component:     a.component,
+		Version:       version.String(),
+		SHA:           a.revision,
+		Bump:          bump,
+		ActionVersion: a.actionVersion,
+	}
+
+	if len(existingReleases) > 0 {
+		previous := existingReleases[0]
+		if previousMetadata, ok := ParseReleaseMetadata(previous.GetBody()); ok && previousMetadata.SHA != "" {
+			metadata.BaseSHA = previousMetadata.SHA
+		} else {
+			metadata.BaseSHA = a.getTagCommitSHA(ctx, previous.GetTagName())
+		}
+	}
+
+	return metadata
+}
+
+// releaseVersion is the version of a release of the component, or nil if the release isn't of the component. The
+// release's metadata is preferred over its tag name, so that releases are still found after the tag template
+// changes.
+func (a VersioningAction) releaseVersion(release *github.RepositoryRelease) *semver.Version {
+	if metadata, ok := ParseReleaseMetadata(release.GetBody()); ok {
+		version, err := semver.NewVersion(metadata.Version)
+		if err != nil || !strings.EqualFold(metadata.Component, a.component) {
+			return nil
+		}
+
+		return version
+	}
+
+	// Tags are matched case-insensitively, so that they're found whichever case they were created in
+	prefix := strings.ToLower(a.tagPrefix())
+	pattern := regexp.MustCompile(fmt.Sprintf(`^%s[0-9\.]+(-.+)?$`, regexp.QuoteMeta(prefix)))
+	tagName := strings.ToLower(release.GetTagName())
+	if !pattern.MatchString(tagName) {
+		return nil
+	}
+
+	version, err := semver.NewVersion(strings.TrimPrefix(tagName, prefix))
+	if err != nil {
+		return nil
+	}
+
+	return version
+}
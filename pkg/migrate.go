@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// migrateTagPattern compiles a tag pattern such as "v{version}" or "{component}_{version}" into a regex whose
+// first capture group is the version
+func migrateTagPattern(pattern string, component string) (*regexp.Regexp, error) {
+	if !strings.Contains(pattern, "{version}") {
+		return nil, fmt.Errorf("tag pattern %q has no {version} placeholder", pattern)
+	}
+
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.ReplaceAll(expression, regexp.QuoteMeta("{component}"), regexp.QuoteMeta(component))
+	expression = strings.Replace(expression, regexp.QuoteMeta("{version}"), `(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)`, 1)
+	return regexp.Compile(fmt.Sprintf("(?i)^%s$", expression))
+}
+
+// Migrate imports the component's versions from an existing tagging scheme, so that a repository adopting the
+// action carries on from its existing versions instead of starting every component at the initial version. Each
+// tag matching the pattern, eg: "v{version}" or "{component}_{version}", is released under the action's tag
+// name at the same commit. Versions which are already released are left alone, so a migration can be re-run.
+// If dryRun is true, the releases are only logged. The created tags are returned.
+func (a VersioningAction) Migrate(ctx context.Context, pattern string, dryRun bool) []string {
+	tagPattern, err := migrateTagPattern(pattern, a.component)
+	if err != nil {
+		panic(err)
+	}
+
+	released := make(map[string]bool)
+	for _, release := range a.getAllReleases(ctx) {
+		released[strings.ToLower(release.GetTagName())] = true
+	}
+
+	var migratedTags []string
+	for _, tag := range a.getAllTags(ctx) {
+		match := tagPattern.FindStringSubmatch(tag.GetName())
+		if match == nil {
+			continue
+		}
+
+		version := semver.MustParse(match[1])
+		tagName := a.tagName(version.String())
+		if released[strings.ToLower(tagName)] {
+			a.logger.Debug("Version is already released, so not migrating it", "component", a.component, "from", tag.GetName(), "tag", tagName)
+			continue
+		}
+
+		migratedTags = append(migratedTags, tagName)
+		if dryRun {
+			a.logger.Info("Would migrate tag, but this is a dry run", "component", a.component, "from", tag.GetName(), "tag", tagName)
+			continue
+		}
+
+		a.logger.Info("Migrating tag", "component", a.component, "from", tag.GetName(), "tag", tagName)
+		a.createMigratedRelease(ctx, tag, tagName, version)
+	}
+
+	if len(migratedTags) == 0 {
+		a.logger.Warn("No tags to migrate", "component", a.component, "pattern", pattern)
+	}
+
+	return migratedTags
+}
+
+// createMigratedRelease releases a version under the action's tag name at the commit of the existing tag
+func (a VersioningAction) createMigratedRelease(ctx context.Context, tag *github.RepositoryTag, tagName string, version *semver.Version) {
+	releaseTitle := a.releaseTitle(version)
+	metadata := ReleaseMetadata{
+		Component:     a.component,
+		Version:       version.String(),
+		SHA:           tag.GetCommit().GetSHA(),
+		Bump:          BumpNone,
+		ActionVersion: a.actionVersion,
+	}
+	releaseNotes := fmt.Sprintf(a.messages().MigratedFrom, tag.GetName()) + metadata.block()
+	isPrerelease := version.Prerelease() != ""
+
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	_, _, err := a.client.Repositories.CreateRelease(requestCtx, a.owner, a.repository, a.withoutUnsupportedFields(ctx, &github.RepositoryRelease{
+		TagName:         &tagName,
+		Name:            &releaseTitle,
+		TargetCommitish: github.String(tag.GetCommit().GetSHA()),
+		Body:            &releaseNotes,
+		Prerelease:      &isPrerelease,
+		// Pick the latest release by version rather than by when each version was migrated
+		MakeLatest: github.String("legacy"),
+	}))
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditCreateRelease, Tag: tagName, SHA: tag.GetCommit().GetSHA()})
+}
+
+// getAllTags of the repository, fetched once and shared between components
+func (a VersioningAction) getAllTags(ctx context.Context) (existingTags []*github.RepositoryTag) {
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	if a.history.tagsListed {
+		return a.history.tags
+	}
+
+	allTagsListed := false
+	page := 1
+
+	for !allTagsListed {
+		requestCtx, cancel := a.requestContext(ctx)
+		tags, _, err := a.client.Repositories.ListTags(requestCtx, a.owner, a.repository, &github.ListOptions{
+			PerPage: 100,
+			Page:    page,
+		})
+		cancel()
+
+		if err != nil {
+			panic(err)
+		}
+
+		existingTags = append(existingTags, tags...)
+		allTagsListed = len(tags) == 0
+		page++
+	}
+
+	a.history.tags = existingTags
+	a.history.tagsListed = true
+	return existingTags
+}
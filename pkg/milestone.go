@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// WithMilestones links each release from the open milestone named after its version, eg: "api 1.5.0", and closes
+// the milestone once the release is published. If next isn't BumpNone, a milestone for the version after the
+// release is created, incrementing the given part of the version.
+func (a VersioningAction) WithMilestones(enabled bool, next Bump) VersioningAction {
+	a.milestones = enabled
+	a.nextMilestone = next
+	return a
+}
+
+// milestoneTitle for a version of the component, eg: "api 1.5.0"
+func (a VersioningAction) milestoneTitle(version *semver.Version) string {
+	return fmt.Sprintf("%s %s", a.component, version.String())
+}
+
+// findMilestone named after a version of the component, or nil if there isn't one open. Milestones named after
+// the version's tag, eg: "api-v1.5.0", are also matched.
+func (a VersioningAction) findMilestone(ctx context.Context, version *semver.Version) *github.Milestone {
+	if !a.milestones {
+		return nil
+	}
+
+	titles := []string{a.milestoneTitle(version), a.tagName(version.String())}
+	page := 1
+	for {
+		requestCtx, cancel := a.requestContext(ctx)
+		milestones, _, err := a.client.Issues.ListMilestones(requestCtx, a.owner, a.repository, &github.MilestoneListOptions{
+			State: "open",
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		if len(milestones) == 0 {
+			return nil
+		}
+
+		for _, milestone := range milestones {
+			for _, title := range titles {
+				if strings.EqualFold(strings.TrimSpace(milestone.GetTitle()), title) {
+					return milestone
+				}
+			}
+		}
+
+		page++
+	}
+}
+
+// completeMilestone closes the milestone of a published release, and creates the next milestone if configured.
+// Prereleases don't complete a milestone, as the stable release is still to come.
+func (a VersioningAction) completeMilestone(ctx context.Context, version *semver.Version) {
+	if !a.milestones || version.Prerelease() != "" {
+		return
+	}
+
+	if milestone := a.findMilestone(ctx, version); milestone != nil {
+		a.logger.Info("Closing milestone", "component", a.component, "milestone", milestone.GetTitle())
+		requestCtx, cancel := a.requestContext(ctx)
+		_, _, err := a.client.Issues.EditMilestone(requestCtx, a.owner, a.repository, milestone.GetNumber(), &github.Milestone{
+			State: github.String("closed"),
+		})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if a.nextMilestone == BumpNone {
+		return
+	}
+
+	next := a.newVersion(version, a.nextMilestone, false)
+	if a.findMilestone(ctx, next) != nil {
+		// Already planned
+		return
+	}
+
+	title := a.milestoneTitle(next)
+	a.logger.Info("Creating milestone", "component", a.component, "milestone", title)
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	_, _, err := a.client.Issues.CreateMilestone(requestCtx, a.owner, a.repository, &github.Milestone{
+		Title: &title,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// releaseMirror is another repository which each release is published to as well
+type releaseMirror struct {
+	client     *github.Client
+	owner      string
+	repository string
+	// Shared by every component, so that the mirror's releases are only listed once
+	history *repositoryHistory
+}
+
+// WithMirror publishes each release to a mirror repository as well, in the format "owner/repository", eg: a public
+// copy on GitHub.com of a repository on GitHub Enterprise Server, so that mirrored components have the same versions
+// in both. The mirror is called with its own client, so it can be on another server, with its own credentials. It
+// must already have the released commits, eg: pushed with `git push --mirror`. Release assets aren't mirrored.
+func (a VersioningAction) WithMirror(ownerAndRepository string, client *github.Client) VersioningAction {
+	owner, repository, ok := strings.Cut(ownerAndRepository, "/")
+	if !ok || owner == "" || repository == "" || strings.Contains(repository, "/") {
+		panic(fmt.Sprintf("mirror repository %q must be in the format owner/repository", ownerAndRepository))
+	}
+
+	a.mirror = &releaseMirror{client: client, owner: owner, repository: repository, history: newRepositoryHistory()}
+	return a
+}
+
+// mirrorAction is a copy of the action which releases to the mirror, without the extras of releasing to the
+// repository itself, eg: alias tags
+func (a VersioningAction) mirrorAction() VersioningAction {
+	m := a
+	m.client, m.owner, m.repository, m.history = a.mirror.client, a.mirror.owner, a.mirror.repository, a.mirror.history
+	m.mirror = nil
+	m.aliasTags = nil
+	return m
+}
+
+// PreflightMirror checks that the mirror is accessible with its client, if there is one, so that a misconfigured
+// mirror fails the run before anything is released
+func (a VersioningAction) PreflightMirror(ctx context.Context) error {
+	if a.mirror == nil {
+		return nil
+	}
+
+	m := a.mirrorAction()
+	requestCtx, cancel := m.requestContext(ctx)
+	defer cancel()
+	_, _, err := m.client.Repositories.Get(requestCtx, m.owner, m.repository)
+
+	var errorResponse *github.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.Response != nil {
+		switch errorResponse.Response.StatusCode {
+		case http.StatusUnauthorized:
+			return errors.New("the mirror-token input is invalid or has expired")
+		case http.StatusForbidden, http.StatusNotFound:
+			return fmt.Errorf("mirror repository %s/%s does not exist, or the mirror-token input can't access it", m.owner, m.repository)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("couldn't check access to mirror repository %s/%s: %w", m.owner, m.repository, err)
+	}
+
+	return nil
+}
+
+// mirrorRelease publishes a release to the mirror, at the same commit, with the same tag, title and notes, or nil if
+// there's no mirror. A release the mirror already has, eg: from an earlier attempt of the run, is reused, and
+// published if the release is no longer a draft.
+func (a VersioningAction) mirrorRelease(ctx context.Context, release *github.RepositoryRelease) *Release {
+	if a.mirror == nil {
+		return nil
+	}
+
+	m := a.mirrorAction()
+	for _, existing := range m.getAllReleases(ctx) {
+		if existing.GetTagName() != release.GetTagName() {
+			continue
+		}
+
+		if existing.GetDraft() && !release.GetDraft() {
+			return newRelease(m.publishMirroredDraft(ctx, existing))
+		}
+
+		m.logger.Info("Mirror already has the release", "component", m.component, "repository", m.Repository(), "tag", release.GetTagName())
+		return newRelease(existing)
+	}
+
+	sha := release.GetTargetCommitish()
+	a.checkMirrorHasCommit(ctx, sha)
+	m.logger.Info("Mirroring release", "component", m.component, "repository", m.Repository(), "tag", release.GetTagName())
+	generateReleaseNotes := false
+	requestCtx, cancel := m.requestContext(ctx)
+	defer cancel()
+	mirrored, _, err := m.client.Repositories.CreateRelease(requestCtx, m.owner, m.repository, m.withoutUnsupportedFields(ctx, &github.RepositoryRelease{
+		TagName:              release.TagName,
+		Name:                 release.Name,
+		TargetCommitish:      &sha,
+		GenerateReleaseNotes: &generateReleaseNotes,
+		Body:                 release.Body,
+		Prerelease:           release.Prerelease,
+		Draft:                release.Draft,
+		MakeLatest:           m.makeLatestOrDefault(),
+	}))
+
+	if err != nil {
+		panic(err)
+	}
+
+	m.audit(AuditRecord{Action: AuditCreateRelease, Tag: mirrored.GetTagName(), SHA: sha})
+	return newRelease(mirrored)
+}
+
+// checkMirrorHasCommit which is about to be released, if there's a mirror, so that a release which can't be
+// mirrored fails before it's created in the repository itself
+func (a VersioningAction) checkMirrorHasCommit(ctx context.Context, sha string) {
+	if a.mirror == nil {
+		return
+	}
+
+	m := a.mirrorAction()
+	requestCtx, cancel := m.requestContext(ctx)
+	defer cancel()
+	_, _, err := m.client.Repositories.GetCommit(requestCtx, m.owner, m.repository, sha, nil)
+	if isNotFound(err) {
+		panic(fmt.Sprintf("Mirror %s doesn't have commit %s, so %s's release can't be mirrored. Push the commit to the mirror before releasing", m.Repository(), sha, a.component))
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// publishMirroredDraft which was mirrored when the release was created as a draft
+func (a VersioningAction) publishMirroredDraft(ctx context.Context, draft *github.RepositoryRelease) *github.RepositoryRelease {
+	a.logger.Info("Publishing mirrored draft release", "component", a.component, "repository", a.Repository(), "tag", draft.GetTagName())
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	isDraft := false
+	release, _, err := a.client.Repositories.EditRelease(requestCtx, a.owner, a.repository, draft.GetID(), a.withoutUnsupportedFields(ctx, &github.RepositoryRelease{
+		Draft:      &isDraft,
+		MakeLatest: a.makeLatestOrDefault(),
+	}))
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditPublishRelease, Tag: release.GetTagName(), SHA: release.GetTargetCommitish()})
+	return release
+}
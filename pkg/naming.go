@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ComponentCaseLower writes component names in lowercase in tags, eg: "payment-api-1.2.0". It's the default.
+	ComponentCaseLower = "lower"
+	// ComponentCasePreserve writes component names in tags as they're named, eg: "Payment-API-1.2.0"
+	ComponentCasePreserve = "preserve"
+)
+
+// ComponentNamesConfig configures how component names are normalised in tags. Component names are always matched
+// case-insensitively, eg: against commit scopes, so existing tags are still found when the normalisation changes.
+type ComponentNamesConfig struct {
+	// Case of component names in tags: ComponentCaseLower or ComponentCasePreserve
+	Case string `yaml:"case,omitempty"`
+	// Slash replaces "/" in component names in tags, eg: "-" to tag "services/api" as "services-api-1.2.0". Slashes
+	// are kept if empty.
+	Slash string `yaml:"slash,omitempty"`
+}
+
+// validate the component names configuration
+func (c ComponentNamesConfig) validate() error {
+	if c.Case != "" && c.Case != ComponentCaseLower && c.Case != ComponentCasePreserve {
+		return fmt.Errorf("invalid component-names case %q, expected one of: %s, %s", c.Case, ComponentCaseLower, ComponentCasePreserve)
+	}
+
+	if strings.ContainsAny(c.Slash, " ~^:?*[\\") {
+		return fmt.Errorf("invalid component-names slash %q, as it isn't allowed in tags", c.Slash)
+	}
+
+	return nil
+}
+
+// tag normalises a tag name, or a prefix of one
+func (c ComponentNamesConfig) tag(name string) string {
+	if c.Case != ComponentCasePreserve {
+		return strings.ToLower(name)
+	}
+
+	return name
+}
+
+// tagComponent is the component's name as it's written in tags
+func (a VersioningAction) tagComponent() string {
+	if slash := a.config.Naming.Slash; slash != "" {
+		return strings.ReplaceAll(a.component, "/", slash)
+	}
+
+	return a.component
+}
+
+// unsafeAssetCharacters are replaced in the names of release assets, as GitHub only keeps letters, digits, and a
+// few punctuation characters
+var unsafeAssetCharacters = regexp.MustCompile(`[^A-Za-z0-9._+-]`)
+
+// assetName of a release asset named after a tag, eg: "services-api-1.2.0.spdx.json" for the tag
+// "services/api-1.2.0"
+func assetName(name string) string {
+	return unsafeAssetCharacters.ReplaceAllString(name, "-")
+}
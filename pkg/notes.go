@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"github.com/ellisto/monorepo-versioning/pkg/changelog"
+	"github.com/google/go-github/v50/github"
+)
+
+const (
+	// NotesMarkdown formats release notes as markdown, as they appear on release pages
+	NotesMarkdown = changelog.Markdown
+	// NotesText formats release notes as plain text, eg: for emails
+	NotesText = changelog.Text
+	// NotesJSON formats release notes as JSON of their sections and entries, for other tools to render
+	NotesJSON = changelog.JSON
+)
+
+// ReleaseNotes are the changes in a version grouped into sections by the type of change. They're modelled and
+// rendered by the changelog package, which other tools can use to produce identical changelogs.
+type ReleaseNotes = changelog.Changelog
+
+// ReleaseNotesSection is a type of change, eg: features
+type ReleaseNotesSection = changelog.Section
+
+// ReleaseNotesEntry is a single change
+type ReleaseNotesEntry = changelog.Entry
+
+// newReleaseNotesEntry for a commit with a description of its change
+func newReleaseNotesEntry(commit *github.RepositoryCommit, description string, author string) ReleaseNotesEntry {
+	return ReleaseNotesEntry{
+		SHA:         commit.GetSHA(),
+		URL:         commit.GetHTMLURL(),
+		Description: description,
+		Author:      author,
+	}
+}
+
+// WithNotesFormat sets the format changelogs are rendered in: markdown, text, or json. Release pages are always
+// markdown.
+func (a VersioningAction) WithNotesFormat(format string) VersioningAction {
+	a.notesFormat = format
+	return a
+}
@@ -0,0 +1,300 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// maxNotifiedCommits is the number of changes listed in a notification, so that large releases stay readable
+const maxNotifiedCommits = 10
+
+// Chat services which releases can be announced in
+const (
+	NotificationSlack   = "slack"
+	NotificationTeams   = "teams"
+	NotificationDiscord = "discord"
+)
+
+// NotificationConfig announces releases of the component in a chat service
+type NotificationConfig struct {
+	// Type of chat service: slack, teams, or discord
+	Type string `yaml:"type,omitempty"`
+	// WebhookURL of the chat service's incoming webhook. Environment variables are expanded, so that the URL can be
+	// kept in a secret, eg: "${SLACK_WEBHOOK_URL}"
+	WebhookURL string `yaml:"webhook-url,omitempty"`
+}
+
+// notificationFormats format an announcement as the message payload of each chat service
+var notificationFormats = map[string]func(announcement announcement) any{
+	NotificationSlack:   slackMessage,
+	NotificationTeams:   teamsMessage,
+	NotificationDiscord: discordMessage,
+}
+
+// announcement of a release, which is formatted for each chat service
+type announcement struct {
+	Title     string
+	Component string
+	Version   string
+	Bump      Bump
+	// Summaries of the changes which caused the release
+	Changes []string
+	// Number of changes which were left out, to keep the announcement readable
+	MoreChanges int
+	// URL of the release page, or empty if there's no release
+	URL string
+}
+
+// newAnnouncement of a release, listing up to maxNotifiedCommits of the changes which bumped the version
+func newAnnouncement(result Result) announcement {
+	announcement := announcement{
+		Title:     fmt.Sprintf("%s released", result.TagName()),
+		Component: result.Component,
+		Version:   result.Version.String(),
+		Bump:      result.Bump,
+	}
+
+	for _, decision := range result.Commits {
+		if !decision.MatchedScope || decision.Bump == BumpNone {
+			continue
+		}
+
+		if len(announcement.Changes) == maxNotifiedCommits {
+			announcement.MoreChanges++
+			continue
+		}
+
+		announcement.Changes = append(announcement.Changes, fmt.Sprintf("%s (`%s`)", decision.Summary, shortSHA(decision.SHA)))
+	}
+
+	if result.Release != nil {
+		announcement.URL = result.Release.HTMLURL
+	}
+
+	return announcement
+}
+
+// changeList formats the changes as a list, with each change starting with bullet
+func (a announcement) changeList(bullet string) string {
+	var changes []string
+	for _, change := range a.Changes {
+		changes = append(changes, bullet+change)
+	}
+
+	if a.MoreChanges > 0 {
+		changes = append(changes, fmt.Sprintf("…and %d more", a.MoreChanges))
+	}
+
+	return strings.Join(changes, "\n")
+}
+
+// WebhookConfig sends a signed JSON payload to a URL when a release of the component is created
+type WebhookConfig struct {
+	// URL the payload is posted to. Environment variables are expanded.
+	URL string `yaml:"url,omitempty"`
+	// Secret the payload is signed with, using HMAC-SHA256. Environment variables are expanded, so that the secret
+	// can be kept in a secret, eg: "${WEBHOOK_SECRET}"
+	Secret string `yaml:"secret,omitempty"`
+}
+
+// webhookSignatureHeader contains the HMAC-SHA256 signature of the payload, in the same format as GitHub's own
+// webhooks so that existing verification code can be reused
+const webhookSignatureHeader = "X-Monorepo-Versioning-Signature-256"
+
+// webhookPayload describes a release for services which react to it
+type webhookPayload struct {
+	Component       string           `json:"component"`
+	Version         string           `json:"version"`
+	PreviousVersion string           `json:"previousVersion,omitempty"`
+	Tag             string           `json:"tag"`
+	Bump            Bump             `json:"bump"`
+	Prerelease      bool             `json:"prerelease"`
+	Notes           string           `json:"notes"`
+	Commits         []CommitDecision `json:"commits"`
+	Release         *Release         `json:"release,omitempty"`
+}
+
+// signPayload with HMAC-SHA256, formatted as "sha256=<hex digest>"
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// notifyRelease sends the notifications configured for the component about a release. Notifications are sent
+// after the release is created, so a failure is logged rather than failing the run, which would leave the
+// release in place but report it as failed.
+func (a VersioningAction) notifyRelease(ctx context.Context, result Result, notes string) {
+	for _, notification := range a.componentConfig().Notifications {
+		format, ok := notificationFormats[notification.Type]
+		if !ok {
+			// The configuration is validated when it's loaded, but not when it's built in code
+			a.logger.Error(fmt.Sprintf("Couldn't send %s notification, as it's not a known type of notification", notification.Type), "component", a.component)
+			continue
+		}
+
+		if err := a.postJSON(ctx, os.ExpandEnv(notification.WebhookURL), format(newAnnouncement(result)), nil); err != nil {
+			a.logger.Error(fmt.Sprintf("Couldn't send %s notification: %s", notification.Type, err), "component", a.component)
+		}
+	}
+
+	if webhook := a.componentConfig().Webhook; webhook != nil {
+		payload := webhookPayload{
+			Component:  result.Component,
+			Version:    result.Version.String(),
+			Tag:        result.TagName(),
+			Bump:       result.Bump,
+			Prerelease: result.Version.Prerelease() != "",
+			Notes:      notes,
+			Commits:    result.Commits,
+			Release:    result.Release,
+		}
+
+		if result.PreviousVersion != nil {
+			payload.PreviousVersion = result.PreviousVersion.String()
+		}
+
+		body, err := json.Marshal(payload)
+		if err == nil {
+			err = a.post(ctx, os.ExpandEnv(webhook.URL), body, map[string]string{
+				webhookSignatureHeader: signPayload(os.ExpandEnv(webhook.Secret), body),
+			})
+		}
+
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Couldn't send release webhook: %s", err), "component", a.component)
+		}
+	}
+}
+
+// slackMessage formats an announcement as a Block Kit message
+func slackMessage(announcement announcement) any {
+	fields := []map[string]any{
+		{"type": "mrkdwn", "text": fmt.Sprintf("*Component*\n%s", announcement.Component)},
+		{"type": "mrkdwn", "text": fmt.Sprintf("*Version*\n%s", announcement.Version)},
+		{"type": "mrkdwn", "text": fmt.Sprintf("*Bump*\n%s", announcement.Bump)},
+	}
+
+	blocks := []map[string]any{
+		{"type": "header", "text": map[string]any{"type": "plain_text", "text": announcement.Title}},
+		{"type": "section", "fields": fields},
+	}
+
+	if changes := announcement.changeList("• "); changes != "" {
+		blocks = append(blocks, map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": changes}})
+	}
+
+	if announcement.URL != "" {
+		blocks = append(blocks, map[string]any{"type": "context", "elements": []map[string]any{
+			{"type": "mrkdwn", "text": fmt.Sprintf("<%s|View the release on GitHub>", announcement.URL)},
+		}})
+	}
+
+	return map[string]any{
+		// Shown in notifications, where blocks aren't rendered
+		"text":   announcement.Title,
+		"blocks": blocks,
+	}
+}
+
+// teamsMessage formats an announcement as an Adaptive Card, as accepted by Teams incoming webhooks and workflows
+func teamsMessage(announcement announcement) any {
+	body := []map[string]any{
+		{"type": "TextBlock", "size": "Large", "weight": "Bolder", "text": announcement.Title, "wrap": true},
+		{"type": "FactSet", "facts": []map[string]any{
+			{"title": "Component", "value": announcement.Component},
+			{"title": "Version", "value": announcement.Version},
+			{"title": "Bump", "value": string(announcement.Bump)},
+		}},
+	}
+
+	if changes := announcement.changeList("- "); changes != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": changes, "wrap": true})
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+
+	if announcement.URL != "" {
+		card["actions"] = []map[string]any{{"type": "Action.OpenUrl", "title": "View the release on GitHub", "url": announcement.URL}}
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
+
+// discordMessage formats an announcement as a Discord webhook message with an embed
+func discordMessage(announcement announcement) any {
+	embed := map[string]any{
+		"title":       announcement.Title,
+		"description": announcement.changeList("• "),
+		"fields": []map[string]any{
+			{"name": "Component", "value": announcement.Component, "inline": true},
+			{"name": "Version", "value": announcement.Version, "inline": true},
+			{"name": "Bump", "value": string(announcement.Bump), "inline": true},
+		},
+	}
+
+	if announcement.URL != "" {
+		embed["url"] = announcement.URL
+	}
+
+	return map[string]any{
+		"embeds": []map[string]any{embed},
+	}
+}
+
+// postJSON sends payload to url as JSON, with any extra headers
+func (a VersioningAction) postJSON(ctx context.Context, url string, payload any, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return a.post(ctx, url, body, headers)
+}
+
+// post a JSON body to url, with any extra headers
+func (a VersioningAction) post(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("%s responded with %s: %s", request.URL.Host, response.Status, strings.TrimSpace(string(responseBody)))
+	}
+
+	return nil
+}
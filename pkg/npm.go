@@ -0,0 +1,31 @@
+package pkg
+
+import "github.com/Masterminds/semver"
+
+// ComponentNpm is the type of components which are npm packages, whose package.json version is updated with each
+// new version, so that the package can be published in lockstep with the release
+const ComponentNpm = "npm"
+
+// distTag is the npm dist-tag a released version of the component should be published with, or an empty string
+// if the component isn't an npm package. Prereleases are tagged with their channel, so that installing the package
+// without a tag never gets one, and maintenance branches with their release line, eg: "release-1.x", like
+// semantic-release, as dist-tags can't be valid version ranges.
+func (a VersioningAction) distTag(version *semver.Version) string {
+	if a.componentConfig().Type != ComponentNpm {
+		return ""
+	}
+
+	if version.Prerelease() != "" {
+		if channel := a.channel(); channel.Prerelease != "" && channel.Name != "" {
+			return channel.Name
+		}
+
+		return "next"
+	}
+
+	if line := a.releaseLine(); line != nil {
+		return "release-" + line.String()
+	}
+
+	return "latest"
+}
@@ -0,0 +1,206 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ociManifestMediaType is the media type of OCI image manifests, which describe artifacts such as Helm charts
+const ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+// ociArtifact is an artifact of one layer, with its metadata as the config, eg: a Helm chart
+type ociArtifact struct {
+	configMediaType string
+	config          []byte
+	layerMediaType  string
+	layer           []byte
+	annotations     map[string]string
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int    `json:"size"`
+}
+
+// ociPusher pushes artifacts to a repository of an OCI registry with the distribution API, see
+// https://github.com/opencontainers/distribution-spec
+type ociPusher struct {
+	registry    string
+	repository  string
+	credentials RegistryCredentials
+	client      *http.Client
+	// Authorization header for the repository, once a token has been issued
+	authorization string
+}
+
+func newOCIPusher(registry string, repository string, credentials RegistryCredentials) *ociPusher {
+	return &ociPusher{registry: registry, repository: repository, credentials: credentials, client: http.DefaultClient}
+}
+
+// push an artifact as a tag of the repository, uploading its config and layer unless the registry already has them
+func (p *ociPusher) push(ctx context.Context, tag string, artifact ociArtifact) error {
+	config, err := p.pushBlob(ctx, artifact.configMediaType, artifact.config)
+	if err != nil {
+		return err
+	}
+
+	layer, err := p.pushBlob(ctx, artifact.layerMediaType, artifact.layer)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := json.Marshal(struct {
+		SchemaVersion int               `json:"schemaVersion"`
+		MediaType     string            `json:"mediaType"`
+		Config        ociDescriptor     `json:"config"`
+		Layers        []ociDescriptor   `json:"layers"`
+		Annotations   map[string]string `json:"annotations,omitempty"`
+	}{2, ociManifestMediaType, config, []ociDescriptor{layer}, artifact.annotations})
+	if err != nil {
+		return err
+	}
+
+	_, err = p.do(ctx, http.MethodPut, p.url("/manifests/"+tag), ociManifestMediaType, manifest, http.StatusCreated)
+	return err
+}
+
+// pushBlob uploads a blob in a single request, returning its descriptor
+func (p *ociPusher) pushBlob(ctx context.Context, mediaType string, blob []byte) (ociDescriptor, error) {
+	descriptor := ociDescriptor{MediaType: mediaType, Digest: fmt.Sprintf("sha256:%x", sha256.Sum256(blob)), Size: len(blob)}
+	if _, err := p.do(ctx, http.MethodHead, p.url("/blobs/"+descriptor.Digest), "", nil, http.StatusOK); err == nil {
+		return descriptor, nil
+	}
+
+	response, err := p.do(ctx, http.MethodPost, p.url("/blobs/uploads/"), "", nil, http.StatusAccepted)
+	if err != nil {
+		return descriptor, err
+	}
+
+	// The upload location may be relative to the registry, and may already have a query
+	location, err := url.Parse(p.url("/"))
+	if err == nil {
+		location, err = location.Parse(response.Header.Get("Location"))
+	}
+
+	if err != nil {
+		return descriptor, fmt.Errorf("registry returned an invalid upload location: %w", err)
+	}
+
+	query := location.Query()
+	query.Set("digest", descriptor.Digest)
+	location.RawQuery = query.Encode()
+	_, err = p.do(ctx, http.MethodPut, location.String(), "application/octet-stream", blob, http.StatusCreated)
+	return descriptor, err
+}
+
+// url of an endpoint of the repository, eg: "/manifests/1.2.3"
+func (p *ociPusher) url(endpoint string) string {
+	return fmt.Sprintf("https://%s/v2/%s%s", p.registry, p.repository, endpoint)
+}
+
+// do a request to the registry, authenticating and retrying once if the registry challenges it, and checking for
+// the expected status
+func (p *ociPusher) do(ctx context.Context, method string, requestURL string, contentType string, body []byte, status int) (*http.Response, error) {
+	response, err := p.send(ctx, method, requestURL, contentType, body)
+	if err == nil && response.StatusCode == http.StatusUnauthorized && p.authorization == "" {
+		response.Body.Close()
+		if err = p.authorize(ctx, response.Header.Get("WWW-Authenticate")); err == nil {
+			response, err = p.send(ctx, method, requestURL, contentType, body)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+	if response.StatusCode != status {
+		message, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, requestURL, response.Status, strings.TrimSpace(string(message)))
+	}
+
+	return response, nil
+}
+
+func (p *ociPusher) send(ctx context.Context, method string, requestURL string, contentType string, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	if p.authorization != "" {
+		request.Header.Set("Authorization", p.authorization)
+	}
+
+	return p.client.Do(request)
+}
+
+// challengeParameter matches a parameter of a WWW-Authenticate challenge, eg: realm="https://ghcr.io/token"
+var challengeParameter = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize requests to the repository by answering the registry's challenge, either with the credentials
+// themselves, or with a token issued for them
+func (p *ociPusher) authorize(ctx context.Context, challenge string) error {
+	scheme, parameters, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") {
+		p.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(p.credentials.Username+":"+p.credentials.Password))
+		return nil
+	}
+
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("registry %s asked for unsupported authentication %q", p.registry, challenge)
+	}
+
+	values := make(map[string]string)
+	for _, match := range challengeParameter.FindAllStringSubmatch(parameters, -1) {
+		values[match[1]] = match[2]
+	}
+
+	tokenURL, err := url.Parse(values["realm"])
+	if err != nil || values["realm"] == "" {
+		return fmt.Errorf("registry %s has no token realm in its challenge %q", p.registry, challenge)
+	}
+
+	query := tokenURL.Query()
+	query.Set("service", values["service"])
+	query.Set("scope", fmt.Sprintf("repository:%s:pull,push", p.repository))
+	tokenURL.RawQuery = query.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	if p.credentials.Username != "" || p.credentials.Password != "" {
+		request.SetBasicAuth(p.credentials.Username, p.credentials.Password)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := doJSON(p.client, request, http.StatusOK, &token); err != nil {
+		return fmt.Errorf("could not get a token for registry %s: %w", p.registry, err)
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	p.authorization = "Bearer " + token.Token
+	return nil
+}
@@ -0,0 +1,167 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+	"github.com/leodido/go-conventionalcommits"
+	"github.com/leodido/go-conventionalcommits/parser"
+)
+
+// DefaultTagTemplate names tags after the component and version, eg: "api-1.2.3"
+const DefaultTagTemplate = "{component}-{version}"
+
+// DefaultInitialVersion is the first version of a component which has never been released
+const DefaultInitialVersion = "1.0.0"
+
+// Option configures an action created with New. Options are the settings a new action is built from, which New
+// validates together. Everything else is set on the action New returns, with its With methods, eg: WithLogger or
+// WithConfig, so that each setting has a single way to be set.
+type Option func(a *VersioningAction) error
+
+// New creates an action from options. The repository, branch, revision and client must be set. Returns an error
+// if the options are invalid, or can't be combined.
+func New(opts ...Option) (VersioningAction, error) {
+	a := VersioningAction{
+		initialVersion: DefaultInitialVersion,
+		tagTemplate:    DefaultTagTemplate,
+		parser:         parser.NewMachine(conventionalcommits.WithTypes(conventionalcommits.TypesConventional)),
+		clock:          systemClock{},
+		history:        newRepositoryHistory(),
+		logger:         slog.Default(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(&a); err != nil {
+			return a, err
+		}
+	}
+
+	var errs []error
+	if a.owner == "" {
+		errs = append(errs, errors.New("a repository must be set with WithRepository"))
+	}
+
+	if a.branch == "" {
+		errs = append(errs, errors.New("a branch must be set with WithBranch"))
+	}
+
+	if a.revision == "" {
+		errs = append(errs, errors.New("a revision must be set with WithRevision"))
+	}
+
+	if a.client == nil {
+		errs = append(errs, errors.New("a GitHub client must be set with WithClient"))
+	}
+
+	if a.label != "" && a.component == "" {
+		errs = append(errs, fmt.Errorf("label %q needs a component to label", a.label))
+	}
+
+	if !strings.Contains(a.tagTemplate, "{component}") && a.component == "" {
+		errs = append(errs, fmt.Errorf("tag template %q has no {component}, so it can only be used with a single component set with WithComponent", a.tagTemplate))
+	}
+
+	return a, errors.Join(errs...)
+}
+
+// WithRepository sets the repository, in the format "owner/repository"
+func WithRepository(ownerAndRepository string) Option {
+	return func(a *VersioningAction) error {
+		owner, repository, ok := strings.Cut(ownerAndRepository, "/")
+		if !ok || owner == "" || repository == "" || strings.Contains(repository, "/") {
+			return fmt.Errorf("repository %q must be in the format owner/repository", ownerAndRepository)
+		}
+
+		a.owner, a.repository = owner, repository
+		return nil
+	}
+}
+
+// WithClient sets the client used to call the GitHub API
+func WithClient(client *github.Client) Option {
+	return func(a *VersioningAction) error {
+		a.client = client
+		return nil
+	}
+}
+
+// WithComponent sets the component to version, and optionally a human-readable label used in release titles
+func WithComponent(component string, label string) Option {
+	return func(a *VersioningAction) error {
+		a.component, a.label = component, label
+		return nil
+	}
+}
+
+// WithBranch sets the branch being versioned, and the repository's default branch, which together decide whether
+// versions are stable or prereleases
+func WithBranch(branch string, defaultBranch string) Option {
+	return func(a *VersioningAction) error {
+		if defaultBranch == "" {
+			return fmt.Errorf("branch %s needs the repository's default branch, to decide whether its versions are prereleases", branch)
+		}
+
+		a.branch, a.defaultBranch = branch, defaultBranch
+		return nil
+	}
+}
+
+// WithRevision sets the commit SHA being versioned
+func WithRevision(revision string) Option {
+	return func(a *VersioningAction) error {
+		if len(revision) < 7 {
+			return fmt.Errorf("revision %q must be a commit SHA of at least 7 characters", revision)
+		}
+
+		a.revision = revision
+		return nil
+	}
+}
+
+// WithInitialVersion sets the first version of a component which has never been released. Defaults to
+// DefaultInitialVersion.
+func WithInitialVersion(version string) Option {
+	return func(a *VersioningAction) error {
+		if _, err := semver.NewVersion(version); err != nil {
+			return fmt.Errorf("initial version %q is not a semantic version: %w", version, err)
+		}
+
+		a.initialVersion = version
+		return nil
+	}
+}
+
+// WithTagTemplate sets how tags are named, where "{component}" is replaced with the component name and
+// "{version}" with the version, eg: "{component}@v{version}". The version must come last, so that the version can
+// be read back from the tag. Defaults to DefaultTagTemplate.
+func WithTagTemplate(template string) Option {
+	return func(a *VersioningAction) error {
+		if !strings.HasSuffix(template, "{version}") || strings.Count(template, "{version}") != 1 {
+			return fmt.Errorf("tag template %q must end with {version}", template)
+		}
+
+		a.tagTemplate = template
+		return nil
+	}
+}
+
+// WithParser sets the parser of commit messages, eg: to allow types other than the conventional ones
+func WithParser(machine conventionalcommits.Machine) Option {
+	return func(a *VersioningAction) error {
+		a.parser = machine
+		return nil
+	}
+}
+
+// WithClock sets the source of the current time. Defaults to the system clock.
+func WithClock(clock Clock) Option {
+	return func(a *VersioningAction) error {
+		a.clock = clock
+		return nil
+	}
+}
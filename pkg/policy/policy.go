@@ -0,0 +1,102 @@
+// Package policy decides how much a component's version is bumped by the commits since its last release. The
+// action uses the Conventional policy unless it's given another, so that programs embedding it can use their own
+// versioning rules, eg: no major versions without sign-off, and test them without a repository.
+package policy
+
+import "strings"
+
+// Bump is the part of a semantic version incremented by a change
+type Bump string
+
+const (
+	Major Bump = "major"
+	Minor Bump = "minor"
+	Patch Bump = "patch"
+	None  Bump = "none"
+)
+
+// order ranks the bumps so the largest can be chosen
+var order = map[Bump]int{None: 0, Patch: 1, Minor: 2, Major: 3}
+
+// Greater checks whether b is a larger version increment than other
+func (b Bump) Greater(other Bump) bool {
+	return order[b] > order[other]
+}
+
+// Highest of the bumps, or None if there are none
+func Highest(bumps ...Bump) Bump {
+	highest := None
+	for _, bump := range bumps {
+		if bump.Greater(highest) {
+			highest = bump
+		}
+	}
+
+	return highest
+}
+
+// Commit which changed a component since its last release
+type Commit struct {
+	SHA string
+	// Summary is the first line of the commit message
+	Summary string
+	// Type of the conventional commit, eg: "feat", or "dependencies" for dependency updates
+	Type  string
+	Scope string
+	// Breaking is whether the commit is a breaking change, eg: "feat!: ..."
+	Breaking bool
+	// DependencyUpdate is whether the commit was made by a dependency update bot, rather than being a conventional
+	// commit
+	DependencyUpdate bool
+}
+
+// Policy decides the version bump of a component from the commits which changed it since its last release. The
+// bump is still limited by the release line of a maintenance branch, and the channel's max-bump.
+type Policy interface {
+	Decide(commits []Commit) Bump
+}
+
+// Func is a function which decides the version bump, eg: to wrap another policy
+type Func func(commits []Commit) Bump
+
+// Decide the version bump by calling the function
+func (f Func) Decide(commits []Commit) Bump {
+	return f(commits)
+}
+
+// Conventional bumps versions following the Conventional Commits specification: breaking changes are a major
+// bump, features a minor bump, and fixes and refactors a patch bump. Other types of commit don't change the version.
+type Conventional struct {
+	// DependencyUpdates is the bump caused by dependency updates: None (the default), or Patch
+	DependencyUpdates Bump
+}
+
+// Bump of a single commit
+func (p Conventional) Bump(commit Commit) Bump {
+	switch {
+	case commit.DependencyUpdate:
+		if p.DependencyUpdates == "" {
+			return None
+		}
+
+		return p.DependencyUpdates
+	case commit.Breaking:
+		return Major
+	case commit.Type == "feat":
+		return Minor
+	case commit.Type == "fix", strings.EqualFold(commit.Type, "refactor"):
+		return Patch
+	default:
+		return None
+	}
+}
+
+// Decide the version bump as the highest bump of any of the commits
+func (p Conventional) Decide(commits []Commit) Bump {
+	bump := None
+	for _, commit := range commits {
+		bump = Highest(bump, p.Bump(commit))
+	}
+
+	return bump
+}
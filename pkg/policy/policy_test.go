@@ -0,0 +1,165 @@
+package policy_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ellisto/monorepo-versioning/pkg"
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+	"github.com/ellisto/monorepo-versioning/pkg/policy"
+)
+
+func TestConventionalDecide(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  policy.Conventional
+		commits []policy.Commit
+		bump    policy.Bump
+	}{
+		{name: "no commits", bump: policy.None},
+		{name: "breaking change", commits: []policy.Commit{{Type: "feat", Breaking: true}}, bump: policy.Major},
+		{name: "breaking fix", commits: []policy.Commit{{Type: "fix", Breaking: true}}, bump: policy.Major},
+		{name: "feature", commits: []policy.Commit{{Type: "feat"}}, bump: policy.Minor},
+		{name: "fix", commits: []policy.Commit{{Type: "fix"}}, bump: policy.Patch},
+		{name: "refactor", commits: []policy.Commit{{Type: "Refactor"}}, bump: policy.Patch},
+		{name: "other type", commits: []policy.Commit{{Type: "docs"}, {Type: "chore"}}, bump: policy.None},
+		{name: "non-conventional", commits: []policy.Commit{{Summary: "Update the readme"}}, bump: policy.None},
+		{name: "highest of several", commits: []policy.Commit{{Type: "fix"}, {Type: "feat"}, {Type: "docs"}}, bump: policy.Minor},
+		{name: "dependency update", commits: []policy.Commit{{Type: "dependencies", DependencyUpdate: true}}, bump: policy.None},
+		{
+			name:    "dependency update with patch bump",
+			policy:  policy.Conventional{DependencyUpdates: policy.Patch},
+			commits: []policy.Commit{{Type: "dependencies", DependencyUpdate: true}},
+			bump:    policy.Patch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if bump := test.policy.Decide(test.commits); bump != test.bump {
+				t.Errorf("Expected a %s bump, but got %s", test.bump, bump)
+			}
+		})
+	}
+}
+
+func TestHighest(t *testing.T) {
+	if bump := policy.Highest(); bump != policy.None {
+		t.Errorf("Expected no bump, but got %s", bump)
+	}
+
+	if bump := policy.Highest(policy.Patch, policy.Major, policy.Minor); bump != policy.Major {
+		t.Errorf("Expected a major bump, but got %s", bump)
+	}
+}
+
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// generateVersion of api after a breaking change, released after a previous version
+func generateVersion(t *testing.T, previous string, customize func(a pkg.VersioningAction) pkg.VersioningAction) pkg.Result {
+	t.Helper()
+	server := githubtest.NewServer("octocat", "monorepo")
+	defer server.Close()
+	released := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+	server.AddRelease(githubtest.Release("api-"+previous, released[0]))
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("fix(api): handle timeouts"), githubtest.Commit("feat(api)!: remove the v1 endpoints"))
+
+	a, err := pkg.New(
+		pkg.WithRepository("octocat/monorepo"),
+		pkg.WithClient(server.Client()),
+		pkg.WithBranch(githubtest.DefaultBranch, githubtest.DefaultBranch),
+		pkg.WithRevision(server.Head(githubtest.DefaultBranch)),
+		pkg.WithComponent("api", ""),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return customize(a.WithLogger(slog.New(slog.NewTextHandler(testLogWriter{t}, nil)))).GenerateVersion(context.Background(), true)
+}
+
+func TestCustomPolicy(t *testing.T) {
+	var decided []policy.Commit
+	// Majors need sign-off, so they're released as minor versions until they're approved
+	minorAtMost := policy.Func(func(commits []policy.Commit) policy.Bump {
+		decided = commits
+		bump := policy.Conventional{}.Decide(commits)
+		if bump == policy.Major {
+			return policy.Minor
+		}
+
+		return bump
+	})
+
+	result := generateVersion(t, "1.4.2", func(a pkg.VersioningAction) pkg.VersioningAction {
+		return a.WithPolicy(minorAtMost)
+	})
+
+	if result.Bump != pkg.BumpMinor || result.Version.String() != "1.5.0" {
+		t.Errorf("Expected the custom policy to bump to 1.5.0, but got a %s bump to %s", result.Bump, result.Version)
+	}
+
+	if len(decided) != 2 || !decided[0].Breaking || decided[0].Type != "feat" || decided[1].Type != "fix" {
+		t.Errorf("Expected the policy to decide from both of api's commits, but got %+v", decided)
+	}
+
+	// Explanations show the bump the custom policy decides for each commit, as the version does
+	if bump := result.Commits[0].Bump; bump != pkg.BumpMinor {
+		t.Errorf("Expected the breaking change to be explained as a minor bump, but got %s", bump)
+	}
+}
+
+func TestCustomPolicyChecks(t *testing.T) {
+	server := githubtest.NewServer("octocat", "monorepo")
+	defer server.Close()
+	released := server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+	server.AddRelease(githubtest.Release("api-1.4.2", released[0]))
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api)!: remove the v1 endpoints"))
+	a, err := pkg.New(
+		pkg.WithRepository("octocat/monorepo"),
+		pkg.WithClient(server.Client()),
+		pkg.WithBranch(githubtest.DefaultBranch, githubtest.DefaultBranch),
+		pkg.WithRevision(server.Head(githubtest.DefaultBranch)),
+		pkg.WithComponent("api", ""),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minorAtMost := policy.Func(func(commits []policy.Commit) policy.Bump {
+		if bump := (policy.Conventional{}).Decide(commits); bump != policy.Major {
+			return bump
+		}
+
+		return policy.Minor
+	})
+
+	a = a.WithLogger(slog.New(slog.NewTextHandler(testLogWriter{t}, nil))).WithConfig(pkg.Config{Check: pkg.CheckConfig{MajorLabel: "breaking-change"}})
+	for _, test := range []struct {
+		name       string
+		policy     policy.Policy
+		violations int
+	}{
+		{name: "conventional", policy: policy.Conventional{}, violations: 1},
+		// The breaking change is only a minor version under the custom policy, so it doesn't need the label
+		{name: "custom", policy: minorAtMost, violations: 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			report := pkg.Check(context.Background(), []pkg.VersioningAction{a.WithPolicy(test.policy)}, nil)
+			if len(report.Violations) != test.violations {
+				t.Errorf("Expected %d violations, but got %+v", test.violations, report.Violations)
+			}
+		})
+	}
+}
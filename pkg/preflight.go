@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// Preflight checks that the repository is accessible with the token, and that the token can push to it if write is
+// true, so that a misconfigured workflow fails with an actionable error before any work is done rather than part
+// way through a release
+func (a VersioningAction) Preflight(ctx context.Context, write bool) error {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	repository, _, err := a.client.Repositories.Get(requestCtx, a.owner, a.repository)
+
+	var errorResponse *github.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.Response != nil {
+		switch errorResponse.Response.StatusCode {
+		case http.StatusUnauthorized:
+			return errors.New("the github-token input is invalid or has expired")
+		case http.StatusForbidden, http.StatusNotFound:
+			// GitHub hides private repositories from tokens which can't access them
+			return fmt.Errorf("repository %s/%s does not exist, or the github-token input can't access it", a.owner, a.repository)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("couldn't check access to repository %s/%s: %w", a.owner, a.repository, err)
+	}
+
+	if !write {
+		return nil
+	}
+
+	permissions := repository.GetPermissions()
+	if permissions == nil {
+		// Tokens for GitHub Apps, including GITHUB_TOKEN, don't report their permissions on the repository
+		a.logger.Debug("Token permissions aren't reported, so can't check them before releasing", "repository", repository.GetFullName())
+		return nil
+	}
+
+	if !permissions["push"] && !permissions["admin"] {
+		return fmt.Errorf("the github-token input can't push to %s, which is needed to create tags and releases. Grant it the `contents: write` permission, eg: with a `permissions` block in the workflow", repository.GetFullName())
+	}
+
+	return nil
+}
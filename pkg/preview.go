@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// ReleasePreview is exactly what is released for a version, so that dry runs can be reviewed before the release
+// is created
+type ReleasePreview struct {
+	TagName string `json:"tagName"`
+	Title   string `json:"title"`
+	Notes   string `json:"notes"`
+}
+
+// releasePreview of the release for a version, with release notes generated from the commits since the
+// previous version
+func (a VersioningAction) releasePreview(ctx context.Context, version *semver.Version, commits []*github.RepositoryCommit) *ReleasePreview {
+	notes := a.generateReleaseNotes(ctx, commits)
+	if milestone := a.findMilestone(ctx, version); milestone != nil {
+		notes += fmt.Sprintf("\n> :triangular_flag_on_post: Planned in the [%s](%s) milestone.\n", milestone.GetTitle(), milestone.GetHTMLURL())
+	}
+
+	return &ReleasePreview{
+		TagName: a.tagName(version.String()),
+		Title:   a.releaseTitle(version),
+		Notes:   notes,
+	}
+}
+
+// Markdown formats the preview as it would appear on the release page, with the tag name under the title
+func (p ReleasePreview) Markdown() string {
+	return fmt.Sprintf("## %s\n\nTag: `%s`\n%s\n", p.Title, p.TagName, p.Notes)
+}
+
+// WritePreviews writes the release previews of the results to w, such as the step summary. Results without a
+// preview, because no version was generated, are skipped.
+func WritePreviews(w io.Writer, results []Result) error {
+	var previews []string
+	for _, result := range results {
+		if result.Preview != nil {
+			previews = append(previews, result.Preview.Markdown())
+		}
+	}
+
+	if len(previews) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(w, strings.Join(previews, "\n---\n\n"))
+	return err
+}
@@ -0,0 +1,223 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// ruleset is a repository ruleset, which the version of go-github in use doesn't have types for, see
+// https://docs.github.com/en/rest/repos/rules
+type ruleset struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Target      string `json:"target"`
+	Enforcement string `json:"enforcement"`
+	// Whether the token can bypass the ruleset: "always", "pull_requests_only" or "never"
+	CurrentUserCanBypass string `json:"current_user_can_bypass"`
+	Conditions           struct {
+		RefName struct {
+			Include []string `json:"include"`
+			Exclude []string `json:"exclude"`
+		} `json:"ref_name"`
+	} `json:"conditions"`
+	Rules []struct {
+		Type string `json:"type"`
+	} `json:"rules"`
+}
+
+// Rules of rulesets which stop the action creating tags, or pushing commits to a branch
+var (
+	blockingTagRules    = []string{"creation", "update"}
+	blockingBranchRules = []string{"update", "pull_request", "required_status_checks", "required_deployments"}
+)
+
+// appTokenAdvice is how to release despite protection rules which the token can't bypass
+const appTokenAdvice = "The workflow's GITHUB_TOKEN can't bypass protection rules, so use a GitHub App's installation token instead, eg: created with actions/create-github-app-token, and allow the app to bypass the rules"
+
+// PreflightProtection checks that the component's tags can be created, and that the commits updating its version
+// files can be pushed, despite the repository's rulesets and branch protection, so that a release which would be
+// blocked fails with a clear diagnostic before anything is changed. Rulesets which the token can bypass are
+// ignored. Classic branch protection doesn't say whether the token can bypass it, so it's only warned about.
+func (a VersioningAction) PreflightProtection(ctx context.Context) error {
+	rulesets, ok := a.getRulesets(ctx)
+	if !ok {
+		return nil
+	}
+
+	tags := []string{a.tagName("1.0.0")}
+	for _, alias := range a.aliasTags {
+		// Alias tags are moved with each release, so they also mustn't be blocked from updating
+		tags = append(tags, a.tagName(map[string]string{AliasMajor: "v1", AliasLatest: "latest"}[alias]))
+	}
+
+	for _, ruleset := range rulesets {
+		if ruleset.Target != "tag" {
+			continue
+		}
+
+		for _, tag := range tags {
+			if rule := ruleset.blocks("refs/tags/"+tag, a.defaultBranch, blockingTagRules); rule != "" {
+				return fmt.Errorf("creating tag %s would be blocked by the %q rule of the %q ruleset. %s", tag, rule, ruleset.Name, appTokenAdvice)
+			}
+		}
+	}
+
+	for _, branch := range a.pushedBranches() {
+		for _, ruleset := range rulesets {
+			if ruleset.Target != "branch" {
+				continue
+			}
+
+			if rule := ruleset.blocks("refs/heads/"+branch, a.defaultBranch, blockingBranchRules); rule != "" {
+				return fmt.Errorf("pushing to branch %s would be blocked by the %q rule of the %q ruleset. %s, or release with release-pull-requests instead", branch, rule, ruleset.Name, appTokenAdvice)
+			}
+		}
+
+		a.warnAboutBranchProtection(ctx, branch)
+	}
+
+	return nil
+}
+
+// pushedBranches are the branches which releasing the component pushes commits to
+func (a VersioningAction) pushedBranches() []string {
+	var branches []string
+	if len(a.componentConfig().versionFiles()) > 0 && !a.releasePullRequests {
+		branches = append(branches, a.branch)
+	}
+
+	if a.config.VersionsManifest != "" && a.branch == a.defaultBranch && len(branches) == 0 {
+		branches = append(branches, a.defaultBranch)
+	}
+
+	return branches
+}
+
+// blocks returns the first of the rules which the ruleset enforces on a ref, if the token can't bypass it
+func (r ruleset) blocks(ref string, defaultBranch string, rules []string) string {
+	if r.Enforcement != "active" || r.CurrentUserCanBypass == "always" || !r.matches(ref, defaultBranch) {
+		return ""
+	}
+
+	for _, rule := range r.Rules {
+		for _, blocking := range rules {
+			if rule.Type == blocking {
+				return rule.Type
+			}
+		}
+	}
+
+	return ""
+}
+
+// matches checks whether the ruleset's ref name conditions include a ref, eg: "refs/tags/api-1.0.0"
+func (r ruleset) matches(ref string, defaultBranch string) bool {
+	matchesAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			switch pattern {
+			case "~ALL":
+				return true
+			case "~DEFAULT_BRANCH":
+				pattern = "refs/heads/" + defaultBranch
+			}
+
+			if compiled, err := compileGlob(pattern); err == nil && compiled.MatchString(ref) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return matchesAny(r.Conditions.RefName.Include) && !matchesAny(r.Conditions.RefName.Exclude)
+}
+
+// getRulesets of the repository, including those inherited from its organisation, fetched once and shared
+// between components. Returns false if they can't be read, eg: because GitHub Enterprise Server doesn't have
+// rulesets yet, in which case they aren't checked.
+func (a VersioningAction) getRulesets(ctx context.Context) ([]ruleset, bool) {
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	if a.history.rulesetsListed {
+		return a.history.rulesets, a.history.rulesets != nil
+	}
+
+	a.history.rulesetsListed = true
+	var summaries []ruleset
+	if err := a.getJSON(ctx, fmt.Sprintf("repos/%s/%s/rulesets?includes_parents=true&per_page=100", a.owner, a.repository), &summaries); err != nil {
+		a.logger.Debug("Can't read the repository's rulesets, so not checking them before releasing", "error", err.Error())
+		return nil, false
+	}
+
+	// The rules, conditions and whether the token can bypass them are only included in each ruleset's details
+	rulesets := []ruleset{}
+	for _, summary := range summaries {
+		if summary.Enforcement != "active" {
+			continue
+		}
+
+		var details ruleset
+		if err := a.getJSON(ctx, fmt.Sprintf("repos/%s/%s/rulesets/%d", a.owner, a.repository, summary.ID), &details); err != nil {
+			panic(err)
+		}
+
+		rulesets = append(rulesets, details)
+	}
+
+	a.history.rulesets = rulesets
+	return rulesets, true
+}
+
+// warnAboutBranchProtection which may block pushes to a branch, as its settings, and whether the token can bypass
+// them, can only be read by admins
+func (a VersioningAction) warnAboutBranchProtection(ctx context.Context, branch string) {
+	var summary struct {
+		Protected  bool `json:"protected"`
+		Protection struct {
+			RequiredStatusChecks struct {
+				EnforcementLevel string   `json:"enforcement_level"`
+				Contexts         []string `json:"contexts"`
+			} `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+
+	if err := a.getJSON(ctx, fmt.Sprintf("repos/%s/%s/branches/%s", a.owner, a.repository, branch), &summary); err != nil {
+		a.logger.Debug("Can't read the branch's protection, so not checking it before releasing", "branch", branch, "error", err.Error())
+		return
+	}
+
+	if !summary.Protected {
+		return
+	}
+
+	checks := summary.Protection.RequiredStatusChecks
+	if checks.EnforcementLevel != "" && checks.EnforcementLevel != "off" {
+		a.logger.Warn(fmt.Sprintf("Branch %s requires the status checks %s, which may block pushing the commit updating %s's version files. %s.", branch, strings.Join(checks.Contexts, ", "), a.component, appTokenAdvice), "component", a.component)
+		return
+	}
+
+	a.logger.Warn(fmt.Sprintf("Branch %s is protected, which may block pushing the commit updating %s's version files. %s.", branch, a.component, appTokenAdvice), "component", a.component)
+}
+
+// getJSON from an API endpoint which go-github doesn't have a method for, eg: "repos/owner/repository/rulesets"
+func (a VersioningAction) getJSON(ctx context.Context, endpoint string, response any) error {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	request, err := a.client.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.client.Do(requestCtx, request, response)
+	var errorResponse *github.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("the github-token input can't read %s: %w", endpoint, err)
+	}
+
+	return err
+}
@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// inTotoStatementType is the type of in-toto v1 statements, see https://github.com/in-toto/attestation
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	// slsaProvenanceType is the predicate type of SLSA v1 provenance, see https://slsa.dev/provenance/v1
+	slsaProvenanceType = "https://slsa.dev/provenance/v1"
+	// workflowBuildType is the build type of GitHub Actions workflows, the same as actions/attest-build-provenance
+	workflowBuildType = "https://actions.github.io/buildtypes/workflow/v1"
+)
+
+// Workflow is the GitHub Actions workflow run which creates releases, recorded as the builder of their provenance
+type Workflow struct {
+	// URL of the GitHub server, eg: "https://github.com"
+	ServerURL string
+	// Ref of the workflow file, eg: "owner/repository/.github/workflows/release.yml@refs/heads/main"
+	Ref string
+	// Event which triggered the run, eg: "push"
+	Event string
+	// ID and attempt of the run, which identify the invocation
+	RunID      string
+	RunAttempt string
+	// Environment of the runner: "github-hosted" or "self-hosted"
+	RunnerEnvironment string
+	// IDs of the repository and its owner, which unlike their names can't be reused
+	RepositoryID      string
+	RepositoryOwnerID string
+}
+
+// ProvenanceStatement is an in-toto statement of SLSA provenance, attesting that a workflow run released a commit
+// as a tag
+type ProvenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []ProvenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     provenancePredicate `json:"predicate"`
+}
+
+// ProvenanceSubject is what a provenance statement is about: a tag, identified by the commit it points at
+type ProvenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type provenancePredicate struct {
+	BuildDefinition struct {
+		BuildType          string `json:"buildType"`
+		ExternalParameters struct {
+			Workflow struct {
+				Ref        string `json:"ref"`
+				Repository string `json:"repository"`
+				Path       string `json:"path"`
+			} `json:"workflow"`
+		} `json:"externalParameters"`
+		InternalParameters struct {
+			GitHub struct {
+				EventName         string `json:"event_name"`
+				RepositoryID      string `json:"repository_id"`
+				RepositoryOwnerID string `json:"repository_owner_id"`
+				RunnerEnvironment string `json:"runner_environment"`
+			} `json:"github"`
+		} `json:"internalParameters"`
+		ResolvedDependencies []provenanceDependency `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Metadata struct {
+			InvocationID string `json:"invocationId"`
+			StartedOn    string `json:"startedOn"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+type provenanceDependency struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+// WithProvenance attaches a SLSA provenance statement to each release, attesting that the workflow run released
+// its commit as its tag. The statement is uploaded as a release asset named after the tag, eg:
+// "api-1.2.3.intoto.json".
+func (a VersioningAction) WithProvenance(workflow Workflow) VersioningAction {
+	a.provenance = &workflow
+	return a
+}
+
+// provenanceStatement that the workflow run released a commit as a tag
+func (a VersioningAction) provenanceStatement(tagName string, sha string) ProvenanceStatement {
+	workflow := a.provenance
+	serverURL := strings.TrimSuffix(workflow.ServerURL, "/")
+	repositoryURL := fmt.Sprintf("%s/%s/%s", serverURL, a.owner, a.repository)
+	// The workflow ref is the repository, the workflow file's path in it, and the ref the workflow ran from
+	workflowPath, workflowRef, _ := strings.Cut(strings.TrimPrefix(workflow.Ref, fmt.Sprintf("%s/%s/", a.owner, a.repository)), "@")
+
+	statement := ProvenanceStatement{
+		Type:          inTotoStatementType,
+		Subject:       []ProvenanceSubject{{Name: tagName, Digest: map[string]string{"gitCommit": sha}}},
+		PredicateType: slsaProvenanceType,
+	}
+
+	definition := &statement.Predicate.BuildDefinition
+	definition.BuildType = workflowBuildType
+	definition.ExternalParameters.Workflow.Ref = workflowRef
+	definition.ExternalParameters.Workflow.Repository = repositoryURL
+	definition.ExternalParameters.Workflow.Path = workflowPath
+	definition.InternalParameters.GitHub.EventName = workflow.Event
+	definition.InternalParameters.GitHub.RepositoryID = workflow.RepositoryID
+	definition.InternalParameters.GitHub.RepositoryOwnerID = workflow.RepositoryOwnerID
+	definition.InternalParameters.GitHub.RunnerEnvironment = workflow.RunnerEnvironment
+	definition.ResolvedDependencies = []provenanceDependency{{
+		URI:    fmt.Sprintf("git+%s@%s", repositoryURL, workflowRef),
+		Digest: map[string]string{"gitCommit": sha},
+	}}
+
+	details := &statement.Predicate.RunDetails
+	details.Builder.ID = fmt.Sprintf("%s/actions/runner/%s", serverURL, workflow.RunnerEnvironment)
+	details.Metadata.InvocationID = fmt.Sprintf("%s/actions/runs/%s/attempts/%s", repositoryURL, workflow.RunID, workflow.RunAttempt)
+	details.Metadata.StartedOn = a.clock.Now().UTC().Format(time.RFC3339)
+	return statement
+}
+
+// attachProvenance uploads the provenance statement of a release as one of its assets, if provenance was enabled.
+// Returns the asset's download URL, or an empty string if provenance wasn't enabled.
+func (a VersioningAction) attachProvenance(ctx context.Context, result Result) string {
+	if a.provenance == nil {
+		return ""
+	}
+
+	tagName := result.TagName()
+	contents, err := json.MarshalIndent(a.provenanceStatement(tagName, result.Release.SHA), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	return a.uploadReleaseAsset(ctx, result.Release, tagName+".intoto.json", contents, "application/vnd.in-toto+json")
+}
@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// PublishDraft publishes the newest draft release of the component, which creates its tag. If dryRun is true,
+// the draft is found but not published. The result has no version if the component has no draft releases.
+func (a VersioningAction) PublishDraft(ctx context.Context, dryRun bool) Result {
+	result := Result{
+		Component: a.component,
+		Bump:      BumpNone,
+		tagPrefix: a.tagPrefix(),
+		latest:    a.releaseLine() == nil,
+	}
+
+	var draft *github.RepositoryRelease
+	// Releases are sorted in descending order of version, so the first draft is the newest
+	for _, release := range a.filterAndSortReleasesForComponent(a.getAllReleases(ctx)) {
+		if release.GetDraft() {
+			draft = release
+			break
+		}
+	}
+
+	if draft == nil {
+		a.logger.Info("No draft release found for component", "component", a.component)
+		return result
+	}
+
+	result.Version = a.releaseVersion(draft)
+	result.ModuleSource = a.moduleSource(result.Version)
+	result.revision = draft.GetTargetCommitish()
+	if result.Frozen = a.frozen(); result.Frozen != "" {
+		a.logger.Warn(fmt.Sprintf("Not publishing draft release %s, as %s", draft.GetName(), result.Frozen), "component", a.component)
+		return result
+	}
+
+	if dryRun {
+		a.logger.Info("Found draft release, but not publishing it as this is a dry run", "component", a.component, "release", draft.GetName())
+		return result
+	}
+
+	a.checkRevisionIsOnDefaultBranch(ctx, result.Version, result.revision)
+	a.checkMirrorHasCommit(ctx, result.revision)
+	// Point the tag and aliases at the draft's revision rather than the current one
+	published := a
+	published.revision = draft.GetTargetCommitish()
+	var tagObject []byte
+	if a.annotatedTags {
+		// Publishing uses the existing tag rather than creating a lightweight one
+		tagObject = published.createAnnotatedTag(ctx, draft.GetTagName(), draft.GetName())
+	}
+
+	a.logger.Info("Publishing draft release", "component", a.component, "release", draft.GetName(), "tag", draft.GetTagName())
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	isDraft := false
+	release, _, err := a.client.Repositories.EditRelease(requestCtx, a.owner, a.repository, draft.GetID(), a.withoutUnsupportedFields(ctx, &github.RepositoryRelease{
+		Draft:                  &isDraft,
+		MakeLatest:             a.makeLatestOrDefault(),
+		DiscussionCategoryName: a.discussionCategoryOrNone(draft.GetPrerelease()),
+	}))
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditPublishRelease, Tag: release.GetTagName(), SHA: release.GetTargetCommitish()})
+	result.Release = newRelease(release)
+	published.signTag(ctx, result.Release, release.GetTagName(), tagObject)
+	result.MirrorRelease = a.mirrorRelease(ctx, release)
+	published.updateAliasTags(ctx, result.Version)
+	a.notifyRelease(ctx, result, stripReleaseMetadata(release.GetBody()))
+	a.recordDeployment(ctx, result)
+	a.completeMilestone(ctx, result.Version)
+	result.DistTag = a.distTag(result.Version)
+	published.runHooks(ctx, HookPostRelease, result, dryRun, stripReleaseMetadata(release.GetBody()))
+	return result
+}
@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/ellisto/monorepo-versioning/pkg/changelog"
+)
+
+// DefaultChangelogFile is the path of the aggregated changelog of every component, relative to the repository root
+const DefaultChangelogFile = "CHANGELOG.md"
+
+// HistoricalRelease is a published stable release of a component, as listed in the aggregated changelog
+type HistoricalRelease struct {
+	Component string          `json:"component"`
+	Version   *semver.Version `json:"version"`
+	Tag       string          `json:"tag"`
+	Date      time.Time       `json:"date"`
+	URL       string          `json:"url"`
+	// Release notes, without the release's metadata
+	Notes string `json:"notes"`
+}
+
+// markdownHeading matches the start of a markdown heading, so that release notes can be nested under another heading
+var markdownHeading = regexp.MustCompile(`(?m)^(#{1,5}) `)
+
+// blankLines matches runs of blank lines left by empty sections of release notes
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// ReleaseHistory lists the published stable releases of every component, newest first. Releases published at the
+// same time are sorted by component name.
+func (a VersioningAction) ReleaseHistory(ctx context.Context) []HistoricalRelease {
+	var history []HistoricalRelease
+	for _, release := range publishedReleases(a.getAllReleases(ctx)) {
+		name, ok := a.releaseComponent(release)
+		if !ok {
+			continue
+		}
+
+		version := a.ForComponent(name, "").releaseVersion(release)
+		if version == nil || version.Prerelease() != "" {
+			continue
+		}
+
+		history = append(history, HistoricalRelease{
+			Component: name,
+			Version:   version,
+			Tag:       release.GetTagName(),
+			Date:      release.GetPublishedAt().Time,
+			URL:       release.GetHTMLURL(),
+			Notes:     strings.TrimSpace(blankLines.ReplaceAllString(stripIntro(stripReleaseMetadata(release.GetBody())), "\n\n")),
+		})
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		if history[i].Date.Equal(history[j].Date) {
+			return history[i].Component < history[j].Component
+		}
+
+		return history[i].Date.After(history[j].Date)
+	})
+
+	a.logger.Info("Found release history", "releases", len(history))
+	return history
+}
+
+// stripIntro removes the introduction to release notes, in any locale, which would be repeated for every release
+func stripIntro(notes string) string {
+	for _, locale := range changelog.Locales() {
+		notes = strings.Replace(notes, fmt.Sprintf("> %s\n", changelog.MessagesFor(locale).Intro), "", 1)
+	}
+
+	return notes
+}
+
+// WriteAggregatedChangelog writes the changelog of every component to a markdown file, with the releases grouped by
+// the date they were published. Headings in release notes are nested under each release's heading.
+func WriteAggregatedChangelog(path string, history []HistoricalRelease) error {
+	var changelog strings.Builder
+	changelog.WriteString("# Changelog\n")
+
+	var date string
+	for _, release := range history {
+		if releaseDate := release.Date.UTC().Format(time.DateOnly); releaseDate != date {
+			date = releaseDate
+			changelog.WriteString(fmt.Sprintf("\n## %s\n", date))
+		}
+
+		changelog.WriteString(fmt.Sprintf("\n### [%s %s](%s)\n", release.Component, release.Version.String(), release.URL))
+		if release.Notes != "" {
+			changelog.WriteString(fmt.Sprintf("\n%s\n", markdownHeading.ReplaceAllString(release.Notes, "#$1 ")))
+		}
+	}
+
+	return os.WriteFile(path, []byte(changelog.String()), 0644)
+}
+
+// WriteChangelogData writes a JSON file of each component's releases to a directory, named after the component,
+// eg: "api.json", for documentation sites to render
+func WriteChangelogData(dir string, history []HistoricalRelease) error {
+	byComponent := make(map[string][]HistoricalRelease)
+	for _, release := range history {
+		byComponent[release.Component] = append(byComponent[release.Component], release)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for component, releases := range byComponent {
+		contents, err := json.MarshalIndent(releases, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		// Component names may contain slashes, which aren't allowed in file names
+		name := strings.ReplaceAll(component, "/", "-") + ".json"
+		if err := os.WriteFile(filepath.Join(dir, name), contents, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// ReleasePleaseConfig reads the components and their current versions from release-please's configuration, so
+// that a repository can migrate from release-please without rewriting its configuration
+type ReleasePleaseConfig struct {
+	// Config is the path of release-please's configuration file. Defaults to release-please-config.json.
+	Config string `yaml:"config,omitempty"`
+	// Manifest is the path of release-please's manifest of current versions. Defaults to
+	// .release-please-manifest.json.
+	Manifest string `yaml:"manifest,omitempty"`
+}
+
+// releasePleaseOptions are the options of release-please's configuration which affect component names and tags.
+// Options of a package override the options at the top level of the configuration.
+type releasePleaseOptions struct {
+	Component             string  `json:"component"`
+	PackageName           string  `json:"package-name"`
+	IncludeComponentInTag *bool   `json:"include-component-in-tag"`
+	IncludeVInTag         *bool   `json:"include-v-in-tag"`
+	TagSeparator          *string `json:"tag-separator"`
+}
+
+type releasePleaseFile struct {
+	releasePleaseOptions
+	Packages map[string]releasePleaseOptions `json:"packages"`
+}
+
+// tagName is the tag release-please created for a version of the package
+func (o releasePleaseOptions) tagName(component string, version string) string {
+	tag := version
+	if o.IncludeVInTag == nil || *o.IncludeVInTag {
+		tag = "v" + tag
+	}
+
+	if component != "" && (o.IncludeComponentInTag == nil || *o.IncludeComponentInTag) {
+		separator := "-"
+		if o.TagSeparator != nil {
+			separator = *o.TagSeparator
+		}
+
+		tag = component + separator + tag
+	}
+
+	return tag
+}
+
+// withDefaults fills in the package's options from the top level of the configuration
+func (o releasePleaseOptions) withDefaults(defaults releasePleaseOptions) releasePleaseOptions {
+	if o.IncludeComponentInTag == nil {
+		o.IncludeComponentInTag = defaults.IncludeComponentInTag
+	}
+
+	if o.IncludeVInTag == nil {
+		o.IncludeVInTag = defaults.IncludeVInTag
+	}
+
+	if o.TagSeparator == nil {
+		o.TagSeparator = defaults.TagSeparator
+	}
+
+	return o
+}
+
+// withReleasePlease adds a component for each package in release-please's configuration, with the version in
+// its manifest as the baseline. Components which are already configured keep their configuration, but gain the
+// baseline if they don't have one.
+func (c Config) withReleasePlease() (Config, error) {
+	if c.ReleasePlease == nil {
+		return c, nil
+	}
+
+	configPath := c.ReleasePlease.Config
+	if configPath == "" {
+		configPath = "release-please-config.json"
+	}
+
+	manifestPath := c.ReleasePlease.Manifest
+	if manifestPath == "" {
+		manifestPath = ".release-please-manifest.json"
+	}
+
+	var file releasePleaseFile
+	if err := readJSONFile(configPath, &file); err != nil {
+		return c, err
+	}
+
+	versions := make(map[string]string)
+	if err := readJSONFile(manifestPath, &versions); err != nil {
+		return c, err
+	}
+
+	components := make(map[string]ComponentConfig)
+	for name, component := range c.Components {
+		components[name] = component
+	}
+
+	// Sort so that errors are reported in the same order on every run
+	var packagePaths []string
+	for packagePath := range file.Packages {
+		packagePaths = append(packagePaths, packagePath)
+	}
+
+	sort.Strings(packagePaths)
+	for _, packagePath := range packagePaths {
+		options := file.Packages[packagePath].withDefaults(file.releasePleaseOptions)
+		name := options.Component
+		if name == "" && options.PackageName != "" {
+			// Scoped npm packages, eg: @org/api, are released as the name after the scope
+			name = path.Base(options.PackageName)
+		}
+
+		if name == "" && packagePath != "." {
+			name = path.Base(packagePath)
+		}
+
+		if name == "" {
+			return c, fmt.Errorf("release-please package %s has no component or package-name to name the component after", packagePath)
+		}
+
+		component := c.Component(name)
+		if component.Path == "" {
+			component.Path = path.Clean(packagePath)
+		}
+
+		if component.Package == "" {
+			component.Package = options.PackageName
+		}
+
+		if version, ok := versions[packagePath]; ok && component.BaselineVersion == "" {
+			if _, err := semver.NewVersion(version); err != nil {
+				return c, fmt.Errorf("release-please manifest %s has an invalid version %q for %s: %w", manifestPath, version, packagePath, err)
+			}
+
+			component.BaselineVersion = version
+			component.BaselineTag = options.tagName(name, version)
+		}
+
+		components[strings.ToLower(name)] = component
+	}
+
+	c.Components = components
+	return c, nil
+}
+
+// readJSONFile decodes a JSON file into value
+func readJSONFile(filePath string, value any) error {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(contents, value); err != nil {
+		return fmt.Errorf("invalid JSON file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// existingVersionOrBaseline gets the newest version of the component, falling back to the baseline version
+// released by another tool, or the initial version if the component has never been released
+func (a VersioningAction) existingVersionOrBaseline(existingReleases []*github.RepositoryRelease) (*semver.Version, bool) {
+	baseline := a.componentConfig().BaselineVersion
+	if len(existingReleases) == 0 && baseline != "" {
+		a.logger.Info("No existing releases for component, will use baseline version", "component", a.component, "baselineVersion", baseline)
+		return semver.MustParse(baseline), false
+	}
+
+	return a.existingVersionOrNew(existingReleases)
+}
+
+// getBaselineChange gets the commit the component's baseline tag points at, or nil if there is no baseline tag, in
+// which case every commit is considered
+func (a VersioningAction) getBaselineChange(ctx context.Context) *changePoint {
+	tagName := a.componentConfig().BaselineTag
+	if tagName == "" {
+		return nil
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	_, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, fmt.Sprintf("refs/tags/%s", tagName))
+	cancel()
+	if isNotFound(err) {
+		a.logger.Warn("Baseline tag does not exist, so every commit will be considered", "component", a.component, "tag", tagName)
+		return nil
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.logger.Info("Using baseline tag for change comparison", "component", a.component, "tag", tagName)
+	change := a.getTagChange(ctx, tagName)
+	return &change
+}
@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// releasePullRequestBranch is the branch the component's release pull request is opened from
+func (a VersioningAction) releasePullRequestBranch() string {
+	return fmt.Sprintf("monorepo-versioning/release-%s", strings.ToLower(a.component))
+}
+
+// releasePullRequestTitle includes the version, so that the version reviewed is the version released when the
+// pull request is merged
+func (a VersioningAction) releasePullRequestTitle(version *semver.Version) string {
+	name := a.component
+	if a.label != "" {
+		name = a.label
+	}
+
+	return fmt.Sprintf("Release %s %s", name, version.String())
+}
+
+// mergedReleasePullRequestVersion gets the version of the component's release pull request which was merged as
+// the current revision, or nil if the current revision isn't a merged release pull request
+func (a VersioningAction) mergedReleasePullRequestVersion(ctx context.Context) *semver.Version {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	pullRequests, _, err := a.client.PullRequests.ListPullRequestsWithCommit(requestCtx, a.owner, a.repository, a.revision, nil)
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, pullRequest := range pullRequests {
+		if pullRequest.GetMergedAt().IsZero() || pullRequest.GetHead().GetRef() != a.releasePullRequestBranch() {
+			continue
+		}
+
+		// The title ends with the version, see releasePullRequestTitle
+		title := strings.Fields(pullRequest.GetTitle())
+		version, err := semver.NewVersion(title[len(title)-1])
+		if err != nil {
+			panic(fmt.Sprintf("Release pull request #%d has no version in its title %q: %s", pullRequest.GetNumber(), pullRequest.GetTitle(), err))
+		}
+
+		a.logger.Info("Current revision is a merged release pull request", "component", a.component, "number", pullRequest.GetNumber(), "version", version.String())
+		return version
+	}
+
+	return nil
+}
+
+// openReleasePullRequest opens a pull request into the current branch which updates the component's version files
+// and changelog for the new version. If the pull request is already open, it is updated to the latest changes, so
+// there is only ever one release pull request per component. Returns the pull request's URL.
+func (a VersioningAction) openReleasePullRequest(ctx context.Context, version *semver.Version, commits []*github.RepositoryCommit) string {
+	releaseNotes := a.generateReleaseNotes(ctx, commits)
+	entries := a.versionFileEntries(ctx, version)
+
+	changelogPath := path.Join(a.componentConfig().Path, "CHANGELOG.md")
+	changelog, _ := a.findFileContents(ctx, changelogPath, a.revision)
+	entries = append(entries, &github.TreeEntry{
+		Path:    github.String(changelogPath),
+		Mode:    github.String("100644"),
+		Type:    github.String("blob"),
+		Content: github.String(fmt.Sprintf("## %s\n%s\n%s", version.String(), releaseNotes, changelog)),
+	})
+
+	title := a.releasePullRequestTitle(version)
+	message := fmt.Sprintf("chore(%s): release %s", a.component, version.String())
+	commitSHA := a.createCommit(ctx, a.revision, message, entries)
+	branch := a.releasePullRequestBranch()
+	a.forcePushBranch(ctx, branch, commitSHA)
+
+	body := fmt.Sprintf("Merging this pull request releases %s.\n\n%s", a.tagName(version.String()), releaseNotes)
+	return a.openOrUpdatePullRequest(ctx, branch, a.branch, title, body).GetHTMLURL()
+}
@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// ForRepository creates a copy of the action for another repository of the same client, in the format
+// "owner/repository", eg: to find the current versions of several repositories from a central workflow. The copy
+// has its own cache of the repository's history, and keeps the action's revision, so only use it to generate
+// versions after WithRevision is set to a commit of that repository.
+func (a VersioningAction) ForRepository(ownerAndRepository string) VersioningAction {
+	owner, repository, ok := strings.Cut(ownerAndRepository, "/")
+	if !ok || owner == "" || repository == "" || strings.Contains(repository, "/") {
+		panic(fmt.Sprintf("repository %q must be in the format owner/repository", ownerAndRepository))
+	}
+
+	a.owner, a.repository = owner, repository
+	a.history = newRepositoryHistory()
+	return a
+}
+
+// Repository the action versions, in the format "owner/repository"
+func (a VersioningAction) Repository() string {
+	return fmt.Sprintf("%s/%s", a.owner, a.repository)
+}
+
+// BranchHead gets the SHA of the newest commit of a branch of a repository, in the format "owner/repository", so
+// that a repository other than the workflow's can be versioned at its branch's head
+func BranchHead(ctx context.Context, client *github.Client, ownerAndRepository string, branch string) (string, error) {
+	owner, repository, _ := strings.Cut(ownerAndRepository, "/")
+	ref, _, err := client.Git.GetRef(ctx, owner, repository, "refs/heads/"+branch)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get the head of branch %s of %s: %w", branch, ownerAndRepository, err)
+	}
+
+	return ref.GetObject().GetSHA(), nil
+}
@@ -0,0 +1,169 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// existingReleaseForTag finds the release of a tag, eg: one created by an earlier attempt of the run, or nil if the
+// tag has no release
+func (a VersioningAction) existingReleaseForTag(ctx context.Context, tagName string) *github.RepositoryRelease {
+	for _, release := range a.getAllReleases(ctx) {
+		if strings.EqualFold(release.GetTagName(), tagName) {
+			return release
+		}
+	}
+
+	return nil
+}
+
+// releasedRevision is the commit a release was created from. Drafts don't have a tag yet, so it's the commit the
+// tag will be created at.
+func (a VersioningAction) releasedRevision(ctx context.Context, release *github.RepositoryRelease) string {
+	if release.GetDraft() {
+		return release.GetTargetCommitish()
+	}
+
+	return a.getTagChange(ctx, release.GetTagName()).sha
+}
+
+// updateReleaseNotes replaces the notes of an existing release, logging what changed, rather than failing to create
+// the release again. Returns the release, which is unchanged if its notes already match.
+func (a VersioningAction) updateReleaseNotes(ctx context.Context, release *github.RepositoryRelease, body string, dryRun bool) (*github.RepositoryRelease, bool) {
+	diff := notesDiff(release.GetBody(), body)
+	if diff == "" {
+		a.logger.Info("Release notes are up to date", "component", a.component, "tag", release.GetTagName())
+		return release, false
+	}
+
+	if dryRun {
+		a.logger.Info("Would update release notes, but this is a dry run", "component", a.component, "tag", release.GetTagName(), "diff", diff)
+		return release, true
+	}
+
+	a.logger.Info("Updating release notes", "component", a.component, "tag", release.GetTagName(), "diff", diff)
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	updated, _, err := a.client.Repositories.EditRelease(requestCtx, a.owner, a.repository, release.GetID(), &github.RepositoryRelease{Body: &body})
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditUpdateRelease, Tag: release.GetTagName(), SHA: release.GetTargetCommitish()})
+	a.replaceListedRelease(updated)
+	return updated, true
+}
+
+// replaceListedRelease with its edited version in the releases listed by the run, so that later steps see the edit
+func (a VersioningAction) replaceListedRelease(release *github.RepositoryRelease) {
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	for i, listed := range a.history.releases {
+		if listed.GetID() == release.GetID() {
+			a.history.releases[i] = release
+		}
+	}
+}
+
+// RegenerateNotes generates the release notes of released versions of the component again, eg: after changing the
+// locale or how commits are attributed, and updates the releases whose notes changed. If version is nil, every
+// published release of the component is regenerated. Yank banners and release metadata are kept. If dryRun is true,
+// the changes are only logged. Returns the tags of the releases whose notes changed.
+func (a VersioningAction) RegenerateNotes(ctx context.Context, version *semver.Version, dryRun bool) []string {
+	allReleases := a.getAllReleases(ctx)
+	stableReleases := publishedReleases(a.filterAndSortReleasesForComponent(allReleases))
+	releases := stableReleases
+	if version != nil {
+		tagName := a.releasedTagName(allReleases, version)
+		release := a.existingReleaseForTag(ctx, tagName)
+		if release == nil {
+			panic(fmt.Sprintf("%s has no release to regenerate notes of", tagName))
+		}
+
+		releases = []*github.RepositoryRelease{release}
+	}
+
+	var updatedTags []string
+	for _, release := range releases {
+		releaseVersion := a.releaseVersion(release)
+		regenerated := a
+		regenerated.revision = a.getTagChange(ctx, release.GetTagName()).sha
+		var since *changePoint
+		// Releases are sorted in descending order of version, so the first lower version is the previous release
+		for _, previous := range stableReleases {
+			if a.releaseVersion(previous).LessThan(releaseVersion) {
+				change := a.getTagChange(ctx, previous.GetTagName())
+				since = &change
+				break
+			}
+		}
+
+		commits := regenerated.applyMergeCommitPolicy(a.getNewCommits(ctx, since, regenerated.revision))
+		a.logger.Info("Regenerating release notes", "component", a.component, "tag", release.GetTagName(), "commits", len(commits))
+		body := yankedBannerOf(release.GetBody()) + regenerated.releasePreview(ctx, releaseVersion, commits).Notes
+		if metadata, ok := ParseReleaseMetadata(release.GetBody()); ok {
+			body += metadata.block()
+		}
+
+		if _, updated := a.updateReleaseNotes(ctx, release, body, dryRun); updated {
+			updatedTags = append(updatedTags, release.GetTagName())
+		}
+	}
+
+	return updatedTags
+}
+
+// yankedBannerOf the notes of a release, or empty if it wasn't yanked
+func yankedBannerOf(body string) string {
+	if index := strings.Index(body, yankedMarker); index >= 0 {
+		return body[:index+len(yankedMarker)] + "\n\n"
+	}
+
+	return ""
+}
+
+// notesDiff is the lines removed from and added to release notes, prefixed with - and +, or empty if they're the
+// same
+func notesDiff(before string, after string) string {
+	if before == after {
+		return ""
+	}
+
+	old, updated := strings.Split(before, "\n"), strings.Split(after, "\n")
+	// Longest common subsequence of the lines, so that only the lines which changed are listed
+	common := make([][]int, len(old)+1)
+	for i := range common {
+		common[i] = make([]int, len(updated)+1)
+	}
+
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(updated) - 1; j >= 0; j-- {
+			if old[i] == updated[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(old) || j < len(updated) {
+		switch {
+		case i < len(old) && j < len(updated) && old[i] == updated[j]:
+			i, j = i+1, j+1
+		case i < len(old) && (j == len(updated) || common[i+1][j] >= common[i][j+1]):
+			diff.WriteString(fmt.Sprintf("- %s\n", old[i]))
+			i++
+		default:
+			diff.WriteString(fmt.Sprintf("+ %s\n", updated[j]))
+			j++
+		}
+	}
+
+	return diff.String()
+}
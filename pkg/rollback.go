@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// Rollback deletes the release and tag of a version of the component, so that a failed deployment of the version
+// can be cleanly undone. The version must have been released from the current revision, which prevents a re-run
+// of an old pipeline from deleting a newer release. Alias tags are moved back to the previous stable release. If
+// dryRun is true, the release is only logged. Returns false if the version has no release, so that a rollback
+// can safely be retried.
+func (a VersioningAction) Rollback(ctx context.Context, version *semver.Version, dryRun bool) bool {
+	tagName := a.tagName(version.String())
+	var release *github.RepositoryRelease
+	for _, existingRelease := range a.getAllReleases(ctx) {
+		if strings.EqualFold(existingRelease.GetTagName(), tagName) {
+			release = existingRelease
+			break
+		}
+	}
+
+	if release == nil {
+		a.logger.Warn("No release found to roll back", "component", a.component, "tag", tagName)
+		return false
+	}
+
+	// Drafts don't have a tag yet, so use the commit the tag will be created at
+	releasedRevision := release.GetTargetCommitish()
+	if !release.GetDraft() {
+		releasedRevision = a.getTagCommitSHA(ctx, release.GetTagName())
+	}
+
+	if releasedRevision != a.revision {
+		panic(fmt.Sprintf("Refusing to roll back %s: it was released from %s, not the current revision %s", tagName, releasedRevision, a.revision))
+	}
+
+	if dryRun {
+		a.logger.Info("Would roll back release, but this is a dry run", "component", a.component, "tag", tagName)
+		return true
+	}
+
+	a.logger.Info("Rolling back release", "component", a.component, "tag", tagName)
+	a.deleteRelease(ctx, release)
+
+	if version.Prerelease() == "" && !release.GetDraft() {
+		var remainingReleases []*github.RepositoryRelease
+		for _, existingRelease := range publishedReleases(a.filterAndSortReleasesForComponent(a.getAllReleases(ctx))) {
+			if existingRelease.GetID() != release.GetID() {
+				remainingReleases = append(remainingReleases, existingRelease)
+			}
+		}
+
+		a.restoreAliasTags(ctx, version, remainingReleases)
+	}
+
+	return true
+}
+
+// restoreAliasTags moves the alias tags of a rolled back version to the newest remaining release they alias.
+// Releases must be sorted in descending order of version.
+func (a VersioningAction) restoreAliasTags(ctx context.Context, rolledBack *semver.Version, releases []*github.RepositoryRelease) {
+	for _, alias := range a.aliasTags {
+		var previous *github.RepositoryRelease
+		for _, release := range releases {
+			version := a.releaseVersion(release)
+			if alias == AliasLatest || version.Major() == rolledBack.Major() {
+				previous = release
+				break
+			}
+		}
+
+		if previous == nil {
+			// Nothing left to alias, so leave the tag in place rather than breaking consumers
+			a.logger.Warn("No previous release to move alias tag to", "component", a.component, "alias", alias)
+			continue
+		}
+
+		restored := a
+		restored.revision = a.getTagCommitSHA(ctx, previous.GetTagName())
+		version := a.releaseVersion(previous)
+		restored.aliasTags = []string{alias}
+		restored.updateAliasTags(ctx, version)
+	}
+}
@@ -0,0 +1,262 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+// SBOMGo generates an SPDX SBOM of a Go component's module dependencies, instead of attaching an existing file
+const SBOMGo = "go"
+
+// sbomSuffixes are the standard file name suffixes of SBOM formats, which asset names keep so tools recognise them
+var sbomSuffixes = []string{".spdx.json", ".spdx", ".cdx.json", ".cdx.xml", ".bom.json", ".bom.xml"}
+
+// WithSBOM attaches an SBOM to each release, as an asset named after the tag, eg: "api-1.2.3.spdx.json". The
+// source is the path of an existing SBOM file, where "{component}" is replaced with the component name, or SBOMGo
+// to generate one from the Go module in the component's path.
+func (a VersioningAction) WithSBOM(source string) VersioningAction {
+	a.sbom = source
+	return a
+}
+
+// releaseSBOM reads or generates the SBOM of a version, returning the name of its asset and its contents, or an
+// empty name if no SBOM is attached. It's called before the release is created, so a missing SBOM doesn't leave
+// a release without one.
+func (a VersioningAction) releaseSBOM(ctx context.Context, version *semver.Version) (string, []byte) {
+	if a.sbom == "" {
+		return "", nil
+	}
+
+	tagName := a.tagName(version.String())
+	if a.sbom == SBOMGo {
+		return tagName + ".spdx.json", a.goSBOM(ctx, tagName, version)
+	}
+
+	path := strings.ReplaceAll(a.sbom, "{component}", a.component)
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("Could not read the SBOM of %s: %s", a.component, err))
+	}
+
+	return tagName + sbomSuffix(path), contents
+}
+
+// sbomSuffix of an SBOM file's name, falling back to ".sbom" and the file's extension for unknown formats
+func sbomSuffix(path string) string {
+	for _, suffix := range sbomSuffixes {
+		if strings.HasSuffix(strings.ToLower(path), suffix) {
+			return suffix
+		}
+	}
+
+	return ".sbom" + strings.ToLower(filepath.Ext(path))
+}
+
+// attachSBOM uploads an SBOM read by releaseSBOM, returning its download URL, or an empty string if there's none
+func (a VersioningAction) attachSBOM(ctx context.Context, release *Release, name string, contents []byte) string {
+	if name == "" {
+		return ""
+	}
+
+	mediaType := "application/json"
+	if strings.HasSuffix(name, ".xml") {
+		mediaType = "application/xml"
+	} else if strings.HasSuffix(name, ".spdx") {
+		mediaType = "text/spdx"
+	}
+
+	return a.uploadReleaseAsset(ctx, release, name, contents, mediaType)
+}
+
+// goModule is a module in a Go module's build list, as listed by "go list -m -json"
+type goModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Replace *goModule
+}
+
+// spdxDocument is an SPDX 2.3 document, see https://spdx.github.io/spdx-spec/v2.3/
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages      []spdxPackage      `json:"packages"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// goSBOM generates an SPDX SBOM of the Go module in the component's path, where the module itself is the version
+// being released and depends on every other module in its build list
+func (a VersioningAction) goSBOM(ctx context.Context, tagName string, version *semver.Version) []byte {
+	dir := a.componentConfig().Path
+	if dir == "" {
+		dir = "."
+	}
+
+	modules, err := goBuildList(ctx, dir)
+	if err != nil {
+		panic(fmt.Sprintf("Could not list the Go modules of %s: %s", a.component, err))
+	}
+
+	document := spdxDocument{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      "SPDXRef-DOCUMENT",
+		Name:        tagName,
+		// The namespace only has to be unique, so it's named after the tag and commit like other tools do
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", tagName, a.revision),
+	}
+
+	document.CreationInfo.Created = a.clock.Now().UTC().Format(time.RFC3339)
+	document.CreationInfo.Creators = []string{"Tool: monorepo-versioning"}
+	for i, module := range modules {
+		if module.Replace != nil {
+			// The replacement is what's built, unless it's a local directory
+			if module.Replace.Version != "" {
+				module = *module.Replace
+			}
+		}
+
+		moduleVersion := module.Version
+		if module.Main {
+			moduleVersion = "v" + version.String()
+		}
+
+		id := fmt.Sprintf("SPDXRef-Package-%d", i)
+		document.Packages = append(document.Packages, spdxPackage{
+			Name:             module.Path,
+			SPDXID:           id,
+			VersionInfo:      moduleVersion,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  fmt.Sprintf("pkg:golang/%s@%s", module.Path, moduleVersion),
+			}},
+		})
+
+		relationship := spdxRelationship{SPDXElementID: "SPDXRef-Package-0", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: id}
+		if module.Main {
+			relationship = spdxRelationship{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: id}
+		}
+
+		document.Relationships = append(document.Relationships, relationship)
+	}
+
+	contents, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	return contents
+}
+
+// goBuildList lists the modules in the build list of the Go module in dir, main module first. The Go toolchain
+// isn't in the action's image, so without it the requirements in go.mod are listed instead, which since Go 1.17
+// include every module needed to build the main module.
+func goBuildList(ctx context.Context, dir string) ([]goModule, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return readGoMod(filepath.Join(dir, "go.mod"))
+	}
+
+	command := exec.CommandContext(ctx, "go", "list", "-m", "-json", "all")
+	command.Dir = dir
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var modules []goModule
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var module goModule
+		if err := decoder.Decode(&module); errors.Is(err, io.EOF) {
+			return modules, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		modules = append(modules, module)
+	}
+}
+
+// readGoMod lists the main module and the modules required by a go.mod file
+func readGoMod(path string) ([]goModule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	var modules []goModule
+	inRequire := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 2 && fields[0] == "module":
+			modules = append([]goModule{{Path: strings.Trim(fields[1], `"`), Main: true}}, modules...)
+		case len(fields) == 2 && fields[0] == "require" && fields[1] == "(":
+			inRequire = true
+		case len(fields) == 1 && fields[0] == ")":
+			inRequire = false
+		case len(fields) == 3 && fields[0] == "require":
+			fields = fields[1:]
+			fallthrough
+		case len(fields) == 2 && inRequire:
+			modules = append(modules, goModule{Path: fields[0], Version: fields[1]})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(modules) == 0 || !modules[0].Main {
+		return nil, fmt.Errorf("%s has no module directive", path)
+	}
+
+	return modules, nil
+}
@@ -0,0 +1,174 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scaffold is a starter configuration generated by the init operation, with notes about anything which needs
+// reviewing before it's used
+type Scaffold struct {
+	Config Config
+	Notes  []string
+}
+
+// note something for the user to review in the generated configuration
+func (s *Scaffold) note(format string, args ...any) {
+	s.Notes = append(s.Notes, fmt.Sprintf(format, args...))
+}
+
+// ConfigYAML renders the configuration file, with the notes as comments at the top so they're seen when the file
+// is reviewed
+func (s Scaffold) ConfigYAML() []byte {
+	buffer := bytes.Buffer{}
+	buffer.WriteString("# Generated by the monorepo-versioning init operation\n")
+	for _, note := range s.Notes {
+		buffer.WriteString(fmt.Sprintf("# TODO: %s\n", note))
+	}
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(s.Config); err != nil {
+		panic(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// WorkflowYAML renders a sample workflow which versions every component of the configuration on each push to
+// the default branch
+func (s Scaffold) WorkflowYAML() []byte {
+	return []byte(fmt.Sprintf(`# Generated by the monorepo-versioning init operation
+name: Release
+on:
+  push:
+    # TODO: change to the repository's default branch, if it isn't main
+    branches: [main]
+
+permissions:
+  contents: write
+
+jobs:
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: ellisto/monorepo-versioning@main
+        id: versioning
+        with:
+          github-token: ${{ secrets.GITHUB_TOKEN }}
+          component: '%s'
+`, strings.Join(s.Config.ComponentNames(), ",")))
+}
+
+// InitFromManifests proposes a component for each directory of the repository checked out at root which contains
+// the manifest of a supported ecosystem, named after the directory, with its well-known version files. If there
+// are no such directories, the repository is proposed as a single component.
+func InitFromManifests(root string) (Scaffold, error) {
+	scaffold := Scaffold{Config: Config{Components: make(map[string]ComponentConfig)}}
+	err := walkRepository(root, func(directory string) error {
+		if directory == "." || !hasAnyFile(root, directory, discoveryManifestNames()) {
+			return nil
+		}
+
+		name := strings.ToLower(path.Base(directory))
+		if existing, ok := scaffold.Config.Components[name]; ok {
+			scaffold.note("Found component %s in %s, but a component with the same name is in %s, so it was skipped. Add it with a different name", name, directory, existing.Path)
+			return nil
+		}
+
+		scaffold.Config.Components[name] = ComponentConfig{Path: directory, VersionFiles: wellKnownVersionFiles(root, directory)}
+		return nil
+	})
+
+	if err != nil {
+		return scaffold, err
+	}
+
+	if len(scaffold.Config.Components) == 0 {
+		absolute, err := filepath.Abs(root)
+		if err != nil {
+			return scaffold, err
+		}
+
+		name := strings.ToLower(filepath.Base(absolute))
+		scaffold.Config.Components[name] = ComponentConfig{VersionFiles: wellKnownVersionFiles(root, ".")}
+		scaffold.note("No components were found, so the whole repository is the component %s. Rename it to match the scope of its commits", name)
+	}
+
+	return scaffold, nil
+}
+
+// discoveryManifestNames are the file names of the manifests of every supported ecosystem
+func discoveryManifestNames() []string {
+	var names []string
+	for _, name := range discoveryManifests {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// wellKnownVersionFiles are the files in the directory whose version can be updated without configuring a regex
+func wellKnownVersionFiles(root string, directory string) []VersionFile {
+	var names []string
+	for name := range versionFileDefaults {
+		names = append(names, name)
+	}
+
+	// Sort so that the generated configuration is the same on every run
+	sort.Strings(names)
+	var files []VersionFile
+	for _, name := range names {
+		if hasAnyFile(root, directory, []string{name}) {
+			files = append(files, VersionFile{Path: name})
+		}
+	}
+
+	return files
+}
+
+// hasAnyFile checks whether the directory contains a file with any of the names
+func hasAnyFile(root string, directory string, names []string) bool {
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(directory), name)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// walkRepository calls visit with the slash-separated path, relative to root, of each directory in the repository
+// which may contain a component, skipping dependency, fixture and hidden directories
+func walkRepository(root string, visit func(directory string) error) error {
+	return filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		relative, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+
+		name := entry.Name()
+		if relative != "." && (ignoredDiscoveryDirectories[name] || strings.HasPrefix(name, ".")) {
+			return filepath.SkipDir
+		}
+
+		return visit(filepath.ToSlash(relative))
+	})
+}
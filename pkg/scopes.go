@@ -0,0 +1,122 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/leodido/go-conventionalcommits"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// ScopeNormalizationNone compares scopes as they're written. It's the default.
+	ScopeNormalizationNone = "none"
+	// ScopeNormalizationNFC compares scopes in Unicode normalization form C, so that eg: "é" matches whether it's
+	// written as one code point or as "e" and a combining accent
+	ScopeNormalizationNFC = "nfc"
+	// ScopeNormalizationNFKC compares scopes in Unicode normalization form KC, which also matches compatibility
+	// characters, eg: full-width "ａｐｉ" matches "api"
+	ScopeNormalizationNFKC = "nfkc"
+)
+
+// ScopeMatchingConfig configures how commit scopes are compared with component names and scope patterns
+type ScopeMatchingConfig struct {
+	// CaseSensitive scopes only match names and patterns in the same case, so that eg: "API" and "api" can be
+	// different components
+	CaseSensitive bool `yaml:"case-sensitive,omitempty"`
+	// Normalization of scopes, names, and patterns before they're compared: ScopeNormalizationNone,
+	// ScopeNormalizationNFC, or ScopeNormalizationNFKC
+	Normalization string `yaml:"normalization,omitempty"`
+}
+
+// validate the scope matching configuration
+func (c ScopeMatchingConfig) validate() error {
+	switch c.Normalization {
+	case "", ScopeNormalizationNone, ScopeNormalizationNFC, ScopeNormalizationNFKC:
+		return nil
+	default:
+		return fmt.Errorf("invalid scope-matching normalization %q, expected one of: %s, %s, %s", c.Normalization, ScopeNormalizationNone, ScopeNormalizationNFC, ScopeNormalizationNFKC)
+	}
+}
+
+// normalize a scope, name, or pattern so it can be compared. Scope regexes aren't case folded, as they can be
+// made case-insensitive with (?i).
+func (c ScopeMatchingConfig) normalize(value string, fold bool) string {
+	switch c.Normalization {
+	case ScopeNormalizationNFC:
+		value = norm.NFC.String(value)
+	case ScopeNormalizationNFKC:
+		value = norm.NFKC.String(value)
+	}
+
+	if fold && !c.CaseSensitive {
+		return cases.Fold().String(value)
+	}
+
+	return value
+}
+
+// configured checks whether scope matching differs from the Conventional Commits parser's, which lowercases
+// scopes and only allows ASCII in them
+func (c ScopeMatchingConfig) configured() bool {
+	return c.CaseSensitive || (c.Normalization != "" && c.Normalization != ScopeNormalizationNone)
+}
+
+// headerScope matches the scope of a conventional commit's header, eg: "API" in "feat(API)!: add users"
+var headerScope = regexp.MustCompile(`^([^\s():!]+)\(([^()\r\n]+)\)(!?:)`)
+
+// errNotConventional is returned for messages which parse, but not as conventional commits
+var errNotConventional = errors.New("not a conventional commit")
+
+// parseCommit parses a commit's message as a conventional commit. If scope matching is configured, the scope is
+// kept as it's written rather than as the parser reads it, so that it's compared in its own case and can contain
+// any Unicode characters.
+func (a VersioningAction) parseCommit(commit *github.RepositoryCommit) (*conventionalcommits.ConventionalCommit, error) {
+	message := commit.GetCommit().GetMessage()
+	scope := ""
+	if a.config.ScopeMatching.configured() {
+		if match := headerScope.FindStringSubmatch(message); match != nil {
+			scope = match[2]
+			message = headerScope.ReplaceAllString(message, "${1}(scope)${3}")
+		}
+	}
+
+	a.history.parsing.Lock()
+	parsedMessage, err := a.parser.Parse([]byte(message))
+	a.history.parsing.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	conventionalCommit, ok := parsedMessage.(*conventionalcommits.ConventionalCommit)
+	if !ok {
+		return nil, errNotConventional
+	}
+
+	if scope != "" {
+		conventionalCommit.Scope = &scope
+	}
+
+	return conventionalCommit, nil
+}
+
+// matchesScope checks whether a commit scope refers to the component. The component's name always matches,
+// case-insensitively unless scope matching is case-sensitive, as do any configured glob patterns and regex.
+func (a VersioningAction) matchesScope(scope string) bool {
+	matching := a.config.ScopeMatching
+	if matching.normalize(scope, true) == matching.normalize(a.component, true) {
+		return true
+	}
+
+	config := a.componentConfig()
+	for _, pattern := range config.Scopes {
+		if compiled, err := compileGlob(matching.normalize(pattern, true)); err == nil && compiled.MatchString(matching.normalize(scope, true)) {
+			return true
+		}
+	}
+
+	return config.ScopeRegex != "" && regexp.MustCompile(config.ScopeRegex).MatchString(matching.normalize(scope, false))
+}
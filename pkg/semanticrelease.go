@@ -0,0 +1,285 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// semanticReleaseFiles are the configuration files semantic-release reads, in order of precedence. YAML is a
+// superset of JSON, so each file can be read as YAML.
+var semanticReleaseFiles = []string{".releaserc", ".releaserc.json", ".releaserc.yaml", ".releaserc.yml"}
+
+// semanticReleaseScriptFiles configure semantic-release with JavaScript, which can't be evaluated
+var semanticReleaseScriptFiles = []string{"release.config.js", "release.config.cjs", "release.config.mjs", ".releaserc.js", ".releaserc.cjs"}
+
+// semanticReleaseBuiltinPlugins are semantic-release plugins whose behaviour the action has built in
+var semanticReleaseBuiltinPlugins = map[string]bool{
+	"@semantic-release/commit-analyzer":         true,
+	"@semantic-release/release-notes-generator": true,
+	"@semantic-release/github":                  true,
+	"semantic-release-monorepo":                 true,
+}
+
+// maintenanceRangePattern matches semantic-release's maintenance branch ranges, eg: "1.x" or "1.2.x"
+var maintenanceRangePattern = regexp.MustCompile(`^\d+(\.\d+)?\.x$`)
+
+type semanticReleaseConfig struct {
+	Branches  any    `yaml:"branches"`
+	TagFormat string `yaml:"tagFormat"`
+	Plugins   []any  `yaml:"plugins"`
+	Extends   any    `yaml:"extends"`
+}
+
+// extendsMonorepo checks whether the configuration extends semantic-release-monorepo, which changes the default
+// tag format to include the package name
+func (c semanticReleaseConfig) extendsMonorepo() bool {
+	extends, ok := c.Extends.([]any)
+	if name, isName := c.Extends.(string); isName {
+		extends, ok = []any{name}, true
+	}
+
+	for _, name := range extends {
+		if ok && name == "semantic-release-monorepo" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InitFromSemanticRelease generates a configuration equivalent to the semantic-release configuration of the
+// repository checked out at root. The configuration at the root of the repository maps to the release channels,
+// and each package with its own configuration, as with semantic-release-monorepo, becomes a component. Anything
+// which can't be mapped is noted.
+func InitFromSemanticRelease(root string) (Scaffold, error) {
+	var scaffold Scaffold
+	configs := make(map[string]semanticReleaseConfig)
+	err := walkRepository(root, func(directory string) error {
+		config, found, err := readSemanticReleaseConfig(root, directory, &scaffold)
+		if found {
+			configs[directory] = config
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return scaffold, err
+	}
+
+	if len(configs) == 0 {
+		return scaffold, errors.New("no semantic-release configuration found, expected a .releaserc file or a release key in package.json")
+	}
+
+	var directories []string
+	for directory := range configs {
+		directories = append(directories, directory)
+	}
+
+	sort.Strings(directories)
+	rootConfig, hasRootConfig := configs["."]
+	if hasRootConfig {
+		scaffold.Config.Channels = semanticReleaseChannels(rootConfig.Branches, &scaffold)
+	}
+
+	scaffold.Config.Components = make(map[string]ComponentConfig)
+	for _, directory := range directories {
+		if directory == "." && len(directories) > 1 {
+			// The root configuration is shared by the packages, rather than being a component of its own
+			continue
+		}
+
+		config := configs[directory]
+		name := packageName(root, directory)
+		component := ComponentConfig{}
+		if directory != "." {
+			component.Path = directory
+		}
+
+		plugins := config.Plugins
+		if len(plugins) == 0 && hasRootConfig {
+			plugins = rootConfig.Plugins
+		}
+
+		for _, plugin := range plugins {
+			semanticReleasePlugin(plugin, name, &component, &scaffold)
+		}
+
+		tagFormat := config.TagFormat
+		if tagFormat == "" && hasRootConfig {
+			tagFormat = rootConfig.TagFormat
+		}
+
+		if tagFormat == "" && (config.extendsMonorepo() || rootConfig.extendsMonorepo()) {
+			tagFormat = "${name}-v${version}"
+		}
+
+		if tagFormat == "" {
+			tagFormat = "v${version}"
+		}
+
+		if pattern := semanticReleaseTagPattern(tagFormat, name); pattern != "{component}-{version}" {
+			scaffold.note("%s is tagged like %s. Run the migrate operation with migrate-from: '%s' to import its existing versions", name, tagFormat, pattern)
+		}
+
+		if _, ok := scaffold.Config.Components[name]; ok {
+			scaffold.note("Several packages are named %s, so only the one in %s was added. Configure the others with different names", name, directory)
+		}
+
+		scaffold.Config.Components[name] = component
+	}
+
+	return scaffold, nil
+}
+
+// readSemanticReleaseConfig reads the semantic-release configuration of a directory, if it has one
+func readSemanticReleaseConfig(root string, directory string, scaffold *Scaffold) (semanticReleaseConfig, bool, error) {
+	var config semanticReleaseConfig
+	for _, name := range semanticReleaseFiles {
+		contents, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(directory), name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return config, false, err
+		}
+
+		if err := yaml.Unmarshal(contents, &config); err != nil {
+			return config, false, fmt.Errorf("invalid semantic-release configuration %s: %w", path.Join(directory, name), err)
+		}
+
+		return config, true, nil
+	}
+
+	var manifest struct {
+		Release *json.RawMessage `json:"release"`
+	}
+
+	contents, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(directory), "package.json"))
+	if err == nil && json.Unmarshal(contents, &manifest) == nil && manifest.Release != nil {
+		if err := yaml.Unmarshal(*manifest.Release, &config); err != nil {
+			return config, false, fmt.Errorf("invalid semantic-release configuration in %s: %w", path.Join(directory, "package.json"), err)
+		}
+
+		return config, true, nil
+	}
+
+	for _, name := range semanticReleaseScriptFiles {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(directory), name)); err == nil {
+			scaffold.note("%s configures semantic-release with JavaScript, which can't be read. Migrate it by hand", path.Join(directory, name))
+		}
+	}
+
+	return config, false, nil
+}
+
+// packageName of the directory's package.json, without any npm scope, falling back to the directory's name
+func packageName(root string, directory string) string {
+	var manifest struct {
+		Name string `json:"name"`
+	}
+
+	contents, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(directory), "package.json"))
+	if err == nil && json.Unmarshal(contents, &manifest) == nil && manifest.Name != "" {
+		return strings.ToLower(path.Base(manifest.Name))
+	}
+
+	return strings.ToLower(filepath.Base(filepath.Join(root, filepath.FromSlash(directory))))
+}
+
+// semanticReleaseChannels maps semantic-release's branches to release channels. Release branches, such as the
+// default branch, are stable without a channel, and maintenance branches are configured with the
+// maintenance-branches input instead.
+func semanticReleaseChannels(branches any, scaffold *Scaffold) []Channel {
+	if name, ok := branches.(string); ok {
+		branches = []any{name}
+	}
+
+	entries, _ := branches.([]any)
+	var channels []Channel
+	for _, entry := range entries {
+		var name, channelName, prerelease, maintenanceRange string
+		switch branch := entry.(type) {
+		case string:
+			name = branch
+		case map[string]any:
+			name, _ = branch["name"].(string)
+			channelName, _ = branch["channel"].(string)
+			maintenanceRange, _ = branch["range"].(string)
+			switch value := branch["prerelease"].(type) {
+			case bool:
+				if value {
+					prerelease = name
+				}
+			case string:
+				prerelease = value
+			}
+		}
+
+		if maintenanceRange != "" || maintenanceRangePattern.MatchString(name) || strings.Contains(name, "+(") {
+			scaffold.note("Branch %s is a maintenance branch. Set the maintenance-branches input to its pattern, eg: '{major}.x'", name)
+			continue
+		}
+
+		if name == "main" || name == "master" {
+			continue
+		}
+
+		if _, err := path.Match(name, ""); err != nil || name == "" {
+			scaffold.note("Branch %q can't be matched with a glob pattern. Add a channel for it by hand", name)
+			continue
+		}
+
+		if channelName == "" {
+			channelName = strings.ReplaceAll(name, "/", "-")
+			if prerelease == "" {
+				channelName = "stable"
+			}
+		}
+
+		channels = append(channels, Channel{Name: channelName, Branch: name, Prerelease: prerelease})
+	}
+
+	return channels
+}
+
+// semanticReleasePlugin maps a semantic-release plugin, which is either a name or a name and its options, onto the
+// component's configuration
+func semanticReleasePlugin(plugin any, component string, config *ComponentConfig, scaffold *Scaffold) {
+	name, _ := plugin.(string)
+	if list, ok := plugin.([]any); ok && len(list) > 0 {
+		name, _ = list[0].(string)
+	}
+
+	switch {
+	case semanticReleaseBuiltinPlugins[name]:
+	case name == "@semantic-release/npm":
+		config.VersionFiles = append(config.VersionFiles, VersionFile{Path: "package.json"})
+		scaffold.note("%s was published to npm by semantic-release. Publish it in a later workflow step using the version output", component)
+	case name == "@semantic-release/changelog":
+		scaffold.note("%s had a CHANGELOG.md maintained by semantic-release. Enable the release-pull-requests input to keep maintaining it", component)
+	case name == "@semantic-release/git":
+		// Version files are committed with each release, the same as the git plugin's assets
+	default:
+		scaffold.note("The semantic-release plugin %s of %s has no equivalent, so it wasn't migrated", name, component)
+	}
+}
+
+// semanticReleaseTagPattern converts semantic-release's tag format, eg: "${name}@v${version}", into a migrate-from
+// pattern
+func semanticReleaseTagPattern(tagFormat string, component string) string {
+	pattern := strings.ReplaceAll(tagFormat, "${version}", "{version}")
+	pattern = strings.ReplaceAll(pattern, "${name}", "{component}")
+	return strings.ReplaceAll(pattern, component, "{component}")
+}
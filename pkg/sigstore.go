@@ -0,0 +1,354 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultFulcioURL is the public Sigstore certificate authority
+	DefaultFulcioURL = "https://fulcio.sigstore.dev"
+	// DefaultRekorURL is the public Sigstore transparency log
+	DefaultRekorURL = "https://rekor.sigstore.dev"
+)
+
+// IdentityTokenSource gets an OIDC identity token for the "sigstore" audience, which Fulcio issues a certificate for
+type IdentityTokenSource func(ctx context.Context) (string, error)
+
+// ActionsIdentityToken gets the workflow run's OIDC token from GitHub Actions with a client, using the request URL
+// and token the runner sets when the workflow has the "id-token: write" permission
+func ActionsIdentityToken(client *http.Client, requestURL string, requestToken string) IdentityTokenSource {
+	return func(ctx context.Context) (string, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL+"&audience=sigstore", nil)
+		if err != nil {
+			return "", err
+		}
+
+		request.Header.Set("Authorization", "Bearer "+requestToken)
+		var token struct {
+			Value string `json:"value"`
+		}
+
+		if err := doJSON(client, request, http.StatusOK, &token); err != nil {
+			return "", fmt.Errorf("could not get the Actions OIDC token: %w", err)
+		}
+
+		return token.Value, nil
+	}
+}
+
+// SigstoreSigner signs blobs keylessly with Sigstore: an ephemeral key is certified by Fulcio for the identity of
+// the OIDC token, and each signature is recorded in the Rekor transparency log, so that it can be verified with
+// "cosign verify-blob" after the key is gone. The certificate is requested with the first signature, and reused
+// by every signature of the run.
+type SigstoreSigner struct {
+	fulcioURL string
+	rekorURL  string
+	token     IdentityTokenSource
+	client    *http.Client
+
+	mu  sync.Mutex
+	key *ecdsa.PrivateKey
+	// PEM encoded certificate of the key
+	certificate []byte
+}
+
+// SigstoreSignature of a blob, in the format written by "cosign sign-blob"
+type SigstoreSignature struct {
+	// Base64 encoded ECDSA signature of the blob's SHA-256 digest
+	Signature []byte
+	// PEM encoded certificate of the signing key, which identifies the workflow which signed the blob
+	Certificate []byte
+	// Index of the signature's entry in the transparency log
+	LogIndex int64
+}
+
+// NewSigstoreSigner creates a signer which gets certificates from a Fulcio server and records signatures in a Rekor
+// log, eg: DefaultFulcioURL and DefaultRekorURL, with a client, eg: one which uses a proxy
+func NewSigstoreSigner(client *http.Client, fulcioURL string, rekorURL string, token IdentityTokenSource) *SigstoreSigner {
+	return &SigstoreSigner{
+		fulcioURL: strings.TrimSuffix(fulcioURL, "/"),
+		rekorURL:  strings.TrimSuffix(rekorURL, "/"),
+		token:     token,
+		client:    client,
+	}
+}
+
+// Sign a blob, recording the signature in the transparency log
+func (s *SigstoreSigner) Sign(ctx context.Context, blob []byte) (SigstoreSignature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.certificate == nil {
+		if err := s.certify(ctx); err != nil {
+			return SigstoreSignature{}, err
+		}
+	}
+
+	digest := sha256.Sum256(blob)
+	signature, err := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return SigstoreSignature{}, err
+	}
+
+	logIndex, err := s.recordSignature(ctx, digest[:], signature)
+	if err != nil {
+		return SigstoreSignature{}, err
+	}
+
+	return SigstoreSignature{
+		Signature:   []byte(base64.StdEncoding.EncodeToString(signature)),
+		Certificate: s.certificate,
+		LogIndex:    logIndex,
+	}, nil
+}
+
+// certify a new ephemeral key with a certificate from Fulcio for the identity of the OIDC token
+func (s *SigstoreSigner) certify(ctx context.Context) error {
+	token, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	// Fulcio checks the key is ours from a signature of the token's subject
+	subjectDigest := sha256.Sum256([]byte(subject))
+	proof, err := ecdsa.SignASN1(rand.Reader, key, subjectDigest[:])
+	if err != nil {
+		return err
+	}
+
+	var certificateRequest struct {
+		Credentials struct {
+			OIDCIdentityToken string `json:"oidcIdentityToken"`
+		} `json:"credentials"`
+		PublicKeyRequest struct {
+			PublicKey struct {
+				Algorithm string `json:"algorithm"`
+				Content   string `json:"content"`
+			} `json:"publicKey"`
+			ProofOfPossession string `json:"proofOfPossession"`
+		} `json:"publicKeyRequest"`
+	}
+
+	certificateRequest.Credentials.OIDCIdentityToken = token
+	certificateRequest.PublicKeyRequest.PublicKey.Algorithm = "ECDSA"
+	certificateRequest.PublicKeyRequest.PublicKey.Content = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	certificateRequest.PublicKeyRequest.ProofOfPossession = base64.StdEncoding.EncodeToString(proof)
+
+	type chain struct {
+		Chain struct {
+			Certificates []string `json:"certificates"`
+		} `json:"chain"`
+	}
+
+	var certificateResponse struct {
+		SignedCertificateEmbeddedSct *chain `json:"signedCertificateEmbeddedSct"`
+		SignedCertificateDetachedSct *chain `json:"signedCertificateDetachedSct"`
+	}
+
+	request, err := newJSONRequest(ctx, s.fulcioURL+"/api/v2/signingCert", certificateRequest)
+	if err != nil {
+		return err
+	}
+
+	if err := doJSON(s.client, request, http.StatusOK, &certificateResponse); err != nil {
+		return fmt.Errorf("could not get a signing certificate from Fulcio: %w", err)
+	}
+
+	issued := certificateResponse.SignedCertificateEmbeddedSct
+	if issued == nil {
+		issued = certificateResponse.SignedCertificateDetachedSct
+	}
+
+	if issued == nil || len(issued.Chain.Certificates) == 0 {
+		return errors.New("could not get a signing certificate from Fulcio: the response has no certificates")
+	}
+
+	// Only the leaf certificate is needed to verify signatures, as the rest of the chain is Sigstore's root
+	leaf, _ := pem.Decode([]byte(issued.Chain.Certificates[0]))
+	if leaf == nil {
+		return errors.New("could not get a signing certificate from Fulcio: the certificate is not PEM encoded")
+	}
+
+	s.key = key
+	s.certificate = pem.EncodeToMemory(leaf)
+	return nil
+}
+
+// recordSignature of a digest in the transparency log as a hashedrekord entry, returning the entry's log index
+func (s *SigstoreSigner) recordSignature(ctx context.Context, digest []byte, signature []byte) (int64, error) {
+	var entry struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Spec       struct {
+			Signature struct {
+				Content   string `json:"content"`
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+		} `json:"spec"`
+	}
+
+	entry.APIVersion = "0.0.1"
+	entry.Kind = "hashedrekord"
+	entry.Spec.Signature.Content = base64.StdEncoding.EncodeToString(signature)
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(s.certificate)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(digest)
+
+	request, err := newJSONRequest(ctx, s.rekorURL+"/api/v1/log/entries", entry)
+	if err != nil {
+		return 0, err
+	}
+
+	// The response is keyed by the entry's UUID
+	var entries map[string]struct {
+		LogIndex int64 `json:"logIndex"`
+	}
+
+	if err := doJSON(s.client, request, http.StatusCreated, &entries); err != nil {
+		return 0, fmt.Errorf("could not record the signature in Rekor: %w", err)
+	}
+
+	for _, created := range entries {
+		return created.LogIndex, nil
+	}
+
+	return 0, errors.New("could not record the signature in Rekor: the response has no entry")
+}
+
+// tokenSubject reads the subject claim of a JWT, without verifying it, as Fulcio verifies the token
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("OIDC token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("could not decode OIDC token: %w", err)
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return "", errors.New("OIDC token has no subject")
+	}
+
+	return claims.Subject, nil
+}
+
+// newJSONRequest creates a POST request with a JSON body
+func newJSONRequest(ctx context.Context, requestURL string, body any) (*http.Request, error) {
+	contents, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	return request, nil
+}
+
+// doJSON makes a request, decoding the response as JSON if it has the expected status
+func doJSON(client *http.Client, request *http.Request, status int, response any) error {
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("%s %s returned %s: %s", request.Method, request.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, response)
+}
+
+// WithSigstore signs each release's tag, and every asset the action uploads, keylessly with Sigstore. Signatures
+// and certificates are uploaded alongside the assets, named the same as "cosign sign-blob" output, eg:
+// "api-1.2.3.intoto.json.sig" and "api-1.2.3.intoto.json.pem". Only annotated tags are signed, as a lightweight tag
+// has no tag object, so use WithAnnotatedTags as well.
+func (a VersioningAction) WithSigstore(signer *SigstoreSigner) VersioningAction {
+	a.sigstore = signer
+	return a
+}
+
+// signTag of a release, if Sigstore signing was enabled, by uploading the tag object as an asset named after the
+// tag, eg: "api-1.2.3.tag", with its signature. The asset is what "git cat-file tag api-1.2.3" shows, so the
+// signature verifies the tag in the repository is the one which was released.
+func (a VersioningAction) signTag(ctx context.Context, release *Release, tagName string, tagObject []byte) {
+	if a.sigstore == nil {
+		return
+	}
+
+	if tagObject == nil {
+		a.logger.Warn("Not signing the tag with Sigstore, as it isn't an annotated tag created by this run", "component", a.component, "tag", tagName)
+		return
+	}
+
+	a.uploadReleaseAsset(ctx, release, tagName+".tag", tagObject, "text/plain")
+}
+
+// uploadSignature of a release asset, and the certificate which verifies it, alongside the asset
+func (a VersioningAction) uploadSignature(ctx context.Context, release *Release, name string, contents []byte) {
+	requestCtx, cancel := a.requestContext(ctx)
+	signature, err := a.sigstore.Sign(requestCtx, contents)
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	a.logger.Info("Signed release asset", "component", a.component, "asset", name, "logIndex", signature.LogIndex)
+	a.uploadAsset(ctx, release, name+".sig", signature.Signature, "text/plain")
+	a.uploadAsset(ctx, release, name+".pem", signature.Certificate, "application/x-pem-file")
+}
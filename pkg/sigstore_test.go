@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+)
+
+// fakeSigstore is an Actions OIDC token endpoint, Fulcio certificate authority and Rekor transparency log, which
+// certifies any key for the token's subject
+type fakeSigstore struct {
+	*httptest.Server
+	subject string
+	caKey   *ecdsa.PrivateKey
+	ca      *x509.Certificate
+
+	mu sync.Mutex
+	// Hashedrekord entries recorded in the log, in order
+	entries []fakeRekorEntry
+}
+
+type fakeRekorEntry struct {
+	// Hex encoded SHA-256 digest of the signed blob
+	digest string
+	// ASN.1 ECDSA signature of the digest
+	signature []byte
+	// PEM encoded certificate of the signing key
+	certificate []byte
+}
+
+// newFakeSigstore serves over TLS, so that requests only succeed with the client which trusts its certificate
+func newFakeSigstore(t *testing.T) *fakeSigstore {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake Fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeSigstore{subject: "repo:octocat/monorepo:ref:refs/heads/main", caKey: caKey, ca: ca}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", fake.token)
+	mux.HandleFunc("/api/v2/signingCert", fake.signingCert)
+	mux.HandleFunc("/api/v1/log/entries", fake.logEntries)
+	fake.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (f *fakeSigstore) token(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer request-token" || r.URL.Query().Get("audience") != "sigstore" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":%q}`, f.subject)))
+	json.NewEncoder(w).Encode(map[string]string{"value": "e30." + claims + ".c2lnbmF0dXJl"})
+}
+
+func (f *fakeSigstore) signingCert(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Credentials struct {
+			OIDCIdentityToken string `json:"oidcIdentityToken"`
+		} `json:"credentials"`
+		PublicKeyRequest struct {
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+			ProofOfPossession string `json:"proofOfPossession"`
+		} `json:"publicKeyRequest"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if subject, err := tokenSubject(request.Credentials.OIDCIdentityToken); err != nil || subject != f.subject {
+		http.Error(w, "The OIDC token isn't the one the runner issued", http.StatusUnauthorized)
+		return
+	}
+
+	block, _ := pem.Decode([]byte(request.PublicKeyRequest.PublicKey.Content))
+	if block == nil {
+		http.Error(w, "The public key isn't PEM encoded", http.StatusBadRequest)
+		return
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The proof of possession is a signature of the token's subject
+	proof, _ := base64.StdEncoding.DecodeString(request.PublicKeyRequest.ProofOfPossession)
+	subjectDigest := sha256.Sum256([]byte(f.subject))
+	if key, ok := publicKey.(*ecdsa.PublicKey); !ok || !ecdsa.VerifyASN1(key, subjectDigest[:], proof) {
+		http.Error(w, "The proof of possession doesn't verify", http.StatusBadRequest)
+		return
+	}
+
+	leaf, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(10 * time.Minute),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}, f.ca, publicKey, f.caKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	chain := []string{
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.ca.Raw})),
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"signedCertificateEmbeddedSct": map[string]any{"chain": map[string]any{"certificates": chain}}})
+}
+
+func (f *fakeSigstore) logEntries(w http.ResponseWriter, r *http.Request) {
+	var entry struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Signature struct {
+				Content   string `json:"content"`
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+		} `json:"spec"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil || entry.Kind != "hashedrekord" || entry.Spec.Data.Hash.Algorithm != "sha256" {
+		http.Error(w, "Expected a hashedrekord entry of a SHA-256 digest", http.StatusBadRequest)
+		return
+	}
+
+	signature, _ := base64.StdEncoding.DecodeString(entry.Spec.Signature.Content)
+	certificate, _ := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, fakeRekorEntry{digest: entry.Spec.Data.Hash.Value, signature: signature, certificate: certificate})
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{fmt.Sprintf("uuid-%d", len(f.entries)): map[string]any{"logIndex": 100 + len(f.entries)}})
+}
+
+// verify a signature of a blob with the public key of a certificate the fake CA issued
+func (f *fakeSigstore) verify(blob []byte, signature []byte, certificate []byte) error {
+	block, _ := pem.Decode(certificate)
+	if block == nil {
+		return fmt.Errorf("the certificate %q isn't PEM encoded", certificate)
+	}
+
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	if err := leaf.CheckSignatureFrom(f.ca); err != nil {
+		return fmt.Errorf("the certificate wasn't issued by Fulcio: %w", err)
+	}
+
+	digest := sha256.Sum256(blob)
+	if !ecdsa.VerifyASN1(leaf.PublicKey.(*ecdsa.PublicKey), digest[:], signature) {
+		return fmt.Errorf("the signature doesn't verify")
+	}
+
+	return nil
+}
+
+func TestSigstoreSignsTagObject(t *testing.T) {
+	sigstore := newFakeSigstore(t)
+	server := newTestServer(t)
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+	taggedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	// Only the fake's client trusts its certificate, like a client configured with a proxy's CA bundle
+	token := ActionsIdentityToken(sigstore.Client(), sigstore.URL+"/token?api-version=2.0", "request-token")
+	signer := NewSigstoreSigner(sigstore.Client(), sigstore.URL, sigstore.URL+"/", token)
+	a := newTestAction(t, server, "api", WithClock(FrozenClock(taggedAt))).
+		WithAnnotatedTags(Tagger{Name: "Release Bot", Email: "bot@example.com"}, nil).
+		WithSigstore(signer)
+
+	result := a.GenerateVersion(context.Background(), false)
+	if result.Release == nil {
+		t.Fatal("Expected a release")
+	}
+
+	// The tag object is uploaded as "git cat-file tag" shows it
+	tagObject, ok := server.Asset("api-1.0.0", "api-1.0.0.tag")
+	expected := fmt.Sprintf("object %s\ntype commit\ntag api-1.0.0\ntagger Release Bot <bot@example.com> %d +0000\n\n%s\n", server.Head(githubtest.DefaultBranch), taggedAt.Unix(), server.Releases()[0].GetName())
+	if !ok || string(tagObject) != expected {
+		t.Fatalf("Expected the tag object to be uploaded as %q, but got %q", expected, tagObject)
+	}
+
+	encodedSignature, _ := server.Asset("api-1.0.0", "api-1.0.0.tag.sig")
+	certificate, _ := server.Asset("api-1.0.0", "api-1.0.0.tag.pem")
+	signature, err := base64.StdEncoding.DecodeString(string(encodedSignature))
+	if err != nil {
+		t.Fatalf("Expected a base64 encoded signature, but got %q", encodedSignature)
+	}
+
+	if err := sigstore.verify(tagObject, signature, certificate); err != nil {
+		t.Errorf("Expected the uploaded signature of the tag object to verify, but %s", err)
+	}
+
+	// The signature is in the transparency log, so it can be verified after the key is gone
+	digest := sha256.Sum256(tagObject)
+	if len(sigstore.entries) != 1 {
+		t.Fatalf("Expected the tag's signature to be recorded in Rekor, but got %d entries", len(sigstore.entries))
+	}
+
+	entry := sigstore.entries[0]
+	if entry.digest != hex.EncodeToString(digest[:]) || string(entry.signature) != string(signature) || string(entry.certificate) != string(certificate) {
+		t.Errorf("Expected the log entry to record the uploaded signature of the tag object, but got %+v", entry)
+	}
+}
+
+func TestSigstoreSkipsLightweightTags(t *testing.T) {
+	sigstore := newFakeSigstore(t)
+	server := newTestServer(t)
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"))
+	token := ActionsIdentityToken(sigstore.Client(), sigstore.URL+"/token?api-version=2.0", "request-token")
+	a := newTestAction(t, server, "api").WithSigstore(NewSigstoreSigner(sigstore.Client(), sigstore.URL, sigstore.URL, token))
+
+	if result := a.GenerateVersion(context.Background(), false); result.Release == nil {
+		t.Fatal("Expected a release")
+	}
+
+	if _, ok := server.Asset("api-1.0.0", "api-1.0.0.tag"); ok || len(sigstore.entries) != 0 {
+		t.Errorf("Expected a lightweight tag not to be signed, as it has no tag object, but %d signatures were recorded", len(sigstore.entries))
+	}
+}
@@ -0,0 +1,175 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/leodido/go-conventionalcommits"
+)
+
+// ComponentSubmodule is the type of components which are git submodules at their path. Commits which update the
+// submodule's pointer are the component's changes, whatever their scope, and release notes link to the range of
+// the submodule's commits the version updated it across.
+const ComponentSubmodule = "submodule"
+
+// submodulePointer is the commit a submodule's path points at in a commit of the superproject
+type submodulePointer struct {
+	sha string
+	// URL of the submodule's repository, as it's configured in .gitmodules, or empty if it isn't
+	gitURL string
+}
+
+// updatesSubmodule checks whether a commit updated the pointer of the component's submodule
+func (a VersioningAction) updatesSubmodule(ctx context.Context, commit *github.RepositoryCommit) bool {
+	config := a.componentConfig()
+	if config.Type != ComponentSubmodule {
+		return false
+	}
+
+	submodulePath := path.Clean(strings.Trim(config.Path, "/"))
+	for _, file := range a.getCommitFiles(ctx, commit.GetSHA()) {
+		if file == submodulePath {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCommitForComponent parses a commit's message as a conventional commit. Commits which update the pointer of
+// the component's submodule without a conventional message, eg: "Update lib", are read as fixes, so that every
+// update of the submodule is released.
+func (a VersioningAction) parseCommitForComponent(ctx context.Context, commit *github.RepositoryCommit) (*conventionalcommits.ConventionalCommit, error) {
+	conventionalCommit, err := a.parseCommit(commit)
+	if err != nil && a.updatesSubmodule(ctx, commit) {
+		summary, _, _ := strings.Cut(commit.GetCommit().GetMessage(), "\n")
+		return &conventionalcommits.ConventionalCommit{Type: "fix", Description: summary}, nil
+	}
+
+	return conventionalCommit, err
+}
+
+// getSubmodulePointer gets the commit the component's submodule points at in a commit of the superproject, or nil
+// if the submodule didn't exist then
+func (a VersioningAction) getSubmodulePointer(ctx context.Context, sha string) *submodulePointer {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	submodulePath := path.Clean(strings.Trim(a.componentConfig().Path, "/"))
+	contents, _, _, err := a.client.Repositories.GetContents(requestCtx, a.owner, a.repository, submodulePath, &github.RepositoryContentGetOptions{Ref: sha})
+	if isNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	if contents == nil || contents.GetType() != "submodule" {
+		panic(fmt.Sprintf("Component %s is a %s, but %s is not a submodule", a.component, ComponentSubmodule, submodulePath))
+	}
+
+	gitmodules, _ := a.findFileContents(ctx, ".gitmodules", sha)
+	return &submodulePointer{sha: contents.GetSHA(), gitURL: gitmodulesURL(gitmodules, submodulePath)}
+}
+
+// gitmodulesURL finds the URL of the submodule at a path in a .gitmodules file, or an empty string if it isn't
+// configured
+func gitmodulesURL(gitmodules string, submodulePath string) string {
+	var sectionPath, sectionURL string
+	// A final section header ends the last submodule's section
+	for _, line := range strings.Split(gitmodules+"\n[", "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			// Each submodule's section ends where the next begins
+			if sectionPath == submodulePath {
+				return sectionURL
+			}
+
+			sectionPath, sectionURL = "", ""
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "path":
+			sectionPath = path.Clean(strings.Trim(strings.TrimSpace(value), "/"))
+		case "url":
+			sectionURL = strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// submoduleUpdate describes the range of the submodule's commits the commits since the last version updated its
+// pointer across, eg: "[`1a2b3c4...5d6e7f8`](https://github.com/owner/lib/compare/1a2b3c4...5d6e7f8)", or returns
+// an empty string if the component isn't a submodule or its pointer wasn't updated. The commits are newest first.
+func (a VersioningAction) submoduleUpdate(ctx context.Context, commits []*github.RepositoryCommit) string {
+	var updates []*github.RepositoryCommit
+	for _, commit := range commits {
+		if a.updatesSubmodule(ctx, commit) {
+			updates = append(updates, commit)
+		}
+	}
+
+	if len(updates) == 0 {
+		return ""
+	}
+
+	to := a.getSubmodulePointer(ctx, updates[0].GetSHA())
+	if to == nil {
+		// The submodule was removed
+		return ""
+	}
+
+	var from *submodulePointer
+	if parents := updates[len(updates)-1].Parents; len(parents) > 0 {
+		from = a.getSubmodulePointer(ctx, parents[0].GetSHA())
+	}
+
+	if from == nil {
+		// The submodule was added, so there's no range yet
+		return fmt.Sprintf("`%s`", shortSHA(to.sha))
+	}
+
+	commitRange := fmt.Sprintf("%s...%s", shortSHA(from.sha), shortSHA(to.sha))
+	if repositoryURL := a.submoduleWebURL(to.gitURL); repositoryURL != "" {
+		return fmt.Sprintf("[`%s`](%s/compare/%s...%s)", commitRange, repositoryURL, from.sha, to.sha)
+	}
+
+	return fmt.Sprintf("`%s`", commitRange)
+}
+
+// submoduleWebURL is the web URL of a submodule's repository from its git URL, eg: "https://github.com/owner/lib"
+// for "git@github.com:owner/lib.git". Relative URLs, eg: "../lib.git", are relative to the superproject's
+// repository. Returns an empty string for other URLs, eg: of local directories.
+func (a VersioningAction) submoduleWebURL(gitURL string) string {
+	if strings.HasPrefix(gitURL, "./") || strings.HasPrefix(gitURL, "../") {
+		base, _ := url.Parse(fmt.Sprintf("%s/%s/%s/", a.serverURL(), a.owner, a.repository))
+		resolved, err := base.Parse(gitURL)
+		if err != nil {
+			return ""
+		}
+
+		gitURL = resolved.String()
+	}
+
+	if host, repository, ok := strings.Cut(strings.TrimPrefix(gitURL, "git@"), ":"); ok && strings.HasPrefix(gitURL, "git@") {
+		return fmt.Sprintf("https://%s/%s", host, strings.TrimSuffix(repository, ".git"))
+	}
+
+	parsed, err := url.Parse(gitURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http" && parsed.Scheme != "ssh") {
+		return ""
+	}
+
+	return fmt.Sprintf("https://%s/%s", parsed.Hostname(), strings.Trim(strings.TrimSuffix(parsed.Path, ".git"), "/"))
+}
@@ -0,0 +1,521 @@
+// Package telemetry traces a run's phases and GitHub API calls, and counts what it did, exporting the spans and
+// metrics to an OpenTelemetry collector with OTLP over HTTP, so that release automation can be monitored across
+// many repositories.
+//
+// Runs are short, so spans and metrics are collected in memory and exported once when the run shuts down, rather
+// than in batches as they happen. They're encoded as OTLP JSON, which every collector accepts on the same
+// /v1/traces and /v1/metrics endpoints as protobuf. A nil *Telemetry is valid, and records nothing.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MetricCommits counts the commits each component's versions were generated from
+	MetricCommits = "monorepo_versioning.commits"
+	// MetricAPIRequests counts the requests made to the GitHub API, by method and status
+	MetricAPIRequests = "monorepo_versioning.api.requests"
+	// MetricRateLimitRemaining is the number of GitHub API requests the token had left, as of its last request
+	MetricRateLimitRemaining = "monorepo_versioning.api.rate_limit.remaining"
+	// MetricDuration is how long the run took, in seconds
+	MetricDuration = "monorepo_versioning.run.duration"
+)
+
+// scopeName identifies the instrumentation in exported spans and metrics
+const scopeName = "github.com/ellisto/monorepo-versioning"
+
+// Telemetry collects the spans and metrics of a run, and exports them when it shuts down
+type Telemetry struct {
+	// endpoint of the collector's OTLP HTTP receiver, eg: "http://localhost:4318"
+	endpoint string
+	// headers sent with each export, eg: to authenticate with the collector
+	headers map[string]string
+	// resource attributes identifying what's being monitored, eg: service.name
+	resource map[string]string
+	client   *http.Client
+	started  time.Time
+
+	mu       sync.Mutex
+	traceID  string
+	spans    []*Span
+	counters map[string]*counter
+	gauges   map[string]*gauge
+}
+
+// New collects telemetry to export to an OTLP HTTP endpoint, identifying the run with resource attributes, which
+// should include service.name
+func New(endpoint string, headers map[string]string, resource map[string]string) *Telemetry {
+	return &Telemetry{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  headers,
+		resource: resource,
+		client:   http.DefaultClient,
+		started:  time.Now(),
+		traceID:  randomID(16),
+		counters: make(map[string]*counter),
+		gauges:   make(map[string]*gauge),
+	}
+}
+
+// ParseHeaders parses headers in the format of OTEL_EXPORTER_OTLP_HEADERS, eg: "api-key=secret,team=platform"
+func ParseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("header %q must be in the format key=value", pair)
+		}
+
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return headers, nil
+}
+
+// Span is an operation of the run, eg: generating a component's version, or an API call
+type Span struct {
+	telemetry  *Telemetry
+	id         string
+	parentID   string
+	name       string
+	kind       int
+	attributes map[string]string
+	start      time.Time
+	end        time.Time
+	failure    string
+}
+
+// Span kinds of OTLP
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// spanKey is the context key of the current span, which new spans are children of
+type spanKey struct{}
+
+// Start a span as a child of the context's span, returning a context with the new span
+func (t *Telemetry) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := t.newSpan(ctx, name, spanKindInternal, attributes)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *Telemetry) newSpan(ctx context.Context, name string, kind int, attributes map[string]string) *Span {
+	span := &Span{telemetry: t, id: randomID(8), name: name, kind: kind, attributes: attributes, start: time.Now()}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.parentID = parent.id
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, span)
+	return span
+}
+
+// End the span. If it's deferred, as in "defer span.End()", a panic marks the span as failed and carries on
+// panicking, as the action panics on errors.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	if r := recover(); r != nil {
+		s.Fail(fmt.Sprint(r))
+		s.finish()
+		panic(r)
+	}
+
+	s.finish()
+}
+
+// Fail marks the span as failed, with a description of the error
+func (s *Span) Fail(message string) {
+	if s == nil {
+		return
+	}
+
+	s.telemetry.mu.Lock()
+	defer s.telemetry.mu.Unlock()
+	s.failure = message
+}
+
+func (s *Span) finish() {
+	s.telemetry.mu.Lock()
+	defer s.telemetry.mu.Unlock()
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+}
+
+// counter is the running total of a sum with a set of attributes
+type counter struct {
+	name       string
+	attributes map[string]string
+	value      int64
+}
+
+// gauge is the last value recorded with a set of attributes
+type gauge struct {
+	name       string
+	unit       string
+	attributes map[string]string
+	value      float64
+	time       time.Time
+}
+
+// Add to a counter with a set of attributes
+func (t *Telemetry) Add(name string, value int64, attributes map[string]string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := seriesKey(name, attributes)
+	if _, ok := t.counters[key]; !ok {
+		t.counters[key] = &counter{name: name, attributes: attributes}
+	}
+
+	t.counters[key].value += value
+}
+
+// Record the current value of a gauge with a set of attributes, in a unit such as "s", or "1" for counts
+func (t *Telemetry) Record(name string, unit string, value float64, attributes map[string]string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gauges[seriesKey(name, attributes)] = &gauge{name: name, unit: unit, attributes: attributes, value: value, time: time.Now()}
+}
+
+// seriesKey identifies a metric's time series by its name and attributes
+func seriesKey(name string, attributes map[string]string) string {
+	key := name
+	for _, attribute := range sortedKeys(attributes) {
+		key += fmt.Sprintf("\x00%s=%s", attribute, attributes[attribute])
+	}
+
+	return key
+}
+
+// Transport traces each request made with transport as a client span of the request context's span, counting the
+// requests and recording the rate limit remaining from GitHub's response headers
+func (t *Telemetry) Transport(transport http.RoundTripper) http.RoundTripper {
+	if t == nil {
+		return transport
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return tracingTransport{telemetry: t, transport: transport}
+}
+
+type tracingTransport struct {
+	telemetry *Telemetry
+	transport http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	span := t.telemetry.newSpan(request.Context(), request.Method, spanKindClient, map[string]string{
+		"http.request.method": request.Method,
+		"url.full":            request.URL.Redacted(),
+	})
+
+	response, err := t.transport.RoundTrip(request)
+	if err != nil {
+		span.Fail(err.Error())
+		span.finish()
+		t.telemetry.Add(MetricAPIRequests, 1, map[string]string{"http.request.method": request.Method, "error.type": "transport"})
+		return nil, err
+	}
+
+	status := strconv.Itoa(response.StatusCode)
+	t.telemetry.mu.Lock()
+	span.attributes["http.response.status_code"] = status
+	t.telemetry.mu.Unlock()
+	if response.StatusCode >= http.StatusBadRequest {
+		span.Fail(response.Status)
+	}
+
+	span.finish()
+	t.telemetry.Add(MetricAPIRequests, 1, map[string]string{"http.request.method": request.Method, "http.response.status_code": status})
+	if remaining, err := strconv.ParseFloat(response.Header.Get("X-RateLimit-Remaining"), 64); err == nil {
+		t.telemetry.Record(MetricRateLimitRemaining, "1", remaining, map[string]string{"resource": response.Header.Get("X-RateLimit-Resource")})
+	}
+
+	return response, nil
+}
+
+// Shutdown records the run's duration, and exports its spans and metrics. Spans which haven't ended, eg: because
+// the run is exiting while they're in progress, end now.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.Record(MetricDuration, "s", time.Since(t.started).Seconds(), nil)
+	t.mu.Lock()
+	traces, metrics := t.tracesRequest(), t.metricsRequest()
+	t.mu.Unlock()
+
+	return errors.Join(t.export(ctx, "/v1/traces", traces), t.export(ctx, "/v1/metrics", metrics))
+}
+
+// export a request to one of the collector's endpoints
+func (t *Telemetry) export(ctx context.Context, path string, body any) error {
+	contents, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+path, bytes.NewReader(contents))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := t.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not export telemetry to %s: %w", t.endpoint+path, err)
+	}
+
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("could not export telemetry to %s: %s: %s", t.endpoint+path, response.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
+
+// The types below are the OTLP JSON encoding of traces and metrics, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding. 64-bit integers are encoded as strings.
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            struct {
+		// 0 is unset, and 2 an error
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsInt             string          `json:"asInt,omitempty"`
+	AsDouble          *float64        `json:"asDouble,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+	// 2 is cumulative
+	AggregationTemporality int  `json:"aggregationTemporality"`
+	IsMonotonic            bool `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Unit  string     `json:"unit"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+}
+
+// tracesRequest is the export request of every span
+func (t *Telemetry) tracesRequest() any {
+	now := time.Now()
+	spans := []otlpSpan{}
+	for _, span := range t.spans {
+		if span.end.IsZero() {
+			span.end = now
+		}
+
+		exported := otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            span.id,
+			ParentSpanID:      span.parentID,
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: unixNano(span.start),
+			EndTimeUnixNano:   unixNano(span.end),
+			Attributes:        otlpAttributes(span.attributes),
+		}
+
+		if span.failure != "" {
+			exported.Status.Code, exported.Status.Message = 2, span.failure
+		}
+
+		spans = append(spans, exported)
+	}
+
+	type scopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	type resourceSpans struct {
+		Resource   otlpResource `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+
+	return struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}{[]resourceSpans{{otlpResource{otlpAttributes(t.resource)}, []scopeSpans{{otlpScope{scopeName}, spans}}}}}
+}
+
+// metricsRequest is the export request of every counter and gauge, with one metric of each name
+func (t *Telemetry) metricsRequest() any {
+	now := unixNano(time.Now())
+	metrics := make(map[string]*otlpMetric)
+	var names []string
+	metric := func(name string, unit string) *otlpMetric {
+		if _, ok := metrics[name]; !ok {
+			metrics[name] = &otlpMetric{Name: name, Unit: unit}
+			names = append(names, name)
+		}
+
+		return metrics[name]
+	}
+
+	for _, key := range sortedKeys(t.counters) {
+		counter := t.counters[key]
+		exported := metric(counter.name, "1")
+		if exported.Sum == nil {
+			exported.Sum = &otlpSum{AggregationTemporality: 2, IsMonotonic: true}
+		}
+
+		exported.Sum.DataPoints = append(exported.Sum.DataPoints, otlpDataPoint{
+			Attributes:        otlpAttributes(counter.attributes),
+			StartTimeUnixNano: unixNano(t.started),
+			TimeUnixNano:      now,
+			AsInt:             strconv.FormatInt(counter.value, 10),
+		})
+	}
+
+	for _, key := range sortedKeys(t.gauges) {
+		gauge := t.gauges[key]
+		exported := metric(gauge.name, gauge.unit)
+		if exported.Gauge == nil {
+			exported.Gauge = &otlpGauge{}
+		}
+
+		value := gauge.value
+		exported.Gauge.DataPoints = append(exported.Gauge.DataPoints, otlpDataPoint{
+			Attributes:   otlpAttributes(gauge.attributes),
+			TimeUnixNano: unixNano(gauge.time),
+			AsDouble:     &value,
+		})
+	}
+
+	exported := []otlpMetric{}
+	for _, name := range names {
+		exported = append(exported, *metrics[name])
+	}
+
+	type scopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+
+	type resourceMetrics struct {
+		Resource     otlpResource   `json:"resource"`
+		ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+	}
+
+	return struct {
+		ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+	}{[]resourceMetrics{{otlpResource{otlpAttributes(t.resource)}, []scopeMetrics{{otlpScope{scopeName}, exported}}}}}
+}
+
+// otlpAttributes of a span, metric or resource, sorted by key
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	exported := []otlpAttribute{}
+	for _, key := range sortedKeys(attributes) {
+		attribute := otlpAttribute{Key: key}
+		attribute.Value.StringValue = attributes[key]
+		exported = append(exported, attribute)
+	}
+
+	return exported
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// randomID of a trace or span, as hex of a number of random bytes
+func randomID(bytes int) string {
+	id := make([]byte, bytes)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(id)
+}
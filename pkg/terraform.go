@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// ComponentTerraform is the type of components which are Terraform modules in a subdirectory of the repository.
+// Their tags are named after the directory, eg: "modules/vpc/v1.2.3", whatever the tag template, as Terraform
+// module sources and registries expect plain "vX.Y.Z" versions.
+const ComponentTerraform = "terraform"
+
+// terraformTagPrefix of the tags of a Terraform module in a directory, eg: "modules/vpc/v"
+func terraformTagPrefix(dir string) string {
+	return strings.ToLower(path.Clean(strings.Trim(dir, "/"))) + "/v"
+}
+
+// moduleSource of the component at a version, if it's a Terraform module, eg:
+// "git::https://github.com/owner/repository.git//modules/vpc?ref=modules/vpc/v1.2.3". Returns an empty string for
+// other components.
+func (a VersioningAction) moduleSource(version *semver.Version) string {
+	config := a.componentConfig()
+	if config.Type != ComponentTerraform || version == nil {
+		return ""
+	}
+
+	dir := path.Clean(strings.Trim(config.Path, "/"))
+	return fmt.Sprintf("git::%s/%s/%s.git//%s?ref=%s", a.serverURL(), a.owner, a.repository, dir, a.tagName(version.String()))
+}
+
+// serverURL of the GitHub server the repository is on, from the API URL of the client, eg: "https://github.com"
+// for "https://api.github.com/" or "https://github.example.com" for "https://github.example.com/api/v3/"
+func (a VersioningAction) serverURL() string {
+	apiURL := *a.client.BaseURL
+	if apiURL.Host == "api.github.com" {
+		apiURL.Host = "github.com"
+	}
+
+	apiURL.Path, _, _ = strings.Cut(apiURL.Path, "/api/")
+	return strings.TrimSuffix(apiURL.String(), "/")
+}
@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// DefaultTitleTemplate titles releases with the component's label, or else its name, eg: "Api: 1.2.0" for "api"
+const DefaultTitleTemplate = "{{.Label | title}}: {{.Version}}"
+
+// TitleData is what release title templates are executed with
+type TitleData struct {
+	// Component name, eg: "services/Payment-API"
+	Component string
+	// Label of the component, or its name if it has no label
+	Label string
+	// Version released, eg: "1.2.0"
+	Version string
+	// Channel the version is released to, eg: "stable"
+	Channel string
+	// Date of the release, eg: "2026-10-14"
+	Date string
+}
+
+// titleFuncs are the functions available to release title templates, besides text/template's own. title only
+// capitalises the first letter of each word, so acronyms like "API" are kept. A caser is created for each call, as
+// they can't be shared by components titled concurrently.
+var titleFuncs = template.FuncMap{
+	"title": func(value string) string {
+		return cases.Title(language.English, cases.NoLower).String(value)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// WithTitleTemplate sets how releases are titled, as a text/template executed with TitleData, eg:
+// "{{.Component}} v{{.Version}} ({{.Date}})". The title, upper, and lower functions change the case of values.
+// Defaults to DefaultTitleTemplate.
+func WithTitleTemplate(text string) Option {
+	return func(a *VersioningAction) error {
+		titleTemplate, err := template.New("title").Funcs(titleFuncs).Option("missingkey=error").Parse(text)
+		if err == nil {
+			// Unknown fields are only found when the template is executed
+			err = titleTemplate.Execute(&strings.Builder{}, TitleData{})
+		}
+
+		if err != nil {
+			return fmt.Errorf("title template %q is invalid: %w", text, err)
+		}
+
+		a.titleTemplate = titleTemplate
+		return nil
+	}
+}
+
+// releaseTitle for a version of the component
+func (a VersioningAction) releaseTitle(version *semver.Version) string {
+	titleTemplate := a.titleTemplate
+	if titleTemplate == nil {
+		titleTemplate = template.Must(template.New("title").Funcs(titleFuncs).Parse(DefaultTitleTemplate))
+	}
+
+	// Prefer a human-readable label if one provided, otherwise use the component name
+	label := a.label
+	if label == "" {
+		label = a.component
+	}
+
+	var title strings.Builder
+	err := titleTemplate.Execute(&title, TitleData{
+		Component: a.component,
+		Label:     label,
+		Version:   version.String(),
+		Channel:   a.channel().Name,
+		Date:      a.clock.Now().UTC().Format(time.DateOnly),
+	})
+
+	if err != nil {
+		panic(fmt.Sprintf("Could not title the release of %s: %s", a.component, err))
+	}
+
+	return title.String()
+}
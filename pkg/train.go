@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// WithReleaseTrain tags each run which releases components as a release train, so the versions released together
+// can be referred to as one, eg: by a weekly scheduled workflow. The tag is named with template, where "{date}" is
+// replaced with the train's date, eg: "train-{date}" for "train-2024-06-03", and "{week}" with its ISO week, eg:
+// "train-{week}" for "train-2024-W23".
+func (a VersioningAction) WithReleaseTrain(template string) VersioningAction {
+	a.releaseTrain = template
+	return a
+}
+
+// TagReleaseTrain tags the revision with the release train's name, as an annotated tag whose message lists the
+// versions released by the train. If the name is already taken, eg: by an earlier train the same day, a number is
+// appended, eg: "train-2024-06-03.2". Returns the tag, or an empty string if release trains aren't enabled or
+// nothing was released. If dryRun is true, the tag is named but not created.
+func (a VersioningAction) TagReleaseTrain(ctx context.Context, results []Result, dryRun bool) string {
+	if a.releaseTrain == "" {
+		return ""
+	}
+
+	var released []Result
+	for _, result := range results {
+		if result.Version != nil && (dryRun || result.Release != nil && !result.Release.draft) {
+			released = append(released, result)
+		}
+	}
+
+	if len(released) == 0 {
+		a.logger.Info("No components were released, so there's no release train")
+		return ""
+	}
+
+	base := a.releaseTrainName()
+	tagName := base
+	for n := 2; a.tagExists(ctx, tagName); n++ {
+		tagName = fmt.Sprintf("%s.%d", base, n)
+	}
+
+	if dryRun {
+		a.logger.Info("Not tagging release train, as this is a dry run", "tag", tagName)
+		return tagName
+	}
+
+	message := fmt.Sprintf("Release train %s\n\n", tagName)
+	for _, result := range released {
+		message += fmt.Sprintf("- %s %s\n", result.Component, result.Version)
+	}
+
+	a.createAnnotatedTag(ctx, tagName, message)
+	return tagName
+}
+
+// releaseTrainName for a train leaving now
+func (a VersioningAction) releaseTrainName() string {
+	now := a.clock.Now().UTC()
+	year, week := now.ISOWeek()
+	name := strings.ReplaceAll(a.releaseTrain, "{date}", now.Format("2006-01-02"))
+	return strings.ReplaceAll(name, "{week}", fmt.Sprintf("%d-W%02d", year, week))
+}
+
+// tagExists checks whether the repository has a tag
+func (a VersioningAction) tagExists(ctx context.Context, tagName string) bool {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	_, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, fmt.Sprintf("refs/tags/%s", tagName))
+	if isNotFound(err) {
+		return false
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return true
+}
@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// WithTransactionalReleases releases the components versioned together by GenerateVersions all or nothing: every
+// version is generated before any is released, and if releasing one fails, the releases, tags and version file
+// commits already created in the run are undone, so a partial release train doesn't need cleaning up by hand.
+// Releases are only announced once every component was released.
+func (a VersioningAction) WithTransactionalReleases(enabled bool) VersioningAction {
+	a.transactional = enabled
+	return a
+}
+
+// releaseTransaction records the releases created by a run, so that they can be rolled back if it fails
+type releaseTransaction struct {
+	mu       sync.Mutex
+	releases []transactionRelease
+	pushes   []transactionPush
+}
+
+type transactionRelease struct {
+	action  VersioningAction
+	release *github.RepositoryRelease
+}
+
+// transactionPush is a commit the run pushed to a branch, eg: updating version files
+type transactionPush struct {
+	action VersioningAction
+	sha    string
+	parent string
+}
+
+// recordRelease created by the action, if it's releasing as part of a transaction
+func (a VersioningAction) recordRelease(release *github.RepositoryRelease) {
+	if a.transaction == nil {
+		return
+	}
+
+	a.transaction.mu.Lock()
+	defer a.transaction.mu.Unlock()
+	a.transaction.releases = append(a.transaction.releases, transactionRelease{action: a, release: release})
+}
+
+// recordPush of a commit on top of the current revision, if the action is releasing as part of a transaction
+func (a VersioningAction) recordPush(sha string) {
+	if a.transaction == nil {
+		return
+	}
+
+	a.transaction.mu.Lock()
+	defer a.transaction.mu.Unlock()
+	a.transaction.pushes = append(a.transaction.pushes, transactionPush{action: a, sha: sha, parent: a.revision})
+}
+
+// generateVersionsTransactionally generates every component's version with a dry run first, so that a component
+// which can't be versioned stops the run before anything is released, then releases each of them, rolling back
+// every release if any of them fails. Only once every component was released are the releases announced, as
+// announcements, deployments and pushes to other places can't be rolled back.
+func generateVersionsTransactionally(ctx context.Context, actions []VersioningAction) []Result {
+	forEachComponent(actions, func(a VersioningAction) Result {
+		a.logger.Info("Planning version", "component", a.component)
+		return a.GenerateVersion(ctx, true)
+	})
+
+	released := make(map[string]Result)
+	for _, result := range releaseTransactionally(ctx, actions) {
+		released[result.Component] = result
+	}
+
+	return forEachComponent(actions, func(a VersioningAction) Result {
+		return a.completeRelease(ctx, released[a.component], false)
+	})
+}
+
+// releaseTransactionally creates the release of each component, rolling back every release if any of them fails
+func releaseTransactionally(ctx context.Context, actions []VersioningAction) []Result {
+	transaction := &releaseTransaction{}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			transaction.rollBack(ctx)
+			panic(recovered)
+		}
+	}()
+
+	return forEachComponent(actions, func(a VersioningAction) Result {
+		ctx, span := a.telemetry.Start(ctx, "version", map[string]string{"component": a.component})
+		defer span.End()
+		a.transaction = transaction
+		a.logger.Info("Generating version", "component", a.component)
+		return a.generateRelease(ctx, false)
+	})
+}
+
+// rollBack deletes the releases created in the transaction, newest first, and moves their alias tags back, then
+// moves the branches back before the commits it pushed. A release or commit which can't be rolled back is logged
+// rather than stopping the rest from being rolled back.
+func (t *releaseTransaction) rollBack(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.releases) - 1; i >= 0; i-- {
+		t.releases[i].rollBack(ctx)
+	}
+
+	for i := len(t.pushes) - 1; i >= 0; i-- {
+		t.pushes[i].rollBack(ctx)
+	}
+}
+
+func (r transactionRelease) rollBack(ctx context.Context) {
+	a := r.action
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			a.logger.Error(fmt.Sprintf("Couldn't roll back release %s, so it must be deleted by hand: %v", r.release.GetTagName(), recovered), "component", a.component)
+		}
+	}()
+
+	a.logger.Warn("Rolling back release, as releasing failed", "component", a.component, "tag", r.release.GetTagName())
+	a.deleteRelease(ctx, r.release)
+	version := a.releaseVersion(r.release)
+	if version == nil || version.Prerelease() != "" || r.release.GetDraft() {
+		return
+	}
+
+	// The releases were listed before the run released anything
+	a.restoreAliasTags(ctx, version, publishedReleases(a.filterAndSortReleasesForComponent(a.getAllReleases(ctx))))
+}
+
+// rollBack moves the branch back to the commit's parent, unless another commit was pushed on top of it since, which
+// would be lost
+func (p transactionPush) rollBack(ctx context.Context) {
+	a := p.action
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			a.logger.Error(fmt.Sprintf("Couldn't roll back commit %s of branch %s, so it must be reverted by hand: %v", shortSHA(p.sha), a.branch, recovered), "component", a.component)
+		}
+	}()
+
+	a.history.pushes.Lock()
+	defer a.history.pushes.Unlock()
+	ref := fmt.Sprintf("refs/heads/%s", a.branch)
+	requestCtx, cancel := a.requestContext(ctx)
+	head, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, ref)
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	if head.GetObject().GetSHA() != p.sha {
+		panic(fmt.Sprintf("the branch has moved on to %s", shortSHA(head.GetObject().GetSHA())))
+	}
+
+	a.logger.Warn("Rolling back commit, as releasing failed", "component", a.component, "branch", a.branch, "sha", p.sha)
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	_, _, err = a.client.Git.UpdateRef(requestCtx, a.owner, a.repository, &github.Reference{
+		Ref:    &ref,
+		Object: &github.GitObject{SHA: &p.parent},
+	}, true)
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditUpdateBranch, Branch: a.branch, SHA: p.parent})
+}
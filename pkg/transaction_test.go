@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ellisto/monorepo-versioning/pkg/githubtest"
+)
+
+// transactionTest is a repository with changes to api, which has a version file and a chat notification, and web
+func transactionTest(t *testing.T, hooks HooksConfig) (*githubtest.Server, []VersioningAction, *atomic.Int32) {
+	t.Helper()
+	var notifications atomic.Int32
+	chat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notifications.Add(1)
+	}))
+	t.Cleanup(chat.Close)
+
+	server := newTestServer(t)
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("chore: add version file").WithFile("api/VERSION", "0.0.0\n"))
+	server.Push(githubtest.DefaultBranch, githubtest.Commit("feat(api): add an endpoint"), githubtest.Commit("feat(web): add a page"))
+	config := Config{
+		Components: map[string]ComponentConfig{
+			"api": {
+				Path:          "api",
+				VersionFiles:  []VersionFile{{Path: "VERSION"}},
+				Notifications: []NotificationConfig{{Type: "slack", WebhookURL: chat.URL}},
+			},
+			"web": {},
+		},
+		Hooks: hooks,
+	}
+
+	api := newTestAction(t, server, "api").WithConfig(config).WithTransactionalReleases(true)
+	return server, []VersioningAction{api, api.ForComponent("web", "")}, &notifications
+}
+
+func TestTransactionalReleasesRollBackWhenAComponentFails(t *testing.T) {
+	server, actions, notifications := transactionTest(t, HooksConfig{
+		PreRelease: []Hook{{Command: []string{"sh", "-c", `if grep -q '"component":"web"'; then exit 1; fi`}}},
+	})
+	head := server.Head(githubtest.DefaultBranch)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Releasing web should have failed")
+			}
+		}()
+
+		GenerateVersions(context.Background(), actions, false)
+	}()
+
+	if releases := server.Releases(); len(releases) != 0 {
+		t.Errorf("Expected api's release to be rolled back, but found %d releases", len(releases))
+	}
+
+	if _, ok := server.Tag("api-1.0.0"); ok {
+		t.Error("Expected api's tag to be rolled back")
+	}
+
+	if updated := server.Head(githubtest.DefaultBranch); updated != head {
+		t.Errorf("Expected the branch to be moved back to %s before api's version file commit, but it's at %s", head, updated)
+	}
+
+	if sent := notifications.Load(); sent != 0 {
+		t.Errorf("Expected api's release not to be announced, but %d notifications were sent", sent)
+	}
+}
+
+func TestTransactionalReleasesAnnounceOnceEveryComponentIsReleased(t *testing.T) {
+	server, actions, notifications := transactionTest(t, HooksConfig{})
+	results := GenerateVersions(context.Background(), actions, false)
+	for _, result := range results {
+		if result.Release == nil {
+			t.Errorf("Expected %s to be released", result.Component)
+		}
+	}
+
+	if releases := server.Releases(); len(releases) != 2 {
+		t.Errorf("Expected 2 releases, but found %d", len(releases))
+	}
+
+	if contents, _ := server.File(githubtest.DefaultBranch, "api/VERSION"); contents != "1.0.0\n" {
+		t.Errorf("Expected api's version file to be updated, but it's %q", contents)
+	}
+
+	if sent := notifications.Load(); sent != 1 {
+		t.Errorf("Expected api's release to be announced once, but %d notifications were sent", sent)
+	}
+}
@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/leodido/go-conventionalcommits"
+)
+
+// Policies for commits without a scope
+const (
+	// Ignore unscoped commits, so they never change a version
+	UnscopedIgnore = "ignore"
+	// Attribute unscoped commits to a designated root component, eg: for repository-wide tooling
+	UnscopedRoot = "root"
+	// Attribute unscoped commits to each component whose path contains a file the commit changed
+	UnscopedPaths = "paths"
+)
+
+// UnscopedCommitsConfig configures which components commits without a scope are attributed to
+type UnscopedCommitsConfig struct {
+	// Policy for unscoped commits: ignore, root, or paths
+	Policy string `yaml:"policy,omitempty"`
+	// Component unscoped commits are attributed to, for the root policy
+	Component string `yaml:"component,omitempty"`
+}
+
+// includesCommit checks whether a conventional commit affects the component, explaining the decision. Commits
+// scoped to the component are always included, and unscoped commits are attributed by the unscoped commit policy.
+func (a VersioningAction) includesCommit(ctx context.Context, commit *github.RepositoryCommit, conventionalCommit *conventionalcommits.ConventionalCommit) (bool, string) {
+	if a.updatesSubmodule(ctx, commit) {
+		return true, fmt.Sprintf("%s commit updated the submodule", conventionalCommit.Type)
+	}
+
+	if conventionalCommit.Scope != nil {
+		if a.matchesScope(*conventionalCommit.Scope) {
+			return true, fmt.Sprintf("%s commit", conventionalCommit.Type)
+		}
+
+		return false, fmt.Sprintf("scope %q does not match component", *conventionalCommit.Scope)
+	}
+
+	unscoped := a.config.UnscopedCommits
+	switch unscoped.Policy {
+	case UnscopedRoot:
+		if strings.EqualFold(unscoped.Component, a.component) {
+			return true, fmt.Sprintf("unscoped %s commit attributed to the root component", conventionalCommit.Type)
+		}
+
+		return false, fmt.Sprintf("commit has no scope, so it is attributed to the root component %s", unscoped.Component)
+	case UnscopedPaths:
+		componentPath := path.Clean(a.componentConfig().Path)
+		if a.componentConfig().Path == "" {
+			return false, "commit has no scope, and the component has no path to attribute it by"
+		}
+
+		ignoredPaths := append(append([]string{}, a.config.IgnorePaths...), a.componentConfig().IgnorePaths...)
+		changedIgnoredPaths := false
+		for _, file := range a.getCommitFiles(ctx, commit.GetSHA()) {
+			if file != componentPath && !strings.HasPrefix(file, componentPath+"/") {
+				continue
+			}
+
+			// Ignore patterns are relative to the component's path
+			if matchesAnyGlob(ignoredPaths, strings.TrimPrefix(file, componentPath+"/")) {
+				changedIgnoredPaths = true
+				continue
+			}
+
+			return true, fmt.Sprintf("unscoped %s commit changed %s", conventionalCommit.Type, file)
+		}
+
+		if changedIgnoredPaths {
+			return false, "commit has no scope, and only changed ignored paths in the component's path"
+		}
+
+		return false, "commit has no scope, and changed no files in the component's path"
+	default:
+		return false, "commit has no scope"
+	}
+}
+
+// getCommitFiles lists the paths of the files a commit changed
+func (a VersioningAction) getCommitFiles(ctx context.Context, sha string) []string {
+	a.history.mu.Lock()
+	files, ok := a.history.commitFiles[sha]
+	a.history.mu.Unlock()
+	if ok {
+		return files
+	}
+
+	// Other components' files can be fetched meanwhile, as commits are fetched one at a time
+	files, err := a.commits().Files(ctx, sha)
+	if err != nil {
+		panic(err)
+	}
+
+	a.history.mu.Lock()
+	a.history.commitFiles[sha] = files
+	a.history.mu.Unlock()
+	return files
+}
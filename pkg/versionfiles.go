@@ -0,0 +1,318 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// VersionFile is a file containing the component's version, which is updated when a new version is released
+type VersionFile struct {
+	// Path of the file, relative to the component's path
+	Path string `yaml:"path,omitempty"`
+	// Regex matching the version, where the first capture group is replaced with the new version
+	Regex string `yaml:"regex,omitempty"`
+	// JSONPath of the version in a JSON file, eg: "$.version"
+	JSONPath string `yaml:"json-path,omitempty"`
+}
+
+// versionFileDefaults for well-known files, used when a version file has no regex or JSON path
+var versionFileDefaults = map[string]VersionFile{
+	"VERSION":      {Regex: `(?s)^\s*(.*?)\s*$`},
+	"version.txt":  {Regex: `(?s)^\s*(.*?)\s*$`},
+	"package.json": {JSONPath: "$.version"},
+	"Chart.yaml":   {Regex: `(?m)^version:\s*["']?([^"'\s]+)`},
+}
+
+// goVersionDefault matches a Go version constant or variable, eg: `const Version = "1.2.3"`
+var goVersionDefault = VersionFile{Regex: `Version\s*=\s*"([^"]*)"`}
+
+// typeVersionFiles are the conventional version files of each component type, which are updated without being
+// configured
+var typeVersionFiles = map[string]VersionFile{
+	ComponentHelm: chartVersionFile,
+	ComponentNpm:  {Path: "package.json", JSONPath: "$.version"},
+}
+
+// versionFiles of the component, including the conventional version file of its type unless it's configured
+func (c ComponentConfig) versionFiles() []VersionFile {
+	typeFile, ok := typeVersionFiles[c.Type]
+	if !ok {
+		return c.VersionFiles
+	}
+
+	for _, file := range c.VersionFiles {
+		if path.Clean(file.Path) == typeFile.Path {
+			return c.VersionFiles
+		}
+	}
+
+	return append([]VersionFile{typeFile}, c.VersionFiles...)
+}
+
+// withDefaults fills in the regex or JSON path for well-known files
+func (f VersionFile) withDefaults() (VersionFile, error) {
+	if f.Regex != "" || f.JSONPath != "" {
+		return f, nil
+	}
+
+	defaults, ok := versionFileDefaults[path.Base(f.Path)]
+	if !ok && path.Ext(f.Path) == ".go" {
+		defaults, ok = goVersionDefault, true
+	}
+
+	if !ok {
+		return f, fmt.Errorf("version file %s needs a regex or json-path", f.Path)
+	}
+
+	defaults.Path = f.Path
+	return defaults, nil
+}
+
+// update the version in the file's contents
+func (f VersionFile) update(contents []byte, version string) ([]byte, error) {
+	f, err := f.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.JSONPath != "" {
+		return replaceJSONString(contents, strings.Split(strings.TrimPrefix(strings.TrimPrefix(f.JSONPath, "$"), "."), "."), version)
+	}
+
+	pattern, err := regexp.Compile(f.Regex)
+	if err != nil {
+		return nil, fmt.Errorf("version file %s has an invalid regex: %w", f.Path, err)
+	}
+
+	matches := pattern.FindAllSubmatchIndex(contents, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("version file %s does not contain a match for %s", f.Path, f.Regex)
+	}
+
+	updated := bytes.Buffer{}
+	previousEnd := 0
+	for _, match := range matches {
+		if len(match) < 4 || match[2] < 0 {
+			return nil, fmt.Errorf("version file %s regex %s must have a capture group for the version", f.Path, f.Regex)
+		}
+
+		updated.Write(contents[previousEnd:match[2]])
+		updated.WriteString(version)
+		previousEnd = match[3]
+	}
+
+	updated.Write(contents[previousEnd:])
+	return updated.Bytes(), nil
+}
+
+// replaceJSONString replaces the string value at a path of object keys in a JSON document, without changing
+// the formatting of the rest of the document
+func replaceJSONString(contents []byte, keys []string, value string) ([]byte, error) {
+	type frame struct {
+		object      bool
+		expectValue bool
+		key         string
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	var stack []*frame
+	currentPath := func() []string {
+		var keys []string
+		for _, f := range stack {
+			keys = append(keys, f.key)
+		}
+
+		return keys
+	}
+
+	for {
+		offsetBefore := decoder.InputOffset()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("JSON document has no string at %s", strings.Join(keys, "."))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		if str, ok := token.(string); ok && top != nil && top.object && !top.expectValue {
+			top.key = str
+			top.expectValue = true
+			continue
+		}
+
+		if _, ok := token.(string); ok && top != nil && top.object && strings.Join(currentPath(), ".") == strings.Join(keys, ".") {
+			// The token ends at the current offset, and starts at the first quote after the previous token
+			end := int(decoder.InputOffset())
+			start := bytes.IndexByte(contents[offsetBefore:end], '"') + int(offsetBefore)
+			encoded, _ := json.Marshal(value)
+			return append(append(append([]byte{}, contents[:start]...), encoded...), contents[end:]...), nil
+		}
+
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, &frame{object: true})
+			continue
+		case json.Delim('['):
+			stack = append(stack, &frame{})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+
+		// A value was read, so the parent object expects another key
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectValue = false
+		}
+	}
+}
+
+// commitVersionFiles updates the component's version files to the new version in a single commit on top of the
+// current revision, and pushes it to the branch. Returns the SHA of the new commit, which should be released
+// instead of the current revision so that the tag includes the updated files.
+func (a VersioningAction) commitVersionFiles(ctx context.Context, version *semver.Version) string {
+	entries := a.versionFileEntries(ctx, version)
+	if len(entries) == 0 {
+		return a.revision
+	}
+
+	message := fmt.Sprintf("chore(%s): release %s [skip ci]", a.component, version.String())
+	commitSHA := a.pushCommit(ctx, message, entries)
+	a.recordPush(commitSHA)
+	return commitSHA
+}
+
+// versionFileEntries are the tree entries updating the component's version files at the current revision to the
+// new version. Files which already contain the version are left out.
+func (a VersioningAction) versionFileEntries(ctx context.Context, version *semver.Version) []*github.TreeEntry {
+	var entries []*github.TreeEntry
+	for _, file := range a.componentConfig().versionFiles() {
+		filePath := path.Join(a.componentConfig().Path, file.Path)
+		current := a.getFileContents(ctx, filePath, a.revision)
+		updated, err := file.update([]byte(current), version.String())
+		if err != nil {
+			panic(err)
+		}
+
+		if string(updated) == current {
+			continue
+		}
+
+		a.logger.Info("Updating version file", "component", a.component, "path", filePath, "version", version.String())
+		entries = append(entries, &github.TreeEntry{
+			Path:    github.String(filePath),
+			Mode:    github.String("100644"),
+			Type:    github.String("blob"),
+			Content: github.String(string(updated)),
+		})
+	}
+
+	return entries
+}
+
+// pushCommit creates a commit of the tree entries on top of the current revision, and fast-forwards the branch
+// to it. Pushing fails if the branch has moved on since the current revision.
+func (a VersioningAction) pushCommit(ctx context.Context, message string, entries []*github.TreeEntry) string {
+	a.history.pushes.Lock()
+	defer a.history.pushes.Unlock()
+	commitSHA := a.createCommit(ctx, a.revision, message, entries)
+
+	a.logger.Info("Pushing commit", "branch", a.branch, "sha", commitSHA, "message", message)
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	_, _, err := a.client.Git.UpdateRef(requestCtx, a.owner, a.repository, &github.Reference{
+		Ref: github.String(fmt.Sprintf("refs/heads/%s", a.branch)),
+		Object: &github.GitObject{
+			SHA: &commitSHA,
+		},
+	}, false)
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditUpdateBranch, Branch: a.branch, SHA: commitSHA})
+	return commitSHA
+}
+
+// createCommit of the tree entries on top of the parent commit, without updating any branch
+func (a VersioningAction) createCommit(ctx context.Context, parentSHA string, message string, entries []*github.TreeEntry) string {
+	requestCtx, cancel := a.requestContext(ctx)
+	parent, _, err := a.client.Git.GetCommit(requestCtx, a.owner, a.repository, parentSHA)
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	requestCtx, cancel = a.requestContext(ctx)
+	tree, _, err := a.client.Git.CreateTree(requestCtx, a.owner, a.repository, parent.GetTree().GetSHA(), entries)
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	commit, _, err := a.client.Git.CreateCommit(requestCtx, a.owner, a.repository, &github.Commit{
+		Message: &message,
+		Tree:    tree,
+		Parents: []*github.Commit{{SHA: &parentSHA}},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return commit.GetSHA()
+}
+
+// getFileContents at a commit, panicking if the file doesn't exist
+func (a VersioningAction) getFileContents(ctx context.Context, filePath string, ref string) string {
+	contents, ok := a.findFileContents(ctx, filePath, ref)
+	if !ok {
+		panic(fmt.Sprintf("File %s does not exist at %s", filePath, ref))
+	}
+
+	return contents
+}
+
+// findFileContents at a commit, if the file exists
+func (a VersioningAction) findFileContents(ctx context.Context, filePath string, ref string) (string, bool) {
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	content, _, _, err := a.client.Repositories.GetContents(requestCtx, a.owner, a.repository, filePath, &github.RepositoryContentGetOptions{
+		Ref: ref,
+	})
+
+	if isNotFound(err) || (err == nil && content == nil) {
+		// Either the file doesn't exist, or the path is a directory
+		return "", false
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	contents, err := content.GetContent()
+	if err != nil {
+		panic(err)
+	}
+
+	return contents, true
+}
@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// WithForce allows releasing versions which aren't newer than the component's latest stable release, whose tag
+// already exists, or whose revision isn't on the default branch, eg: to deliberately re-release a version after
+// deleting its release.
+func (a VersioningAction) WithForce(force bool) VersioningAction {
+	a.force = force
+	return a
+}
+
+// checkVersionIsNew refuses to release a version which isn't newer than the component's latest stable release, or
+// whose tag already exists, unless forced. Either means a misconfiguration, such as a changed initial-version or tag
+// template, would otherwise release an older version over the component's history, or attach a release to an
+// existing tag. On a maintenance branch, the version only has to be newer than the latest release in its line.
+func (a VersioningAction) checkVersionIsNew(ctx context.Context, version *semver.Version, allReleases []*github.RepositoryRelease) {
+	if a.force {
+		return
+	}
+
+	if latest, tagName := a.latestStableRelease(allReleases); latest != nil && !version.GreaterThan(latest) {
+		panic(fmt.Sprintf("Refusing to release %s %s, as it isn't newer than its latest release %s (%s). Check the component's initial-version and tag-template, or release it anyway with force.", a.component, version, latest, tagName))
+	}
+
+	tagName := a.tagName(version.String())
+	for _, tag := range a.getAllTags(ctx) {
+		if strings.EqualFold(tag.GetName(), tagName) {
+			panic(fmt.Sprintf("Refusing to release %s %s, as its tag %s already exists. Check whether it was released by another tool, or release it anyway with force.", a.component, version, tag.GetName()))
+		}
+	}
+}
+
+// checkRevisionIsOnDefaultBranch refuses to release a stable version on the default branch from a revision which
+// isn't an ancestor of the default branch, unless forced, eg: because the workflow checked out a stale branch but
+// was told it's on the default branch. Stable versions released from elsewhere would skip the commits on the
+// default branch, and aren't included in the history later versions are calculated from.
+func (a VersioningAction) checkRevisionIsOnDefaultBranch(ctx context.Context, version *semver.Version, revision string) {
+	if a.force || a.branch != a.defaultBranch || version.Prerelease() != "" {
+		return
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	defer cancel()
+	comparison, _, err := a.client.Repositories.CompareCommits(requestCtx, a.owner, a.repository, a.defaultBranch, revision, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		panic(err)
+	}
+
+	// The revision is an ancestor if it's the head of the default branch, or the default branch is ahead of it
+	if status := comparison.GetStatus(); status != "identical" && status != "behind" {
+		panic(fmt.Sprintf("Refusing to release %s %s, as revision %s isn't on the default branch %s, which doesn't have %d of its commits. Check which branch the workflow checked out, or release it anyway with force.", a.component, version, revision, a.defaultBranch, comparison.GetAheadBy()))
+	}
+}
+
+// latestStableRelease of the component in the current release line, returning its version and tag, or nil if it
+// hasn't been released. Releases tagged with another template are included as long as their tag starts with the
+// component's name, so that changing the tag template doesn't hide the component's history.
+func (a VersioningAction) latestStableRelease(allReleases []*github.RepositoryRelease) (*semver.Version, string) {
+	formerTag := regexp.MustCompile(fmt.Sprintf(`^(?i)(?:%s|%s)[-_/@]?v?(\d+\.\d+\.\d+)$`, regexp.QuoteMeta(a.component), regexp.QuoteMeta(a.tagComponent())))
+	line := a.releaseLine()
+	var latest *semver.Version
+	var latestTag string
+	for _, release := range publishedReleases(allReleases) {
+		version := a.releaseVersion(release)
+		if _, hasMetadata := ParseReleaseMetadata(release.GetBody()); version == nil && !hasMetadata {
+			if match := formerTag.FindStringSubmatch(release.GetTagName()); match != nil {
+				version, _ = semver.NewVersion(match[1])
+			}
+		}
+
+		if version == nil || version.Prerelease() != "" || line != nil && !line.contains(version) {
+			continue
+		}
+
+		if latest == nil || version.GreaterThan(latest) {
+			latest, latestTag = version, release.GetTagName()
+		}
+	}
+
+	return latest, latestTag
+}
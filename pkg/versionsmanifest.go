@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// VersionsManifest records the current version of each component, keyed by component name, so that build scripts
+// can read versions from the repository without calling the GitHub API
+type VersionsManifest map[string]VersionsManifestEntry
+
+// VersionsManifestEntry is the current version of a single component
+type VersionsManifestEntry struct {
+	Version string `json:"version"`
+	Tag     string `json:"tag"`
+	// SHA of the commit the version was released at
+	SHA string `json:"sha"`
+}
+
+// ParseVersionsManifest reads the contents of a versions manifest file
+func ParseVersionsManifest(contents []byte) (VersionsManifest, error) {
+	manifest := make(VersionsManifest)
+	if len(strings.TrimSpace(string(contents))) == 0 {
+		return manifest, nil
+	}
+
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid versions manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// UpdateVersionsManifest records the stable versions released on the default branch in the versions manifest, if
+// one is configured, in a single commit pushed to the default branch. Versions released from other branches,
+// prereleases, drafts and dry runs are left out, so that the manifest only records published versions.
+func (a VersioningAction) UpdateVersionsManifest(ctx context.Context, results []Result) {
+	manifestPath := a.config.VersionsManifest
+	if manifestPath == "" || a.branch != a.defaultBranch {
+		return
+	}
+
+	requestCtx, cancel := a.requestContext(ctx)
+	head, _, err := a.client.Git.GetRef(requestCtx, a.owner, a.repository, fmt.Sprintf("refs/heads/%s", a.defaultBranch))
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	headSHA := head.GetObject().GetSHA()
+	contents, _ := a.findFileContents(ctx, manifestPath, headSHA)
+	manifest, err := ParseVersionsManifest([]byte(contents))
+	if err != nil {
+		panic(fmt.Sprintf("Versions manifest %s: %s", manifestPath, err))
+	}
+
+	var released []string
+	for _, result := range results {
+		if result.Version == nil || result.Version.Prerelease() != "" || result.Release == nil || result.Release.draft {
+			continue
+		}
+
+		manifest[result.Component] = VersionsManifestEntry{
+			Version: result.Version.String(),
+			Tag:     result.TagName(),
+			SHA:     result.Release.SHA,
+		}
+		released = append(released, fmt.Sprintf("%s %s", result.Component, result.Version.String()))
+	}
+
+	if len(released) == 0 {
+		return
+	}
+
+	updated, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	a.logger.Info("Updating versions manifest", "path", manifestPath, "released", strings.Join(released, ", "))
+	message := fmt.Sprintf("chore: record %s in %s [skip ci]", strings.Join(released, ", "), manifestPath)
+	commitSHA := a.createCommit(ctx, headSHA, message, []*github.TreeEntry{{
+		Path:    github.String(manifestPath),
+		Mode:    github.String("100644"),
+		Type:    github.String("blob"),
+		Content: github.String(string(updated) + "\n"),
+	}})
+
+	requestCtx, cancel = a.requestContext(ctx)
+	defer cancel()
+	_, _, err = a.client.Git.UpdateRef(requestCtx, a.owner, a.repository, &github.Reference{
+		Ref: github.String(fmt.Sprintf("refs/heads/%s", a.defaultBranch)),
+		Object: &github.GitObject{
+			SHA: &commitSHA,
+		},
+	}, false)
+
+	if err != nil {
+		panic(err)
+	}
+
+	a.audit(AuditRecord{Action: AuditUpdateBranch, Branch: a.defaultBranch, SHA: commitSHA})
+}
@@ -0,0 +1,201 @@
+package pkg
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithConcurrency generates up to workers components' versions at once in GenerateVersions, so that a run versioning
+// many components doesn't wait for each component's API calls in turn. Results are still in the order of the
+// actions. Defaults to 1, which versions the components one at a time.
+func (a VersioningAction) WithConcurrency(workers int) VersioningAction {
+	a.concurrency = workers
+	return a
+}
+
+// forEachComponent calls fn with each action, up to the first action's concurrency at once, returning the results in
+// the same order as the actions. If fn panics, components which haven't started yet aren't, and once the rest have
+// finished, the panic of the earliest action is raised again.
+func forEachComponent[T any](actions []VersioningAction, fn func(a VersioningAction) T) []T {
+	results := make([]T, len(actions))
+	workers := min(actions[0].concurrency, len(actions))
+	if workers <= 1 {
+		for i, a := range actions {
+			results[i] = fn(a)
+		}
+
+		return results
+	}
+
+	panics := make([]any, len(actions))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	pending := make(chan int)
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				func() {
+					defer func() {
+						if recovered := recover(); recovered != nil {
+							panics[i] = recovered
+							failed.Store(true)
+						}
+					}()
+
+					results[i] = fn(actions[i])
+				}()
+			}
+		}()
+	}
+
+	for i := range actions {
+		if failed.Load() {
+			break
+		}
+
+		pending <- i
+	}
+
+	close(pending)
+	wg.Wait()
+	for _, recovered := range panics {
+		if recovered != nil {
+			panic(recovered)
+		}
+	}
+
+	return results
+}
+
+const (
+	// mutationInterval is the least time between requests which change the repository, as GitHub recommends to
+	// stay within its secondary rate limits
+	mutationInterval = time.Second
+	// maxRateLimitWait is the longest requests wait for a rate limit to reset, rather than failing
+	maxRateLimitWait = time.Minute
+	// rateLimitAttempts is how many times a rate limited request is made before failing
+	rateLimitAttempts = 3
+)
+
+// ThrottledTransport paces the requests of components versioned concurrently to stay within GitHub's rate limits:
+// requests which change the repository are made at least a second apart, and once a request is rate limited, every
+// request waits for the limit to reset, for up to a minute, before the limited request is retried
+func ThrottledTransport(transport http.RoundTripper, logger *slog.Logger) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &throttledTransport{transport: transport, logger: logger}
+}
+
+type throttledTransport struct {
+	transport http.RoundTripper
+	logger    *slog.Logger
+
+	mu sync.Mutex
+	// When requests can be made again after being rate limited
+	pausedUntil time.Time
+	// When the latest request changing the repository was made, or is scheduled to be
+	lastMutation time.Time
+}
+
+func (t *throttledTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := t.wait(request); err != nil {
+			return nil, err
+		}
+
+		response, err := t.transport.RoundTrip(request)
+		if err != nil {
+			return nil, err
+		}
+
+		delay, limited := rateLimitDelay(response)
+		if !limited || delay > maxRateLimitWait || attempt == rateLimitAttempts || (request.Body != nil && request.GetBody == nil) {
+			return response, nil
+		}
+
+		response.Body.Close()
+		t.logger.Warn(fmt.Sprintf("Rate limited by GitHub, so waiting %s before retrying", delay.Round(time.Second)), "method", request.Method, "url", request.URL.Redacted())
+		t.mu.Lock()
+		if until := time.Now().Add(delay); until.After(t.pausedUntil) {
+			t.pausedUntil = until
+		}
+		t.mu.Unlock()
+
+		if request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			request = request.Clone(request.Context())
+			request.Body = body
+		}
+	}
+}
+
+// wait until a request can be made, reserving its slot if it changes the repository
+func (t *throttledTransport) wait(request *http.Request) error {
+	t.mu.Lock()
+	start := time.Now()
+	if t.pausedUntil.After(start) {
+		start = t.pausedUntil
+	}
+
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		if next := t.lastMutation.Add(mutationInterval); next.After(start) {
+			start = next
+		}
+
+		t.lastMutation = start
+	}
+	t.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-request.Context().Done():
+		return request.Context().Err()
+	}
+}
+
+// rateLimitDelay is how long to wait before retrying a request which was rate limited, or false if it wasn't. GitHub
+// says how long to wait for secondary rate limits with Retry-After, and when the primary rate limit resets with
+// X-RateLimit-Reset.
+func rateLimitDelay(response *http.Response) (time.Duration, bool) {
+	if response.StatusCode != http.StatusForbidden && response.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if response.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Until(time.Unix(reset, 0)), true
+		}
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		// Secondary rate limits without a Retry-After need waiting at least a minute
+		return time.Minute, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/google/go-github/v50/github"
+)
+
+// yankedMarker ends the banner of a yanked release's notes, so that it's only added once
+const yankedMarker = "<!-- monorepo-versioning:yanked -->"
+
+// YankedAsset is the name of the asset attached to yanked releases, so that tools can detect withdrawn versions
+// without parsing release notes
+const YankedAsset = "YANKED.json"
+
+// YankNotice is the contents of the asset attached to a yanked release
+type YankNotice struct {
+	Component string `json:"component"`
+	Version   string `json:"version"`
+	Reason    string `json:"reason,omitempty"`
+	// Replacement is the version to upgrade to instead, if there's a newer release
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// YankResult is the outcome of yanking a version
+type YankResult struct {
+	Component string
+	Tag       string
+	// Replacement is the version to upgrade to instead, or nil if there's no newer release
+	Replacement *semver.Version
+	// Release that was yanked
+	Release *Release
+}
+
+// Yank marks the release of a version of the component as deprecated, rather than deleting it, so that consumers
+// who already use it can still download it: its notes start with a warning banner naming the replacement version,
+// and a YankedAsset is attached to it. The replacement is the newest release of the same major version which is
+// newer and not yanked itself, or else the newest of any major version. If dryRun is true, the release is only
+// logged. Yanking a version which is already yanked changes nothing, so that a yank can safely be retried.
+func (a VersioningAction) Yank(ctx context.Context, version *semver.Version, reason string, dryRun bool) YankResult {
+	tagName := a.tagName(version.String())
+	allReleases := a.getAllReleases(ctx)
+	var release *github.RepositoryRelease
+	for _, existingRelease := range allReleases {
+		if strings.EqualFold(existingRelease.GetTagName(), tagName) && !existingRelease.GetDraft() {
+			release = existingRelease
+			break
+		}
+	}
+
+	if release == nil {
+		panic(fmt.Sprintf("Can't yank %s, as it has no published release", tagName))
+	}
+
+	// The listed releases may have been read before an earlier yank, so get the release's current notes and assets
+	requestCtx, cancel := a.requestContext(ctx)
+	release, _, err := a.client.Repositories.GetRelease(requestCtx, a.owner, a.repository, release.GetID())
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+
+	result := YankResult{Component: a.component, Tag: tagName, Replacement: a.yankReplacement(version, allReleases), Release: newRelease(release)}
+	if dryRun {
+		a.logger.Info("Would yank release, but this is a dry run", "component", a.component, "tag", tagName, "replacement", result.Replacement)
+		return result
+	}
+
+	if !strings.Contains(release.GetBody(), yankedMarker) {
+		a.logger.Info("Yanking release", "component", a.component, "tag", tagName, "replacement", result.Replacement)
+		body := a.yankedBanner(reason, result.Replacement) + release.GetBody()
+		requestCtx, cancel := a.requestContext(ctx)
+		edited, _, err := a.client.Repositories.EditRelease(requestCtx, a.owner, a.repository, release.GetID(), &github.RepositoryRelease{Body: &body})
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+
+		a.audit(AuditRecord{Action: AuditYankRelease, Tag: tagName, SHA: release.GetTargetCommitish()})
+		a.replaceListedRelease(edited)
+	} else {
+		a.logger.Info("Release is already yanked", "component", a.component, "tag", tagName)
+	}
+
+	for _, asset := range release.Assets {
+		if asset.GetName() == YankedAsset {
+			return result
+		}
+	}
+
+	notice := YankNotice{Component: a.component, Version: version.String(), Reason: reason}
+	if result.Replacement != nil {
+		notice.Replacement = result.Replacement.String()
+	}
+
+	contents, err := json.MarshalIndent(notice, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	a.uploadAsset(ctx, result.Release, YankedAsset, contents, "application/json")
+	return result
+}
+
+// yankReplacement is the version to upgrade to from a yanked version, or nil if there's no newer release
+func (a VersioningAction) yankReplacement(yanked *semver.Version, releases []*github.RepositoryRelease) *semver.Version {
+	var newest *semver.Version
+	for _, release := range publishedReleases(a.filterAndSortReleasesForComponent(releases)) {
+		version := a.releaseVersion(release)
+		if !version.GreaterThan(yanked) || strings.Contains(release.GetBody(), yankedMarker) {
+			continue
+		}
+
+		if version.Major() == yanked.Major() {
+			// Releases are sorted in descending order of version, so this is the newest of the same major version
+			return version
+		}
+
+		if newest == nil {
+			newest = version
+		}
+	}
+
+	return newest
+}
+
+// yankedBanner warns readers of a yanked release's notes not to use it
+func (a VersioningAction) yankedBanner(reason string, replacement *semver.Version) string {
+	messages := a.messages()
+	banner := messages.Yanked
+	if reason != "" {
+		banner += " " + reason
+	}
+
+	if replacement != nil {
+		banner += " " + fmt.Sprintf(messages.YankedReplacement, a.tagName(replacement.String()))
+	}
+
+	return fmt.Sprintf("> [!CAUTION]\n> :no_entry: %s\n%s\n\n", banner, yankedMarker)
+}